@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/logsink"
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "LogSink 로컬 아카이브 관리",
+}
+
+var archiveRestoreOpts logsink.RestoreOptions
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "아카이브 이벤트를 event-logs 일별 인덱스로 재색인 (실시간 색인과 같은 eventId로 create — 반복 실행해도 중복 없음)",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.LoadFromEnv("logsink")
+		if archiveRestoreOpts.Dir == "" {
+			archiveRestoreOpts.Dir = cfg.LogSink.ArchiveDir
+		}
+		if archiveRestoreOpts.Dir == "" {
+			log.Fatal("아카이브 디렉터리 미설정 (LOGSINK_ARCHIVE_DIR 또는 --dir)")
+		}
+		osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+		if err != nil {
+			log.Fatalf("OpenSearch 클라이언트 설정 오류: %v", err)
+		}
+		log.Printf("아카이브 복원: %s %s ~ %s → %s (dry-run: %v)", archiveRestoreOpts.Dir,
+			archiveRestoreOpts.From, archiveRestoreOpts.To, cfg.OpenSearch.URL, archiveRestoreOpts.DryRun)
+
+		results, err := logsink.RestoreArchive(osClient, cfg.IndexPrefix, archiveRestoreOpts, cfg.LogSink.BulkMaxDocs)
+		if err != nil {
+			log.Fatalf("복원 실패: %v", err)
+		}
+		total := 0
+		for _, r := range results {
+			total += r.Indexed
+			log.Printf("  %s → %s: 파일 %d개, 이벤트 %d건, 색인 %d건, 기존 %d건, 실패 %d건",
+				r.Day, r.Index, r.Files, r.Events, r.Indexed, r.Existing, r.Failed)
+			for _, e := range r.Errors {
+				log.Printf("    %s", e)
+			}
+		}
+		if len(results) == 0 {
+			log.Println("  기간 내 아카이브 없음")
+			return
+		}
+		log.Printf("복원 완료: %d일, 색인 %d건", len(results), total)
+	},
+}
+
+func init() {
+	f := archiveRestoreCmd.Flags()
+	f.StringVar(&archiveRestoreOpts.From, "from", "", "시작 일자 (yyyy-mm-dd, 포함)")
+	f.StringVar(&archiveRestoreOpts.To, "to", "", "종료 일자 (yyyy-mm-dd, 포함)")
+	f.StringVar(&archiveRestoreOpts.Dir, "dir", "", "아카이브 디렉터리 (기본: LOGSINK_ARCHIVE_DIR)")
+	f.BoolVar(&archiveRestoreOpts.DryRun, "dry-run", false, "색인 없이 체크섬 검증/건수 집계만")
+	archiveRestoreCmd.MarkFlagRequired("from")
+	archiveRestoreCmd.MarkFlagRequired("to")
+	archiveCmd.AddCommand(archiveRestoreCmd)
+}
@@ -25,7 +25,10 @@ var cepCmd = &cobra.Command{
 		log.Printf("  Flink: %s", cfg.Flink.RestAPI)
 		log.Printf("  Kafka: %s", cfg.Kafka.Bootstrap)
 
-		os := common.NewOSClient(cfg.OpenSearch.URL)
+		os, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+		if err != nil {
+			log.Fatalf("OpenSearch 클라이언트 설정 오류: %v", err)
+		}
 		common.InitTimezone(cfg.Timezone)
 		flink := services.NewFlinkService(
 			cfg.Flink.SQLGateway,
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/logsink"
+	"github.com/spf13/cobra"
+)
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "LogSink dead-letter 관리",
+}
+
+var dlqReplayOpts logsink.ReplayOptions
+
+var dlqReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "dead-letter 이벤트를 원본 토픽에 재발행, 색인 거부 이벤트는 재색인 (원인 수정 후 재처리)",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.LoadFromEnv("logsink")
+		src := cfg.LogSink.DeadLetterTopic
+		if dlqReplayOpts.File != "" {
+			src = dlqReplayOpts.File
+		}
+		log.Printf("dead-letter 재처리: %s (dry-run: %v)", src, dlqReplayOpts.DryRun)
+
+		res, err := logsink.ReplayDeadLetters(cfg, dlqReplayOpts)
+		if res != nil {
+			log.Printf("  스캔 %d건, 재발행 %d건, 재색인 %d건, 재색인 거부 %d건, 제외 %d건",
+				res.Scanned, res.Replayed, res.Reindexed, res.Failed, res.Skipped)
+			for stage, n := range res.ByStage {
+				log.Printf("  단계 %s: %d건", stage, n)
+			}
+			for topic, n := range res.ByTopic {
+				log.Printf("  토픽 %s: %d건", topic, n)
+			}
+		}
+		if err != nil {
+			log.Fatalf("재처리 실패: %v", err)
+		}
+	},
+}
+
+func init() {
+	f := dlqReplayCmd.Flags()
+	f.StringVar(&dlqReplayOpts.File, "file", "", "Kafka 대신 로컬 dead-letter 파일(NDJSON)에서 읽기")
+	f.StringVar(&dlqReplayOpts.Stage, "stage", "", "실패 단계 필터 (decode/publish/index)")
+	f.StringVar(&dlqReplayOpts.SourceTopic, "source-topic", "", "원본 토픽 필터")
+	f.StringVar(&dlqReplayOpts.TargetTopic, "target-topic", "", "원본 토픽 대신 발행할 토픽 (syslog 출처 레코드 재처리 시 필요, 지정하면 색인 단계 레코드도 재색인 대신 재발행)")
+	f.BoolVar(&dlqReplayOpts.DryRun, "dry-run", false, "발행 없이 건수만 집계")
+	dlqCmd.AddCommand(dlqReplayCmd)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/markany/safepc-siem/config"
 	"github.com/markany/safepc-siem/internal/logsink"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var logsinkCmd = &cobra.Command{
@@ -18,6 +19,19 @@ var logsinkCmd = &cobra.Command{
 		log.Printf("  원본 토픽: %s", cfg.Kafka.EventTopics)
 		log.Printf("  변환 토픽: %s", cfg.LogSink.TransformedTopic)
 		log.Printf("  OpenSearch: %s", cfg.OpenSearch.URL)
-		logsink.Start(cfg)
+		if cfg.LogSink.SyslogUDP != "" || cfg.LogSink.SyslogTCP != "" {
+			log.Printf("  Syslog: udp=%s tcp=%s", cfg.LogSink.SyslogUDP, cfg.LogSink.SyslogTCP)
+		}
+		if err := logsink.Start(cfg); err != nil {
+			log.Fatalf("[LogSink] %v", err)
+		}
 	},
 }
+
+func init() {
+	// 플래그가 지정되면 환경변수보다 우선
+	logsinkCmd.Flags().String("syslog-udp", "", "syslog UDP 리스너 주소 (예: :514)")
+	logsinkCmd.Flags().String("syslog-tcp", "", "syslog TCP 리스너 주소 (예: :514, octet-counting/LF 프레이밍)")
+	viper.BindPFlag("LOGSINK_SYSLOG_UDP", logsinkCmd.Flags().Lookup("syslog-udp"))
+	viper.BindPFlag("LOGSINK_SYSLOG_TCP", logsinkCmd.Flags().Lookup("syslog-tcp"))
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateOpts           common.MigrateOptions
+	migrateKeepRecentDays int
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "OpenSearch index template 설치 + 매핑 drift 점검 (반복 실행 가능)",
+	Long: `indices.go의 모든 인덱스 계열(event-logs, cep-alerts, ueba-scores, rules, settings, baselines, field-meta, retention-audit)에
+버전이 붙은 index template을 설치한다. 이미 같은 버전이 설치돼 있으면 변경하지 않는다.
+
+기존 인덱스 중 이전 템플릿 버전으로 생성됐거나 필드 타입이 다른 인덱스는 drift로 보고하며,
+--reindex 지정 시 새 매핑의 인덱스로 재색인한 뒤 원래 이름을 alias로 원자적으로 전환한다
+(오늘부터 --keep-recent-days일 이내 일별 인덱스는 지연 이벤트가 도착할 수 있으므로 제외).
+마지막 증분 재색인 동안 원본 인덱스는 쓰기 차단되어 색인 요청이 잠시 거부될 수 있다.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.LoadFromEnv("migrate")
+		common.InitTimezone(cfg.Timezone)
+		osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+		if err != nil {
+			log.Fatalf("OpenSearch 클라이언트 설정 오류: %v", err)
+		}
+		now := common.Now()
+		for i := 0; i < migrateKeepRecentDays; i++ {
+			migrateOpts.Active = append(migrateOpts.Active, now.AddDate(0, 0, -i).Format("2006.01.02"))
+		}
+
+		log.Printf("index template 마이그레이션: %s (prefix: %s, dry-run: %v, reindex: %v)",
+			cfg.OpenSearch.URL, cfg.IndexPrefix, migrateOpts.DryRun, migrateOpts.Reindex)
+
+		results, err := common.Migrate(osClient, cfg.IndexPrefix, migrateOpts)
+		drifted := 0
+		for _, r := range results {
+			log.Printf("  %s: %s", r.Template, r.Action)
+			for _, d := range r.Drifts {
+				drifted++
+				log.Printf("    drift %s (생성 버전 v%d)", d.Index, d.Version)
+				for _, m := range d.Mismatches {
+					log.Printf("      %s", m)
+				}
+			}
+			for _, idx := range r.Reindexed {
+				log.Printf("    재색인 %s", idx)
+			}
+			for _, e := range r.Errors {
+				log.Printf("    오류 %s", e)
+			}
+		}
+		if err != nil {
+			log.Fatalf("마이그레이션 실패: %v", err)
+		}
+		if drifted > 0 && !migrateOpts.Reindex {
+			log.Printf("drift 인덱스 %d개 — 새 매핑 적용은 `siem migrate --reindex` (일별 인덱스는 보존 기간 경과 후 자연 교체)", drifted)
+		}
+	},
+}
+
+func init() {
+	f := migrateCmd.Flags()
+	f.StringVar(&migrateOpts.Family, "index", "", "특정 인덱스 계열만 (event-logs, cep-alerts, ueba-scores, common-rules, common-settings, ueba-baselines, common-field-meta, common-retention-audit)")
+	f.BoolVar(&migrateOpts.DryRun, "dry-run", false, "변경 없이 설치/drift 계획만 출력")
+	f.BoolVar(&migrateOpts.Reindex, "reindex", false, "drift 인덱스를 새 매핑으로 재색인")
+	f.IntVar(&migrateKeepRecentDays, "keep-recent-days", 2, "재색인에서 제외할 최근 일별 인덱스 일수 (오늘 포함, 지연 이벤트 도착 구간)")
+}
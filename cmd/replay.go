@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/logsink"
+	"github.com/spf13/cobra"
+)
+
+var replayOpts logsink.EventReplayOptions
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "과거 이벤트(NDJSON 파일 또는 event-logs 기간)를 변환 토픽에 재주입",
+	Long: "새 탐지 규칙 검증용. --file 또는 --from/--to 중 하나를 지정한다.\n" +
+		"--rate로 초당 발행 건수를 제한하고, --rewrite-time now|shift로 이벤트 시각을 현재 기준으로 바꾼다.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.LoadFromEnv("logsink")
+		if replayOpts.BatchSize == 0 {
+			replayOpts.BatchSize = cfg.LogSink.BulkMaxDocs
+		}
+		src := replayOpts.File
+		if src == "" {
+			src = replayOpts.From + " ~ " + replayOpts.To
+		}
+		target := replayOpts.Topic
+		if target == "" {
+			target = cfg.LogSink.TransformedTopic
+		}
+		log.Printf("이벤트 재주입: %s → %s (초당 %.0f건, 시각 재작성: %s, dry-run: %v)",
+			src, target, replayOpts.Rate, replayOpts.RewriteTime, replayOpts.DryRun)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		start := time.Now()
+		res, err := logsink.ReplayEvents(ctx, cfg, replayOpts)
+		if res != nil {
+			log.Printf("  읽음 %d건, 발행 %d건, 제외 %d건 (%s)", res.Read, res.Published, res.Skipped,
+				time.Since(start).Round(time.Second))
+			if !res.First.IsZero() {
+				log.Printf("  원본 시각 범위: %s ~ %s", res.First.Format(time.RFC3339), res.Last.Format(time.RFC3339))
+			}
+			ids := make([]string, 0, len(res.ByMsgID))
+			for id := range res.ByMsgID {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return res.ByMsgID[ids[i]] > res.ByMsgID[ids[j]] })
+			for _, id := range ids {
+				log.Printf("  msgId %s: %d건", id, res.ByMsgID[id])
+			}
+		}
+		if err != nil {
+			log.Fatalf("재주입 실패: %v", err)
+		}
+	},
+}
+
+func init() {
+	f := replayCmd.Flags()
+	f.StringVar(&replayOpts.File, "file", "", "NDJSON 파일 (.gz 가능, 아카이브 세그먼트 포함)")
+	f.StringVar(&replayOpts.From, "from", "", "event-logs 조회 시작 (yyyy-mm-dd 또는 RFC3339)")
+	f.StringVar(&replayOpts.To, "to", "", "event-logs 조회 끝 (yyyy-mm-dd 또는 RFC3339, 생략 시 현재까지)")
+	f.StringSliceVar(&replayOpts.MsgIDs, "msg-id", nil, "msgId 필터 (쉼표 구분 또는 반복 지정)")
+	f.StringVar(&replayOpts.Topic, "topic", "", "대상 토픽 (기본: KAFKA_TRANSFORMED_TOPIC)")
+	f.Float64Var(&replayOpts.Rate, "rate", 500, "초당 최대 발행 건수 (0이면 제한 없음)")
+	f.StringVar(&replayOpts.RewriteTime, "rewrite-time", "", "이벤트 시각 재작성: now(발행 시각) / shift(첫 이벤트를 현재로, 간격 유지)")
+	f.IntVar(&replayOpts.BatchSize, "batch", 0, "OpenSearch scroll 페이지 크기 (기본: LOGSINK_BULK_MAX_DOCS)")
+	f.BoolVar(&replayOpts.DryRun, "dry-run", false, "발행 없이 msgId별 건수만 집계")
+}
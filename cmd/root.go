@@ -24,4 +24,8 @@ func init() {
 	rootCmd.AddCommand(cepCmd)
 	rootCmd.AddCommand(uebaCmd)
 	rootCmd.AddCommand(logsinkCmd)
+	rootCmd.AddCommand(dlqCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(replayCmd)
 }
@@ -23,7 +23,10 @@ var uebaCmd = &cobra.Command{
 		log.Printf("  OpenSearch: %s", cfg.OpenSearch.URL)
 		log.Printf("  Kafka: %s", cfg.Kafka.Bootstrap)
 
-		osClient := common.NewOSClient(cfg.OpenSearch.URL)
+		osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+		if err != nil {
+			log.Fatalf("OpenSearch 클라이언트 설정 오류: %v", err)
+		}
 		common.InitTimezone(cfg.Timezone)
 
 		fieldMetaCtrl := common.NewFieldMetaController(osClient, cfg.IndexPrefix)
@@ -31,6 +34,14 @@ var uebaCmd = &cobra.Command{
 		statusCtrl := controllers.NewStatusController()
 		userCtrl := controllers.NewUserController()
 
+		// 일별 인덱스 보존 관리 (UEBA 인스턴스 1곳에서만 실행)
+		retention := common.NewRetentionManager(osClient, cfg.IndexPrefix, map[string]common.RetentionPolicy{
+			"logs":   {Days: cfg.Retention.LogsDays, Action: cfg.Retention.Action},
+			"alerts": {Days: cfg.Retention.AlertsDays, Action: cfg.Retention.Action},
+			"scores": {Days: cfg.Retention.ScoresDays, Action: cfg.Retention.Action},
+		}, cfg.Retention.Hour)
+		retentionCtrl := common.NewRetentionController(retention)
+
 		e := echo.New()
 		e.HideBanner = true
 		e.Use(middleware.Logger())
@@ -42,6 +53,13 @@ var uebaCmd = &cobra.Command{
 		e.POST("/api/field-meta/analyze", fieldMetaCtrl.Analyze)
 		e.POST("/api/field-meta/analyze-field", fieldMetaCtrl.AnalyzeField)
 
+		// 인덱스 보존 정책 API
+		e.GET("/api/retention", retentionCtrl.Get)
+		e.PUT("/api/retention", retentionCtrl.Put)
+		e.GET("/api/retention/preview", retentionCtrl.Preview)
+		e.POST("/api/retention/run", retentionCtrl.Run)
+		e.GET("/api/retention/audit", retentionCtrl.Audit)
+
 		// UEBA 규칙 API
 		e.GET("/api/rules", ruleCtrl.List)
 		e.POST("/api/rules", ruleCtrl.Create)
@@ -70,7 +88,14 @@ var uebaCmd = &cobra.Command{
 		e.PUT("/api/users/:id/context", userCtrl.SetContext)
 
 		// UEBA 전체 로직 시작
-		go services.StartProcessor(cfg)
+		go func() {
+			if err := services.StartProcessor(cfg); err != nil {
+				log.Fatalf("[UEBA] %v", err)
+			}
+		}()
+		if cfg.Retention.Enabled {
+			go retention.Start()
+		}
 
 		e.Logger.Fatal(e.Start(cfg.Server.Port))
 	},
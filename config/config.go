@@ -1,6 +1,9 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -11,6 +14,7 @@ type Config struct {
 	Flink       FlinkConfig
 	UEBA        UEBAConfig
 	LogSink     LogSinkConfig
+	Retention   RetentionConfig
 	Timezone    string
 	IndexPrefix string
 }
@@ -20,7 +24,17 @@ type ServerConfig struct {
 }
 
 type OpenSearchConfig struct {
-	URL string
+	URL        string
+	Username   string        // basic auth 사용자
+	Password   string        // basic auth 비밀번호
+	APIKey     string        // API 키 (지정 시 basic auth 대신 사용)
+	CAFile     string        // 사설 CA PEM
+	CertFile   string        // mTLS 클라이언트 인증서
+	KeyFile    string        // mTLS 클라이언트 개인키
+	Insecure   bool          // 서버 인증서 검증 생략 (테스트 환경 전용)
+	Timeout    time.Duration // 요청 타임아웃
+	MaxRetries int           // 연결 오류/429/5xx 재시도 횟수
+	Backoff    time.Duration // 첫 재시도 대기 (지수 증가 + jitter)
 }
 
 type KafkaConfig struct {
@@ -42,7 +56,58 @@ type UEBAConfig struct {
 }
 
 type LogSinkConfig struct {
-	TransformedTopic string
+	TransformedTopic  string
+	BulkMaxDocs       int
+	BulkMaxBytes      int
+	BulkFlushInterval time.Duration
+	BulkMaxRetries    int
+	TopicFormats      map[string]string // 토픽 → 입력 포맷 (json/cef/leef), 미지정 토픽은 json
+	LEEFAliasFile     string            // LEEF 벤더별 속성 키 별칭 JSON (선택)
+	SyslogUDP         string            // syslog UDP 리스너 주소 (빈값이면 비활성)
+	SyslogTCP         string            // syslog TCP 리스너 주소 (빈값이면 비활성)
+	DeadLetterTopic   string            // dead-letter Kafka 토픽 (빈값이면 파일만)
+	DeadLetterFile    string            // dead-letter 로컬 fallback 파일 (NDJSON)
+	PipelineFile      string            // 변환 파이프라인 JSON 파일 (settings 문서가 있으면 그쪽 우선)
+	PipelineReload    time.Duration     // settings 파이프라인 문서 재조회 주기
+	EnrichCIDRFiles   []string          // CIDR → 사이트/부서 CSV 목록
+	EnrichAssetFile   string            // hostname → 자산 소유자 CSV
+	EnrichMMDBFile    string            // MaxMind 형식 GeoIP MMDB (선택)
+	EnrichReload      time.Duration     // 참조 파일 변경 확인 주기
+	TimeFields        []string          // rt/start/end 다음으로 볼 이벤트 시각 필드 (점 표기)
+	ClockSkew         time.Duration     // 이 값보다 미래인 이벤트 시각은 futureEvent로 표시
+	ArchiveDir        string            // 로컬 gzip NDJSON 아카이브 디렉터리 (빈값이면 비활성)
+	ArchiveMaxBytes   int64             // 세그먼트 최대 크기 (압축 후)
+	NormalizeSchema   string            // 정규화 스키마 (ocsf/ecs, 빈값이면 비활성)
+	NormalizeOutput   string            // embed: "normalized" 하위 객체, topic: 별도 토픽 발행
+	NormalizeTopic    string            // 정규화 문서 토픽 (output=topic)
+	NormalizeFile     string            // 매핑 테이블 JSON (빈값이면 내장 테이블)
+	IngestKeys        map[string]string // HTTP 수집 출처 → API 키 (비어 있으면 /api/ingest 비활성)
+	IngestMaxBytes    int64             // HTTP 수집 본문 최대 크기 (gzip 해제 후)
+	AdminKey          string            // 관리 API 키 (빈값이면 수집 키로 인증, 둘 다 없으면 인증 없음)
+	TLSCert           string            // API 서버 TLS 인증서 (빈값이면 HTTP)
+	TLSKey            string            // API 서버 TLS 개인키
+	PartitionKeys     []string          // 변환 토픽 메시지 키 후보 필드 (점 표기, 처음으로 값이 있는 필드)
+	RedactFile        string            // 비식별 정책 JSON ({"opensearch": [...], "kafka": [...]})
+	RedactKey         string            // hash 규칙 HMAC 키
+	HealthTopic       string            // 수집량 이상(silent/spike) health 이벤트 토픽
+	VolumeInterval    time.Duration     // 수집량 집계 구간
+	VolumeWindow      int               // 기대 유입량 EWMA 창 (구간 수)
+	VolumeSpikeFactor float64           // 기대치 대비 이 배수 초과 시 spike
+	VolumeSilentAfter int               // 연속 0건 구간 수가 이 값 이상이면 silent
+	VolumeMinRate     float64           // 기대치(구간당 건수)가 이 값 미만인 출처는 경보 제외
+	DedupFields       []string          // 내용 지문 필드 (점 표기, 비어 있으면 지문/중복 억제 비활성)
+	DedupWindow       time.Duration     // 중복 억제 구간 (0이면 지문을 _id로만 사용)
+	DedupAction       string            // drop: 버림, tag: duplicate=true로 발행
+}
+
+// RetentionConfig: 일별 인덱스 보존 기간 (settings 인덱스 "retention" 문서가 있으면 그쪽 우선)
+type RetentionConfig struct {
+	Enabled    bool
+	Hour       int    // 매일 실행 시각 (설정 타임존 기준)
+	Action     string // delete / close
+	LogsDays   int
+	AlertsDays int
+	ScoresDays int
 }
 
 func LoadFromEnv(service string) *Config {
@@ -51,18 +116,50 @@ func LoadFromEnv(service string) *Config {
 	viper.SetDefault("KAFKA_BOOTSTRAP_SERVERS", "43.202.241.121:44105")
 	viper.SetDefault("KAFKA_CONSUMER_GROUP_PREFIX", "siem")
 	viper.SetDefault("OPENSEARCH_URL", "http://203.229.154.49:49200")
+	viper.SetDefault("OPENSEARCH_TIMEOUT", "30s")
+	viper.SetDefault("OPENSEARCH_MAX_RETRIES", 3)
+	viper.SetDefault("OPENSEARCH_RETRY_BACKOFF", "200ms")
 	viper.SetDefault("TIMEZONE", "Asia/Seoul")
 	viper.SetDefault("INDEX_PREFIX", "safepc")
 	viper.SetDefault("KAFKA_TRANSFORMED_TOPIC", "safepc-siem-events")
 	viper.SetDefault("KAFKA_EVENT_TOPICS", "MESSAGE_AGENT,MESSAGE_DEVICE,MESSAGE_NETWORK,MESSAGE_PROCESS,MESSAGE_PRINT,MESSAGE_DRM,MESSAGE_CLIPBOARD,MESSAGE_CAPTURE,MESSAGE_PC,MESSAGE_SCREENBLOCKER,MESSAGE_ASSETS")
 
+	viper.SetDefault("RETENTION_ENABLED", false)
+	viper.SetDefault("RETENTION_HOUR", 3)
+	viper.SetDefault("RETENTION_ACTION", "delete")
+	viper.SetDefault("RETENTION_LOGS_DAYS", 90)
+	viper.SetDefault("RETENTION_ALERTS_DAYS", 365)
+	viper.SetDefault("RETENTION_SCORES_DAYS", 365)
+
 	prefix := viper.GetString("KAFKA_CONSUMER_GROUP_PREFIX")
 	transformedTopic := viper.GetString("KAFKA_TRANSFORMED_TOPIC")
 
+	openSearch := OpenSearchConfig{
+		URL:        viper.GetString("OPENSEARCH_URL"),
+		Username:   viper.GetString("OPENSEARCH_USERNAME"),
+		Password:   viper.GetString("OPENSEARCH_PASSWORD"),
+		APIKey:     viper.GetString("OPENSEARCH_API_KEY"),
+		CAFile:     viper.GetString("OPENSEARCH_CA_FILE"),
+		CertFile:   viper.GetString("OPENSEARCH_CERT_FILE"),
+		KeyFile:    viper.GetString("OPENSEARCH_KEY_FILE"),
+		Insecure:   viper.GetBool("OPENSEARCH_INSECURE"),
+		Timeout:    viper.GetDuration("OPENSEARCH_TIMEOUT"),
+		MaxRetries: viper.GetInt("OPENSEARCH_MAX_RETRIES"),
+		Backoff:    viper.GetDuration("OPENSEARCH_RETRY_BACKOFF"),
+	}
+
 	cfg := &Config{
-		OpenSearch:  OpenSearchConfig{URL: viper.GetString("OPENSEARCH_URL")},
-		Kafka:       KafkaConfig{Bootstrap: viper.GetString("KAFKA_BOOTSTRAP_SERVERS")},
-		LogSink:     LogSinkConfig{TransformedTopic: transformedTopic},
+		OpenSearch: openSearch,
+		Kafka:      KafkaConfig{Bootstrap: viper.GetString("KAFKA_BOOTSTRAP_SERVERS")},
+		LogSink:    LogSinkConfig{TransformedTopic: transformedTopic},
+		Retention: RetentionConfig{
+			Enabled:    viper.GetBool("RETENTION_ENABLED"),
+			Hour:       viper.GetInt("RETENTION_HOUR"),
+			Action:     viper.GetString("RETENTION_ACTION"),
+			LogsDays:   viper.GetInt("RETENTION_LOGS_DAYS"),
+			AlertsDays: viper.GetInt("RETENTION_ALERTS_DAYS"),
+			ScoresDays: viper.GetInt("RETENTION_SCORES_DAYS"),
+		},
 		Timezone:    viper.GetString("TIMEZONE"),
 		IndexPrefix: viper.GetString("INDEX_PREFIX"),
 	}
@@ -72,6 +169,72 @@ func LoadFromEnv(service string) *Config {
 		// LogSink: 원본 11개 토픽 구독
 		cfg.Kafka.EventTopics = viper.GetString("KAFKA_EVENT_TOPICS")
 		cfg.Kafka.GroupID = prefix + "-logsink"
+		viper.SetDefault("LOGSINK_BULK_MAX_DOCS", 1000)
+		viper.SetDefault("LOGSINK_BULK_MAX_BYTES", 5*1024*1024)
+		viper.SetDefault("LOGSINK_BULK_FLUSH_INTERVAL", "1s")
+		viper.SetDefault("LOGSINK_BULK_MAX_RETRIES", 5)
+		cfg.LogSink.BulkMaxDocs = viper.GetInt("LOGSINK_BULK_MAX_DOCS")
+		cfg.LogSink.BulkMaxBytes = viper.GetInt("LOGSINK_BULK_MAX_BYTES")
+		cfg.LogSink.BulkFlushInterval = viper.GetDuration("LOGSINK_BULK_FLUSH_INTERVAL")
+		cfg.LogSink.BulkMaxRetries = viper.GetInt("LOGSINK_BULK_MAX_RETRIES")
+		cfg.LogSink.TopicFormats = parseKeyValueList(viper.GetString("LOGSINK_TOPIC_FORMATS"))
+		cfg.LogSink.LEEFAliasFile = viper.GetString("LOGSINK_LEEF_ALIAS_FILE")
+		cfg.LogSink.SyslogUDP = viper.GetString("LOGSINK_SYSLOG_UDP")
+		cfg.LogSink.SyslogTCP = viper.GetString("LOGSINK_SYSLOG_TCP")
+		viper.SetDefault("LOGSINK_DLQ_TOPIC", "safepc-siem-dlq")
+		viper.SetDefault("LOGSINK_DLQ_FILE", "data/dlq.ndjson")
+		cfg.LogSink.DeadLetterTopic = viper.GetString("LOGSINK_DLQ_TOPIC")
+		cfg.LogSink.DeadLetterFile = viper.GetString("LOGSINK_DLQ_FILE")
+		viper.SetDefault("LOGSINK_PORT", ":48086")
+		viper.SetDefault("LOGSINK_PIPELINE_RELOAD", "30s")
+		cfg.Server.Port = viper.GetString("LOGSINK_PORT")
+		cfg.LogSink.PipelineFile = viper.GetString("LOGSINK_PIPELINE_FILE")
+		cfg.LogSink.PipelineReload = viper.GetDuration("LOGSINK_PIPELINE_RELOAD")
+		viper.SetDefault("LOGSINK_ENRICH_RELOAD", "30s")
+		cfg.LogSink.EnrichCIDRFiles = splitList(viper.GetString("LOGSINK_ENRICH_CIDR_FILES"))
+		cfg.LogSink.EnrichAssetFile = viper.GetString("LOGSINK_ENRICH_ASSET_FILE")
+		cfg.LogSink.EnrichMMDBFile = viper.GetString("LOGSINK_ENRICH_MMDB_FILE")
+		cfg.LogSink.EnrichReload = viper.GetDuration("LOGSINK_ENRICH_RELOAD")
+		viper.SetDefault("LOGSINK_CLOCK_SKEW", "5m")
+		cfg.LogSink.TimeFields = splitList(viper.GetString("LOGSINK_TIME_FIELDS"))
+		cfg.LogSink.ClockSkew = viper.GetDuration("LOGSINK_CLOCK_SKEW")
+		viper.SetDefault("LOGSINK_ARCHIVE_MAX_BYTES", 256*1024*1024)
+		cfg.LogSink.ArchiveDir = viper.GetString("LOGSINK_ARCHIVE_DIR")
+		cfg.LogSink.ArchiveMaxBytes = viper.GetInt64("LOGSINK_ARCHIVE_MAX_BYTES")
+		viper.SetDefault("LOGSINK_NORMALIZE_OUTPUT", "topic")
+		viper.SetDefault("LOGSINK_NORMALIZE_TOPIC", "safepc-siem-normalized")
+		cfg.LogSink.NormalizeSchema = viper.GetString("LOGSINK_NORMALIZE_SCHEMA")
+		cfg.LogSink.NormalizeOutput = viper.GetString("LOGSINK_NORMALIZE_OUTPUT")
+		cfg.LogSink.NormalizeTopic = viper.GetString("LOGSINK_NORMALIZE_TOPIC")
+		cfg.LogSink.NormalizeFile = viper.GetString("LOGSINK_NORMALIZE_FILE")
+		viper.SetDefault("LOGSINK_INGEST_MAX_BYTES", 10*1024*1024)
+		cfg.LogSink.IngestKeys = parseKeyValueList(viper.GetString("LOGSINK_INGEST_KEYS"))
+		cfg.LogSink.IngestMaxBytes = viper.GetInt64("LOGSINK_INGEST_MAX_BYTES")
+		cfg.LogSink.AdminKey = viper.GetString("LOGSINK_ADMIN_KEY")
+		cfg.LogSink.TLSCert = viper.GetString("LOGSINK_TLS_CERT")
+		cfg.LogSink.TLSKey = viper.GetString("LOGSINK_TLS_KEY")
+		viper.SetDefault("LOGSINK_PARTITION_KEY", "cefExtensions.suid,hostname")
+		cfg.LogSink.PartitionKeys = splitList(viper.GetString("LOGSINK_PARTITION_KEY"))
+		cfg.LogSink.RedactFile = viper.GetString("LOGSINK_REDACT_FILE")
+		cfg.LogSink.RedactKey = viper.GetString("LOGSINK_REDACT_KEY")
+		viper.SetDefault("LOGSINK_HEALTH_TOPIC", "safepc-siem-health")
+		viper.SetDefault("LOGSINK_VOLUME_INTERVAL", "1m")
+		viper.SetDefault("LOGSINK_VOLUME_WINDOW", 60)
+		viper.SetDefault("LOGSINK_VOLUME_SPIKE_FACTOR", 5.0)
+		viper.SetDefault("LOGSINK_VOLUME_SILENT_AFTER", 5)
+		viper.SetDefault("LOGSINK_VOLUME_MIN_RATE", 1.0)
+		cfg.LogSink.HealthTopic = viper.GetString("LOGSINK_HEALTH_TOPIC")
+		cfg.LogSink.VolumeInterval = viper.GetDuration("LOGSINK_VOLUME_INTERVAL")
+		cfg.LogSink.VolumeWindow = viper.GetInt("LOGSINK_VOLUME_WINDOW")
+		cfg.LogSink.VolumeSpikeFactor = viper.GetFloat64("LOGSINK_VOLUME_SPIKE_FACTOR")
+		cfg.LogSink.VolumeSilentAfter = viper.GetInt("LOGSINK_VOLUME_SILENT_AFTER")
+		cfg.LogSink.VolumeMinRate = viper.GetFloat64("LOGSINK_VOLUME_MIN_RATE")
+		viper.SetDefault("LOGSINK_DEDUP_FIELDS", "")
+		viper.SetDefault("LOGSINK_DEDUP_WINDOW", "10m")
+		viper.SetDefault("LOGSINK_DEDUP_ACTION", "drop")
+		cfg.LogSink.DedupFields = splitList(viper.GetString("LOGSINK_DEDUP_FIELDS"))
+		cfg.LogSink.DedupWindow = viper.GetDuration("LOGSINK_DEDUP_WINDOW")
+		cfg.LogSink.DedupAction = viper.GetString("LOGSINK_DEDUP_ACTION")
 
 	case "cep":
 		// CEP: 변환 토픽 1개 구독
@@ -106,3 +269,28 @@ func LoadFromEnv(service string) *Config {
 
 	return cfg
 }
+
+// parseKeyValueList: "A:x,B:y" → {A:x, B:y} (공백 무시, 형식 오류 항목은 건너뜀)
+func parseKeyValueList(s string) map[string]string {
+	result := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(item, ":")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// splitList: "a, b,,c" → [a b c]
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	github.com/IBM/sarama v1.43.0
 	github.com/labstack/echo/v4 v4.12.0
+	github.com/oschwald/maxminddb-golang v1.13.1
 	github.com/spf13/cobra v1.8.1
 	github.com/spf13/viper v1.19.0
 )
@@ -2,18 +2,17 @@ package controllers
 
 import (
 	"strconv"
-	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/markany/safepc-siem/internal/common"
 )
 
 type AlertController struct {
-	OS          *common.OSClient
+	OS          common.Store
 	IndexPrefix string
 }
 
-func NewAlertController(os *common.OSClient, indexPrefix string) *AlertController {
+func NewAlertController(os common.Store, indexPrefix string) *AlertController {
 	return &AlertController{OS: os, IndexPrefix: indexPrefix}
 }
 
@@ -64,8 +63,8 @@ func (c *AlertController) List(ctx echo.Context) error {
 		query = map[string]interface{}{"bool": map[string]interface{}{"must": must}}
 	}
 
-	// 인덱스 패턴
-	index := common.DailyAlertsIndex(c.IndexPrefix, time.Now().Format("2006.01.02"))
+	// 오늘 인덱스 (consumer와 같은 설정 타임존 기준 일자)
+	index := common.DailyAlertsIndex(c.IndexPrefix, common.Now().Format("2006.01.02"))
 
 	// 총 개수
 	total := 0
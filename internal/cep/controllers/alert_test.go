@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/ostest"
+)
+
+// TestAlertListTodayIndex: 목록은 오늘(설정 타임존) cep-alerts 인덱스만 조회
+func TestAlertListTodayIndex(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	srv := ostest.NewServer()
+	defer srv.Close()
+	client := srv.OSClient()
+
+	now := common.Now()
+	todayIndex := common.DailyAlertsIndex("test", now.Format("2006.01.02"))
+	yesterdayIndex := common.DailyAlertsIndex("test", now.AddDate(0, 0, -1).Format("2006.01.02"))
+	alerts := []struct {
+		index, id string
+		doc       map[string]interface{}
+	}{
+		{todayIndex, "a1", map[string]interface{}{"ruleId": "r1", "ruleName": "USB", "severity": "HIGH", "userId": "u1", "@timestamp": now.Format(time.RFC3339)}},
+		{todayIndex, "a2", map[string]interface{}{"ruleId": "r2", "ruleName": "Print", "severity": "LOW", "userId": "u2", "@timestamp": now.Format(time.RFC3339)}},
+		{yesterdayIndex, "a3", map[string]interface{}{"ruleId": "r1", "ruleName": "USB", "severity": "HIGH", "userId": "u3"}},
+	}
+	for _, a := range alerts {
+		if err := client.Put(a.index, a.id, a.doc); err != nil {
+			t.Fatalf("Put %s: %v", a.id, err)
+		}
+	}
+
+	list := func(query string) map[string]interface{} {
+		t.Helper()
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/alerts?"+query, nil), rec)
+		if err := NewAlertController(client, "test").List(ctx); err != nil || rec.Code != 200 {
+			t.Fatalf("List(%s): %v (status %d)", query, err, rec.Code)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("응답 파싱 실패: %v", err)
+		}
+		return body
+	}
+
+	body := list("draw=1")
+	if body["recordsTotal"] != 2.0 || len(body["data"].([]interface{})) != 2 {
+		t.Errorf("today alerts = %v, want 2 (a3 is in %s)", body, yesterdayIndex)
+	}
+	body = list("severity=HIGH&rule=r1")
+	data := body["data"].([]interface{})
+	if body["recordsTotal"] != 1.0 || len(data) != 1 || data[0].([]interface{})[4] != "u1" {
+		t.Errorf("filtered alerts = %v, want only u1", body)
+	}
+}
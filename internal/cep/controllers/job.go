@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -11,11 +12,11 @@ import (
 
 type JobController struct {
 	Flink       *services.FlinkService
-	OS          *common.OSClient
+	OS          common.Store
 	IndexPrefix string
 }
 
-func NewJobController(flink *services.FlinkService, os *common.OSClient, indexPrefix string) *JobController {
+func NewJobController(flink *services.FlinkService, os common.Store, indexPrefix string) *JobController {
 	return &JobController{Flink: flink, OS: os, IndexPrefix: indexPrefix}
 }
 
@@ -31,9 +32,9 @@ func (c *JobController) Submit(ctx echo.Context) error {
 		return ctx.JSON(400, map[string]string{"error": "invalid JSON"})
 	}
 
-	sql := req.SQL
+	sql, fromRule := req.SQL, false
 	if sql == "" && req.Rule != nil {
-		sql = services.BuildSQLFromRule(req.Rule)
+		sql, fromRule = services.BuildSQLFromRule(req.Rule), true
 	}
 	if sql == "" {
 		return ctx.JSON(400, map[string]string{"error": "sql 또는 rule 필요"})
@@ -42,7 +43,7 @@ func (c *JobController) Submit(ctx echo.Context) error {
 		req.Severity = "MEDIUM"
 	}
 
-	jobID, err := c.Flink.SubmitRule(req.RuleID, req.Name, req.Severity, sql)
+	jobID, err := c.Flink.SubmitRule(req.RuleID, req.Name, req.Severity, sql, fromRule)
 	if err != nil {
 		return ctx.JSON(500, map[string]string{"error": err.Error()})
 	}
@@ -63,14 +64,11 @@ func (c *JobController) ReloadAll() (int, error) {
 	}
 
 	// CEP 규칙 조회 (jobId 포함)
-	docs, err := c.OS.Search(common.RulesIndex(c.IndexPrefix), map[string]interface{}{
-		"size": 100,
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": []map[string]interface{}{
-					{"term": map[string]interface{}{"enabled": true}},
-					{"term": map[string]interface{}{"cep.enabled": true}},
-				},
+	docs, err := common.SearchAll(context.Background(), c.OS, common.RulesIndex(c.IndexPrefix), map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": []map[string]interface{}{
+				{"term": map[string]interface{}{"enabled": true}},
+				{"term": map[string]interface{}{"cep.enabled": true}},
 			},
 		},
 	})
@@ -130,7 +128,7 @@ func (c *JobController) ReloadAll() (int, error) {
 	// 직렬 제출 + jobId 저장
 	submitted := 0
 	for _, r := range toSubmit {
-		jobId, err := c.Flink.SubmitRule(r.ruleID, r.name, r.severity, r.sql)
+		jobId, err := c.Flink.SubmitRule(r.ruleID, r.name, r.severity, r.sql, true)
 		if err != nil {
 			log.Printf("[CEP] 제출 실패: %s - %v", r.name, err)
 			c.updateRuleJobStatus(r.ruleID, "", "FAILED")
@@ -10,20 +10,18 @@ import (
 )
 
 type RuleController struct {
-	OS          *common.OSClient
+	OS          common.Store
 	Flink       *services.FlinkService
 	IndexPrefix string
 }
 
-func NewRuleController(os *common.OSClient, flink *services.FlinkService, indexPrefix string) *RuleController {
+func NewRuleController(os common.Store, flink *services.FlinkService, indexPrefix string) *RuleController {
 	return &RuleController{OS: os, Flink: flink, IndexPrefix: indexPrefix}
 }
 
 func (c *RuleController) List(ctx echo.Context) error {
-	docs, err := c.OS.Search(common.RulesIndex(c.IndexPrefix), map[string]interface{}{
-		"query": map[string]interface{}{"term": map[string]interface{}{"cep.enabled": true}},
-		"size":  100,
-	})
+	docs, err := common.SearchAll(ctx.Request().Context(), c.OS, common.RulesIndex(c.IndexPrefix),
+		map[string]interface{}{"term": map[string]interface{}{"cep.enabled": true}})
 	if err != nil {
 		return ctx.JSON(500, map[string]string{"error": err.Error()})
 	}
@@ -31,6 +29,9 @@ func (c *RuleController) List(ctx echo.Context) error {
 		docs[i]["id"] = docs[i]["_id"]
 		delete(docs[i], "_id")
 	}
+	if docs == nil {
+		docs = []map[string]interface{}{}
+	}
 	return ctx.JSON(200, map[string]interface{}{"rules": docs})
 }
 
@@ -68,7 +69,7 @@ func (c *RuleController) Create(ctx echo.Context) error {
 				severity = s
 			}
 		}
-		c.Flink.SubmitRule(ruleID, name, severity, sql)
+		c.Flink.SubmitRule(ruleID, name, severity, sql, true)
 	}
 
 	return ctx.JSON(200, map[string]string{"status": "ok", "ruleId": ruleID})
@@ -105,7 +106,7 @@ func (c *RuleController) Update(ctx echo.Context) error {
 				severity = s
 			}
 		}
-		c.Flink.SubmitRule(ruleID, name, severity, sql)
+		c.Flink.SubmitRule(ruleID, name, severity, sql, true)
 	} else {
 		c.Flink.CancelRule(ruleID)
 	}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,7 +11,7 @@ import (
 	"github.com/markany/safepc-siem/internal/common"
 )
 
-func StartAlertConsumer(kafka, groupID, topic, indexPrefix string, os *common.OSClient) {
+func StartAlertConsumer(kafka, groupID, topic, indexPrefix string, os common.Store) {
 	config := sarama.NewConfig()
 	config.Consumer.Offsets.Initial = sarama.OffsetNewest
 
@@ -22,6 +23,10 @@ func StartAlertConsumer(kafka, groupID, topic, indexPrefix string, os *common.OS
 
 	log.Printf("[CEP Alert] 시작: %s (topic: %s)", kafka, topic)
 
+	// alert 색인은 bulk로 모아서 (1초 주기)
+	writer := common.NewBulkWriter(os, common.BulkOptions{Name: "CEP Alert", MaxRetries: 3, StatsInterval: time.Minute})
+	go writer.Run(context.Background())
+
 	partitions, err := consumer.Partitions(topic)
 	if err != nil {
 		log.Printf("[CEP Alert] 토픽 파티션 조회 실패: %v", err)
@@ -37,14 +42,14 @@ func StartAlertConsumer(kafka, groupID, topic, indexPrefix string, os *common.OS
 		go func(pc sarama.PartitionConsumer) {
 			defer pc.Close()
 			for msg := range pc.Messages() {
-				processAlert(msg.Value, os, indexPrefix)
+				processAlert(msg.Value, writer, indexPrefix)
 			}
 		}(pc)
 	}
 	select {}
 }
 
-func processAlert(data []byte, os *common.OSClient, indexPrefix string) {
+func processAlert(data []byte, writer *common.BulkWriter, indexPrefix string) {
 	var alert map[string]interface{}
 	if err := json.Unmarshal(data, &alert); err != nil {
 		return
@@ -55,5 +60,5 @@ func processAlert(data []byte, os *common.OSClient, indexPrefix string) {
 	}
 	indexName := common.DailyAlertsIndex(indexPrefix, now.Format("2006.01.02"))
 	docID := fmt.Sprintf("%d", now.UnixNano())
-	os.Put(indexName, docID, alert)
+	writer.Add(indexName, docID, alert)
 }
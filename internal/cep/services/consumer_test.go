@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/ostest"
+)
+
+// TestProcessAlertDailyIndex: alert는 @timestamp와 무관하게 수신 일자(설정 타임존)의 cep-alerts 인덱스로 bulk 색인
+func TestProcessAlertDailyIndex(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	srv := ostest.NewServer()
+	defer srv.Close()
+
+	writer := common.NewBulkWriter(srv.OSClient(), common.BulkOptions{Name: "test", MaxRetries: 1})
+	today := common.Now().Format("2006.01.02")
+	processAlert([]byte(`{"ruleId":"r1","userId":"u1","severity":"HIGH"}`), writer, "test")
+	processAlert([]byte(`{"ruleId":"r2","userId":"u2","@timestamp":"2024-01-01T10:00:00+09:00"}`), writer, "test")
+	processAlert([]byte(`not json`), writer, "test")
+	writer.Flush(context.Background())
+
+	if got := srv.Indices("test-siem-cep-alerts-*"); len(got) != 1 || got[0] != common.DailyAlertsIndex("test", today) {
+		t.Fatalf("alert indices = %v, want [%s]", got, common.DailyAlertsIndex("test", today))
+	}
+	docs := srv.Docs(common.DailyAlertsIndex("test", today))
+	if len(docs) != 2 {
+		t.Fatalf("alerts = %d, want 2: %v", len(docs), docs)
+	}
+	byRule := map[string]map[string]interface{}{}
+	for _, doc := range docs {
+		byRule[doc["ruleId"].(string)] = doc
+	}
+	if ts, _ := byRule["r1"]["@timestamp"].(string); ts == "" {
+		t.Errorf("r1 @timestamp not set: %v", byRule["r1"])
+	}
+	if ts := byRule["r2"]["@timestamp"]; ts != "2024-01-01T10:00:00+09:00" {
+		t.Errorf("r2 @timestamp = %v, want original", ts)
+	}
+}
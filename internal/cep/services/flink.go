@@ -94,6 +94,7 @@ func (s *FlinkService) EnsureSession() error {
 				"  hostname STRING,"+
 				"  appName STRING,"+
 				"  cefExtensions MAP<STRING, STRING>,"+
+				"  replayed BOOLEAN,"+
 				"  userId AS cefExtensions['suid'],"+
 				"  userName AS cefExtensions['suser'],"+
 				"  userIp AS cefExtensions['src'],"+
@@ -116,7 +117,7 @@ func (s *FlinkService) EnsureSession() error {
 		alertsDDL := fmt.Sprintf(
 			"CREATE TABLE IF NOT EXISTS alerts ("+
 				"  ruleId STRING, ruleName STRING, severity STRING, userId STRING,"+
-				"  hostname STRING, userIp STRING, cnt BIGINT, ts TIMESTAMP(3)"+
+				"  hostname STRING, userIp STRING, cnt BIGINT, ts TIMESTAMP(3), replayed BOOLEAN"+
 				") WITH ("+
 				"  'connector' = 'kafka',"+
 				"  'topic' = '%s',"+
@@ -175,7 +176,10 @@ func (s *FlinkService) ExecSQL(sql string) error {
 	return nil
 }
 
-func (s *FlinkService) SubmitRule(ruleID, ruleName, severity, sql string) (string, error) {
+// SubmitRule: 규칙 SQL을 alerts INSERT 잡으로 제출 (기존 잡은 취소)
+// fromRule: sql이 BuildSQLFromRule 출력이면 true — replayed 컬럼을 출력하므로 alert에 그대로 싣는다.
+// 직접 작성 SQL은 replayed 컬럼이 없을 수 있어 NULL로 채운다.
+func (s *FlinkService) SubmitRule(ruleID, ruleName, severity, sql string, fromRule bool) (string, error) {
 	if err := s.EnsureSession(); err != nil {
 		return "", err
 	}
@@ -186,15 +190,20 @@ func (s *FlinkService) SubmitRule(ruleID, ruleName, severity, sql string) (strin
 	jobName := "CEP: " + safeName
 	flat := strings.ReplaceAll(sql, "\n", " ")
 
+	replayed := "CAST(NULL AS BOOLEAN)"
+	if fromRule {
+		replayed = "COALESCE(replayed, FALSE)"
+	}
+
 	var insertSQL string
 	if strings.Contains(strings.ToUpper(sql), "MATCH_RECOGNIZE") {
 		insertSQL = fmt.Sprintf(
-			"INSERT INTO alerts SELECT '%s', '%s', '%s', userId, hostname, userIp, cnt, CURRENT_TIMESTAMP FROM (%s)",
-			ruleID, safeName, severity, flat)
+			"INSERT INTO alerts SELECT '%s', '%s', '%s', userId, hostname, userIp, cnt, CURRENT_TIMESTAMP, %s FROM (%s)",
+			ruleID, safeName, severity, replayed, flat)
 	} else {
 		insertSQL = fmt.Sprintf(
-			"INSERT INTO alerts SELECT '%s', '%s', '%s', userId, hostname, userIp, cnt, CURRENT_TIMESTAMP FROM (%s) AS t",
-			ruleID, safeName, severity, flat)
+			"INSERT INTO alerts SELECT '%s', '%s', '%s', userId, hostname, userIp, cnt, CURRENT_TIMESTAMP, %s FROM (%s) AS t",
+			ruleID, safeName, severity, replayed, flat)
 	}
 
 	s.ExecSQL(fmt.Sprintf("SET 'pipeline.name' = '%s'", jobName))
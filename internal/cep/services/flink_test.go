@@ -0,0 +1,76 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSubmitRuleReplayed: 규칙에서 만든 SQL만 replayed 컬럼을 alert에 싣고, 직접 작성 SQL은 NULL
+func TestSubmitRuleReplayed(t *testing.T) {
+	var mu sync.Mutex
+	var statements []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/sessions":
+			json.NewEncoder(w).Encode(map[string]string{"sessionHandle": "s1"})
+		case r.URL.Path == "/v1/sessions/s1/statements":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			statements = append(statements, body["statement"])
+			mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]string{"operationHandle": "op"})
+		case r.URL.Path == "/jobs/overview":
+			json.NewEncoder(w).Encode(map[string]interface{}{"jobs": []map[string]string{
+				{"jid": "j1", "name": "CEP: rule one", "state": "RUNNING"},
+				{"jid": "j2", "name": "CEP: rule two", "state": "RUNNING"},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := NewFlinkService(srv.URL, srv.URL, "kafka:9092", "alerts", "cep", "events")
+
+	tests := []struct {
+		name     string
+		ruleID   string
+		ruleName string
+		sql      string
+		fromRule bool
+		wantJob  string
+		want     string
+	}{
+		{
+			name: "built from rule", ruleID: "r1", ruleName: "rule one", fromRule: true, wantJob: "j1",
+			sql:  BuildSQLFromRule(map[string]interface{}{"match": map[string]interface{}{"msgId": "USB"}}),
+			want: "CURRENT_TIMESTAMP, COALESCE(replayed, FALSE) FROM (",
+		},
+		{
+			name: "hand written sql", ruleID: "r2", ruleName: "rule two", wantJob: "j2",
+			sql:  "SELECT userId, hostname, userIp, 1 as cnt FROM events",
+			want: "CURRENT_TIMESTAMP, CAST(NULL AS BOOLEAN) FROM (",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jid, err := s.SubmitRule(tt.ruleID, tt.ruleName, "HIGH", tt.sql, tt.fromRule)
+			if err != nil {
+				t.Fatalf("SubmitRule: %v", err)
+			}
+			if jid != tt.wantJob {
+				t.Errorf("job = %s, want %s", jid, tt.wantJob)
+			}
+			mu.Lock()
+			last := statements[len(statements)-1]
+			mu.Unlock()
+			if !strings.HasPrefix(last, "INSERT INTO alerts SELECT '"+tt.ruleID+"'") || !strings.Contains(last, tt.want) {
+				t.Errorf("insert = %s, want %q", last, tt.want)
+			}
+		})
+	}
+}
@@ -167,9 +167,10 @@ func BuildSQLFromRule(rule map[string]interface{}) string {
 	byFields := toStringSlice(rule["by"], []string{"userId"})
 	aggregate, _ := rule["aggregate"].(map[string]interface{})
 
-	// 출력 필드: by 필드 + hostname, userIp (alerts 테이블에 필요)
-	selectFields := strings.Join(byFields, ", ") + ", hostname, userIp"
-	groupFields := strings.Join(byFields, ", ") + ", hostname, userIp"
+	// 출력 필드: by 필드 + hostname, userIp, replayed (alerts 테이블에 필요)
+	// replayed로도 묶어 재주입 이벤트와 라이브 이벤트가 한 alert로 합쳐지지 않게 한다
+	selectFields := strings.Join(byFields, ", ") + ", hostname, userIp, replayed"
+	groupFields := strings.Join(byFields, ", ") + ", hostname, userIp, replayed"
 
 	// ── 통합 JSON: events[] → patterns[].match 변환 ──
 	if len(patterns) == 0 {
@@ -314,7 +315,7 @@ func BuildSQLFromRule(rule map[string]interface{}) string {
 			}
 		}
 
-		partitionBy := strings.Join(byFields, ", ")
+		partitionBy := strings.Join(byFields, ", ") + ", replayed"
 		interval := ParseWindow(within)
 		if within == nil {
 			interval = ParseWindow("5m")
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBuildSQLFromRuleReplayed: 규칙 형태별 SQL이 replayed를 출력하고 그룹/파티션 키에도 포함
+func TestBuildSQLFromRuleReplayed(t *testing.T) {
+	usb := map[string]interface{}{"msgId": "USB"}
+	tests := []struct {
+		name string
+		rule map[string]interface{}
+		want []string // SQL에 포함돼야 하는 조각
+	}{
+		{
+			name: "simple filter",
+			rule: map[string]interface{}{"match": usb},
+			want: []string{"SELECT userId, hostname, userIp, replayed, 1 as cnt FROM events WHERE msgId = 'USB'"},
+		},
+		{
+			name: "aggregate",
+			rule: map[string]interface{}{"match": usb, "aggregate": map[string]interface{}{"minCount": 5.0, "within": "10m"}},
+			want: []string{
+				"SELECT userId, hostname, userIp, replayed, COUNT(*) as cnt",
+				"GROUP BY TUMBLE(proctime, INTERVAL '10' MINUTE), userId, hostname, userIp, replayed",
+				"HAVING COUNT(*) >= 5",
+			},
+		},
+		{
+			name: "quantifier with custom by",
+			rule: map[string]interface{}{
+				"patterns": []interface{}{map[string]interface{}{"match": usb, "quantifier": map[string]interface{}{"min": 3.0}}},
+				"by":       []interface{}{"hostname"},
+			},
+			want: []string{"GROUP BY TUMBLE(proctime, INTERVAL '1' HOUR), hostname, hostname, userIp, replayed"},
+		},
+		{
+			name: "sequence",
+			rule: map[string]interface{}{"events": []interface{}{usb, map[string]interface{}{"msgId": "PRINT"}}, "within": "5m"},
+			want: []string{"PARTITION BY userId, replayed", "PATTERN (P1 P2) WITHIN INTERVAL '5' MINUTE"},
+		},
+		{
+			name: "or patterns",
+			rule: map[string]interface{}{
+				"logic":    "or",
+				"patterns": []interface{}{map[string]interface{}{"match": usb}, map[string]interface{}{"match": map[string]interface{}{"msgId": "PRINT"}}},
+			},
+			want: []string{"SELECT userId, hostname, userIp, replayed, 1 as cnt FROM events WHERE (msgId = 'USB') OR (msgId = 'PRINT')"},
+		},
+		{
+			name: "and patterns",
+			rule: map[string]interface{}{
+				"patterns": []interface{}{map[string]interface{}{"match": usb}, map[string]interface{}{"match": map[string]interface{}{"msgId": "PRINT"}}},
+			},
+			want: []string{
+				"SELECT userId, hostname, userIp, replayed, MAX(CASE WHEN msgId = 'USB' THEN 1 ELSE 0 END) AS p0",
+				"GROUP BY TUMBLE(proctime, INTERVAL '30' MINUTE), userId, hostname, userIp, replayed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := BuildSQLFromRule(tt.rule)
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("SQL missing %q\n%s", want, sql)
+				}
+			}
+		})
+	}
+}
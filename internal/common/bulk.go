@@ -0,0 +1,347 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// BulkItem: _bulk로 색인할 문서 1건
+type BulkItem struct {
+	Index     string
+	ID        string // 빈값이면 AddItem이 클라이언트 _id를 부여하고 Create로 보낸다
+	Create    bool   // op_type=create (같은 _id 문서가 있으면 409 → 이미 색인된 것으로 간주)
+	Doc       []byte
+	OnSuccess func()      // 색인 성공 시 호출 (nil 가능)
+	Meta      interface{} // 호출측 데이터 (OnFailure에서 원본 추적용)
+
+	Attempts int
+	Status   int // 마지막 응답 상태 (0은 요청 자체 실패)
+	LastErr  string
+}
+
+// BulkOptions: flush 조건과 재시도 정책
+type BulkOptions struct {
+	Name          string        // 로그 접두사 (예: "LogSink")
+	MaxDocs       int           // 버퍼 건수가 이 값에 도달하면 flush
+	MaxBytes      int           // 버퍼 크기가 이 값에 도달하면 flush
+	FlushInterval time.Duration // 주기 flush (Run 사용 시)
+	MaxRetries    int           // 429/5xx/요청 실패 항목의 flush 내 재시도 횟수 (초과 시 버퍼에 보류 — 실패 처리하지 않음)
+	Backoff       time.Duration // 첫 재시도 대기 (지수 증가)
+	MaxBackoff    time.Duration
+	StatsInterval time.Duration // 통계 로그 주기 (0이면 로그 없음)
+}
+
+// BulkWriter: 문서를 버퍼링했다가 건수/크기/주기 조건으로 _bulk flush
+//
+// Add/AddItem은 여러 goroutine에서 호출할 수 있다. flush는 직렬화되며,
+// 버퍼가 가득 차면 Add 호출측이 flush 완료까지 대기한다 (backpressure).
+// 응답은 항목별로 해석해 성공은 OnSuccess, 재시도 불가(4xx 거부)는 OnFailure로 넘긴다.
+// 일시 오류(연결/429/5xx)는 재시도 횟수를 넘겨도 실패 처리하지 않고 버퍼에 남겨 다음 flush에서 다시 보낸다
+// — OnSuccess가 ack인 호출측은 OpenSearch가 복구될 때까지 해당 이벤트를 ack하지 않는다.
+// _id가 없는 항목은 추가 시점에 _id를 정해 create로 보내므로, 색인됐지만 응답을 못 받은 항목의 재전송은 409(기존 문서)가 되어 중복 색인되지 않는다.
+type BulkWriter struct {
+	os   Store
+	opts BulkOptions
+
+	// OnFailure: 재시도 불가 항목 처리 (Run/Add 전에 설정, 기본은 로그)
+	OnFailure func(item *BulkItem)
+	// BeforeFlush: 전송 직전 호출 (nil 가능). error면 전송하지 않고 백오프 후 다시 호출한다 (항목은 성공 처리되지 않은 채 대기)
+	BeforeFlush func() error
+
+	mu    sync.Mutex
+	items []*BulkItem
+	size  int
+	ctx   context.Context // Run의 ctx (AddItem이 트리거한 flush의 대기 취소용)
+
+	flushMu sync.Mutex
+	stats   bulkStats
+}
+
+type bulkStats struct {
+	flushes  int
+	docs     int
+	bytes    int
+	latency  time.Duration
+	retried  int
+	failed   int
+	existing int // create 409 (같은 _id 문서가 이미 있음)
+}
+
+func NewBulkWriter(os Store, opts BulkOptions) *BulkWriter {
+	if opts.MaxDocs <= 0 {
+		opts.MaxDocs = 1000
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 5 * 1024 * 1024
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	w := &BulkWriter{os: os, opts: opts, ctx: context.Background()}
+	w.OnFailure = func(item *BulkItem) {
+		log.Printf("[%s] bulk 색인 실패 %s/%s: %s", opts.Name, item.Index, item.ID, item.LastErr)
+	}
+	return w
+}
+
+// Add: 문서 추가 (doc이 []byte/json.RawMessage면 그대로, 아니면 JSON 직렬화), id가 있으면 같은 _id 문서를 덮어쓴다
+func (w *BulkWriter) Add(index, id string, doc interface{}) {
+	item := &BulkItem{Index: index, ID: id}
+	switch d := doc.(type) {
+	case []byte:
+		item.Doc = d
+	case json.RawMessage:
+		item.Doc = d
+	default:
+		data, err := json.Marshal(doc)
+		if err != nil {
+			item.LastErr = fmt.Sprintf("직렬화 실패: %v", err)
+			w.OnFailure(item)
+			return
+		}
+		item.Doc = data
+	}
+	w.AddItem(item)
+}
+
+// AddItem: 콜백/op_type을 지정한 항목 추가, 임계치 도달 시 즉시 flush
+// ID가 빈 항목은 NewDocID + Create로 바꾼다 (재시도 시 중복 방지)
+func (w *BulkWriter) AddItem(item *BulkItem) {
+	if item.ID == "" {
+		item.ID, item.Create = NewDocID(), true
+	}
+	w.mu.Lock()
+	w.items = append(w.items, item)
+	w.size += len(item.Doc)
+	full := len(w.items) >= w.opts.MaxDocs || w.size >= w.opts.MaxBytes
+	ctx := w.ctx
+	w.mu.Unlock()
+	if full {
+		w.Flush(ctx)
+	}
+}
+
+// Run: FlushInterval 주기로 flush, ctx 종료 시 잔여분을 한 번 더 보내고 반환 (실패분은 재시도 없이 남김)
+func (w *BulkWriter) Run(ctx context.Context) {
+	w.mu.Lock()
+	w.ctx = ctx
+	w.mu.Unlock()
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	var statsC <-chan time.Time
+	if w.opts.StatsInterval > 0 {
+		statsTicker := time.NewTicker(w.opts.StatsInterval)
+		defer statsTicker.Stop()
+		statsC = statsTicker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			w.Flush(ctx)
+			w.logStats()
+			return
+		case <-ticker.C:
+			w.Flush(ctx)
+		case <-statsC:
+			w.logStats()
+		}
+	}
+}
+
+// Flush: 현재 버퍼 전체를 _bulk 전송, 재시도 가능한 실패는 백오프 후 재전송
+//
+// 재시도 횟수를 넘긴 일시 오류 항목과 ctx 종료로 중단된 항목은 버퍼 앞쪽으로 되돌린다 (성공/실패 콜백 없이).
+// 백오프 대기는 ctx 종료 시 즉시 중단된다.
+func (w *BulkWriter) Flush(ctx context.Context) {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	items := w.items
+	w.items = nil
+	w.size = 0
+	w.mu.Unlock()
+
+	backoff := w.opts.Backoff
+	if w.BeforeFlush != nil && len(items) > 0 {
+		for {
+			err := w.BeforeFlush()
+			if err == nil {
+				break
+			}
+			log.Printf("[%s] flush 준비 실패, %s 후 재시도 (%d건 대기): %v", w.opts.Name, backoff, len(items), err)
+			if !sleepCtx(ctx, backoff) {
+				w.requeue(items)
+				return
+			}
+			backoff *= 2
+			if backoff > w.opts.MaxBackoff {
+				backoff = w.opts.MaxBackoff
+			}
+		}
+		backoff = w.opts.Backoff
+	}
+
+	for len(items) > 0 {
+		retry := w.send(items)
+		if len(retry) == 0 {
+			return
+		}
+		w.stats.retried += len(retry)
+		exhausted := false
+		for _, item := range retry {
+			item.Attempts++
+			if item.Attempts > w.opts.MaxRetries {
+				exhausted = true
+			}
+		}
+		if exhausted {
+			// OpenSearch 장애가 길어짐 — 다음 flush로 넘기되 MaxBackoff만큼 대기해 Add 호출측에 backpressure
+			log.Printf("[%s] bulk %d건 재시도 %d회 초과, 버퍼에 보류 (다음 flush에서 재전송): %s",
+				w.opts.Name, len(retry), w.opts.MaxRetries, retry[0].LastErr)
+			sleepCtx(ctx, w.opts.MaxBackoff)
+			w.requeue(retry)
+			return
+		}
+		log.Printf("[%s] bulk %d건 실패, %s 후 재시도: %s", w.opts.Name, len(retry), backoff, retry[0].LastErr)
+		if !sleepCtx(ctx, backoff) {
+			w.requeue(retry)
+			return
+		}
+		backoff *= 2
+		if backoff > w.opts.MaxBackoff {
+			backoff = w.opts.MaxBackoff
+		}
+		items = retry
+	}
+}
+
+// requeue: 보내지 못한 항목을 버퍼 앞쪽에 되돌림 (이후 추가된 항목보다 먼저 전송)
+func (w *BulkWriter) requeue(items []*BulkItem) {
+	if len(items) == 0 {
+		return
+	}
+	size := 0
+	for _, item := range items {
+		size += len(item.Doc)
+	}
+	w.mu.Lock()
+	w.items = append(append([]*BulkItem(nil), items...), w.items...)
+	w.size += size
+	w.mu.Unlock()
+}
+
+// sleepCtx: d만큼 대기, ctx가 먼저 끝나면 false
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// send: 1회 _bulk 요청. 성공 항목은 OnSuccess, 영구 실패는 OnFailure, 재시도 대상만 반환
+func (w *BulkWriter) send(items []*BulkItem) []*BulkItem {
+	var body bytes.Buffer
+	for _, item := range items {
+		op := "index"
+		if item.Create {
+			op = "create"
+		}
+		action, _ := json.Marshal(map[string]interface{}{op: map[string]string{"_index": item.Index, "_id": item.ID}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(item.Doc)
+		body.WriteByte('\n')
+	}
+
+	start := time.Now()
+	result, err := w.os.Bulk(body.Bytes())
+	w.stats.flushes++
+	w.stats.docs += len(items)
+	w.stats.bytes += body.Len()
+	w.stats.latency += time.Since(start)
+	if err != nil {
+		// 요청 자체 실패(연결/429/5xx)는 전체 재시도, 그 외 거부(4xx)는 전체 실패
+		for _, item := range items {
+			item.Status, item.LastErr = 0, err.Error()
+		}
+		var osErr *OSError
+		if !errors.As(err, &osErr) || errors.Is(err, ErrOSTransient) {
+			return items
+		}
+		for _, item := range items {
+			w.fail(item)
+		}
+		return nil
+	}
+
+	respItems, _ := result["items"].([]interface{})
+	if len(respItems) != len(items) {
+		for _, item := range items {
+			item.LastErr = fmt.Sprintf("bulk 응답 항목 수 불일치 (%d/%d)", len(respItems), len(items))
+		}
+		return items
+	}
+
+	var retry []*BulkItem
+	for i, raw := range respItems {
+		item := items[i]
+		entry, _ := raw.(map[string]interface{})
+		action, _ := entry["index"].(map[string]interface{})
+		if action == nil {
+			action, _ = entry["create"].(map[string]interface{})
+		}
+		status, _ := action["status"].(float64)
+		item.Status = int(status)
+		if status >= 200 && status < 300 || (status == 409 && item.Create) {
+			if status == 409 {
+				w.stats.existing++
+			}
+			if item.OnSuccess != nil {
+				item.OnSuccess()
+			}
+			continue
+		}
+		item.LastErr = fmt.Sprintf("status %d: %v", int(status), action["error"])
+		if isTransientStatus(int(status)) {
+			retry = append(retry, item)
+		} else {
+			w.fail(item)
+		}
+	}
+	return retry
+}
+
+func (w *BulkWriter) fail(item *BulkItem) {
+	w.stats.failed++
+	w.OnFailure(item)
+}
+
+func (w *BulkWriter) logStats() {
+	w.flushMu.Lock()
+	s := w.stats
+	w.stats = bulkStats{}
+	w.flushMu.Unlock()
+	if s.flushes == 0 {
+		return
+	}
+	log.Printf("[%s] bulk 통계: flush %d회, 평균 %d건/%dKB, 평균 지연 %dms, 재시도 %d건, 실패 %d건, 기존 문서 %d건",
+		w.opts.Name, s.flushes, s.docs/s.flushes, s.bytes/s.flushes/1024, (s.latency / time.Duration(s.flushes)).Milliseconds(),
+		s.retried, s.failed, s.existing)
+}
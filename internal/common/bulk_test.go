@@ -0,0 +1,215 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestBulkWriterFlushThresholds: 건수/크기 임계치에 도달한 AddItem만 즉시 flush, 나머지는 Flush까지 버퍼에 보관
+func TestBulkWriterFlushThresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        BulkOptions
+		docs        int
+		wantIndexed int // Flush 호출 전 색인된 건수
+	}{
+		{"below thresholds", BulkOptions{MaxDocs: 10, MaxBytes: 1 << 20}, 5, 0},
+		{"max docs", BulkOptions{MaxDocs: 3, MaxBytes: 1 << 20}, 7, 6},
+		{"max bytes", BulkOptions{MaxDocs: 100, MaxBytes: 40}, 5, 4}, // 문서당 20 bytes → 2건마다 flush
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			tt.opts.Name = "test"
+			w := NewBulkWriter(s, tt.opts)
+			acked := 0
+			for i := 0; i < tt.docs; i++ {
+				w.AddItem(&BulkItem{
+					Index:     "bulk-test",
+					Doc:       []byte(fmt.Sprintf(`{"seq":"%010d"}`, i)),
+					OnSuccess: func() { acked++ },
+				})
+			}
+			if n, _ := s.Count("bulk-test", nil); n != tt.wantIndexed || acked != tt.wantIndexed {
+				t.Errorf("before Flush: indexed %d, acked %d, want %d", n, acked, tt.wantIndexed)
+			}
+			w.Flush(context.Background())
+			if n, _ := s.Count("bulk-test", nil); n != tt.docs || acked != tt.docs {
+				t.Errorf("after Flush: indexed %d, acked %d, want %d", n, acked, tt.docs)
+			}
+		})
+	}
+}
+
+// TestBulkWriterItemOutcome: 항목별 응답 상태에 따라 OnSuccess/OnFailure 중 하나만 호출
+func TestBulkWriterItemOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		item        BulkItem
+		wantSuccess bool
+		wantStatus  int
+	}{
+		{"index", BulkItem{Index: "bulk-test", ID: "new"}, true, 201},
+		{"overwrite", BulkItem{Index: "bulk-test", ID: "existing"}, true, 200},
+		{"create existing is success", BulkItem{Index: "bulk-test", ID: "existing", Create: true}, true, 409},
+		{"rejected", BulkItem{Index: "", ID: "x"}, false, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			if err := s.Put("bulk-test", "existing", map[string]interface{}{"v": 0}); err != nil {
+				t.Fatal(err)
+			}
+			w := NewBulkWriter(s, BulkOptions{Name: "test"})
+			succeeded, failed := 0, 0
+			w.OnFailure = func(*BulkItem) { failed++ }
+			item := tt.item
+			item.Doc = []byte(`{"v":1}`)
+			item.OnSuccess = func() { succeeded++ }
+			w.AddItem(&item)
+			w.Flush(context.Background())
+
+			if tt.wantSuccess && (succeeded != 1 || failed != 0) || !tt.wantSuccess && (succeeded != 0 || failed != 1) {
+				t.Errorf("succeeded %d, failed %d, want success=%v", succeeded, failed, tt.wantSuccess)
+			}
+			if item.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", item.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// flakyStore: 호출 순서별 장애를 흉내 내는 Store (장애 목록을 다 쓰면 정상 동작)
+//
+//	"lost"     — 적용 후 503 (색인됐지만 응답 유실)
+//	"down"     — 적용하지 않고 503
+//	"rejected" — 적용하지 않고 400
+//	"item429"  — 적용 후 첫 항목만 429로 응답
+type flakyStore struct {
+	*MemoryStore
+	faults []string
+	calls  int
+}
+
+func (s *flakyStore) Bulk(body []byte) (map[string]interface{}, error) {
+	fault := ""
+	if s.calls < len(s.faults) {
+		fault = s.faults[s.calls]
+	}
+	s.calls++
+	switch fault {
+	case "down":
+		return nil, &OSError{Op: "bulk", Status: 503, Body: "unavailable"}
+	case "rejected":
+		return nil, &OSError{Op: "bulk", Status: 400, Body: "bad request"}
+	}
+	result, err := s.MemoryStore.Bulk(body)
+	if err != nil {
+		return nil, err
+	}
+	switch fault {
+	case "lost":
+		return nil, &OSError{Op: "bulk", Status: 503, Body: "timeout"}
+	case "item429":
+		first := result["items"].([]interface{})[0].(map[string]interface{})
+		for _, action := range first {
+			action.(map[string]interface{})["status"] = float64(429)
+		}
+	}
+	return result, nil
+}
+
+// TestBulkWriterRetry: 일시 오류는 같은 _id로 재전송 (create 409는 성공), 재시도 소진분은 버퍼에 보류, 거부는 실패 처리
+func TestBulkWriterRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		faults       []string
+		maxRetries   int
+		ids          bool // 호출측 _id 지정 (index로 덮어쓰기)
+		wantCalls    int
+		wantAcked    int
+		wantFailed   int
+		wantBuffered int
+		wantExisting int
+	}{
+		{name: "no faults", maxRetries: 3, wantCalls: 1, wantAcked: 3},
+		{name: "response lost", faults: []string{"lost"}, maxRetries: 3, wantCalls: 2, wantAcked: 3, wantExisting: 3},
+		{name: "response lost with ids", faults: []string{"lost"}, maxRetries: 3, ids: true, wantCalls: 2, wantAcked: 3},
+		{name: "unavailable then ok", faults: []string{"down", "down"}, maxRetries: 3, wantCalls: 3, wantAcked: 3},
+		{name: "item 429", faults: []string{"item429"}, maxRetries: 3, wantCalls: 2, wantAcked: 3, wantExisting: 1},
+		{name: "retries exhausted", faults: []string{"down", "down"}, maxRetries: 1, wantCalls: 2, wantBuffered: 3},
+		{name: "rejected", faults: []string{"rejected"}, maxRetries: 3, wantCalls: 1, wantFailed: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &flakyStore{MemoryStore: NewMemoryStore(), faults: tt.faults}
+			w := NewBulkWriter(s, BulkOptions{
+				Name: "test", MaxRetries: tt.maxRetries, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond,
+			})
+			failed := 0
+			w.OnFailure = func(*BulkItem) { failed++ }
+			acks := make([]int, 3)
+			items := make([]*BulkItem, 3)
+			for i := range items {
+				i := i
+				items[i] = &BulkItem{Index: "bulk-test", Doc: []byte(fmt.Sprintf(`{"seq":%d}`, i)), OnSuccess: func() { acks[i]++ }}
+				if tt.ids {
+					items[i].ID = fmt.Sprintf("doc-%d", i)
+				}
+				w.AddItem(items[i])
+			}
+			for i, item := range items {
+				if item.ID == "" || item.Create == tt.ids {
+					t.Errorf("item %d: id=%q create=%v, want id assigned and create=%v", i, item.ID, item.Create, !tt.ids)
+				}
+			}
+			w.Flush(context.Background())
+
+			acked := 0
+			for i, n := range acks {
+				if n > 1 {
+					t.Errorf("item %d acked %d times", i, n)
+				}
+				acked += n
+			}
+			if s.calls != tt.wantCalls || acked != tt.wantAcked || failed != tt.wantFailed || len(w.items) != tt.wantBuffered {
+				t.Errorf("calls/acked/failed/buffered = %d/%d/%d/%d, want %d/%d/%d/%d", s.calls, acked, failed, len(w.items),
+					tt.wantCalls, tt.wantAcked, tt.wantFailed, tt.wantBuffered)
+			}
+			if w.stats.existing != tt.wantExisting {
+				t.Errorf("existing = %d, want %d", w.stats.existing, tt.wantExisting)
+			}
+			if n, _ := s.Count("bulk-test", nil); n > 3 {
+				t.Errorf("indexed %d docs, want no duplicates", n)
+			}
+
+			// 보류된 항목은 다음 flush에서 같은 _id로 전송
+			if tt.wantBuffered > 0 {
+				w.Flush(context.Background())
+				if n, _ := s.Count("bulk-test", nil); n != 3 || acks[0]+acks[1]+acks[2] != 3 || len(w.items) != 0 {
+					t.Errorf("after recovery: indexed %d, acks %v, buffered %d", n, acks, len(w.items))
+				}
+			}
+		})
+	}
+}
+
+// TestBulkWriterFlushCancel: 재시도 대기 중 ctx가 끝나면 항목을 버퍼에 되돌리고 반환
+func TestBulkWriterFlushCancel(t *testing.T) {
+	s := &flakyStore{MemoryStore: NewMemoryStore(), faults: []string{"down", "down"}}
+	w := NewBulkWriter(s, BulkOptions{Name: "test", MaxRetries: 5, Backoff: 10 * time.Second})
+	w.Add("bulk-test", "a", map[string]interface{}{"v": 1})
+	w.Add("bulk-test", "b", map[string]interface{}{"v": 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	w.Flush(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Flush returned after %v, want prompt return", elapsed)
+	}
+	if len(w.items) != 2 || w.items[0].ID != "a" || w.items[1].ID != "b" {
+		t.Errorf("buffered = %d items, want a, b in order", len(w.items))
+	}
+}
@@ -1,6 +1,9 @@
 package common
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // ExpandCEFLabels: *Label 접미사 키를 찾아 label 이름으로 값을 매핑
 // 예: cs1Label="Config Type", cs1="ipchange" → ConfigType="ipchange"
@@ -20,3 +23,123 @@ func ExpandCEFLabels(ext map[string]interface{}) {
 		}
 	}
 }
+
+// cefHeaderFields: "CEF:Version|" 뒤 헤더 필드 순서
+var cefHeaderFields = []string{"deviceVendor", "deviceProduct", "deviceVersion", "signatureId", "name", "severity"}
+
+// ParseCEF: raw CEF 문자열 → LogSink 이벤트 구조 (헤더 필드 + cefExtensions)
+// 예: CEF:0|MarkAny|SafePC|5.0|MESSAGE_DEVICE_USAGE|Device|3|suid=u1 fname=a b.txt
+// → msgId=MESSAGE_DEVICE_USAGE, appName=SafePC, severity=3, cefExtensions={suid:u1, fname:"a b.txt"}
+// "CEF:" 앞의 syslog 헤더 등은 무시한다. 헤더의 \| \\, 확장의 \= \\ \n \r 이스케이프를 처리한다.
+func ParseCEF(line string) (map[string]interface{}, error) {
+	start := strings.Index(line, "CEF:")
+	if start < 0 {
+		return nil, fmt.Errorf("CEF 접두사 없음")
+	}
+	rest := line[start+len("CEF:"):]
+
+	// 헤더: 이스케이프되지 않은 | 7개로 구분 (Version + 6개 필드), 나머지는 확장
+	header := make([]string, 0, 7)
+	var cur strings.Builder
+	i := 0
+	for ; i < len(rest) && len(header) < 7; i++ {
+		c := rest[i]
+		if c == '\\' && i+1 < len(rest) && (rest[i+1] == '|' || rest[i+1] == '\\') {
+			cur.WriteByte(rest[i+1])
+			i++
+			continue
+		}
+		if c == '|' {
+			header = append(header, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	if len(header) < 7 {
+		return nil, fmt.Errorf("CEF 헤더 필드 부족 (%d/7)", len(header))
+	}
+
+	event := map[string]interface{}{"cefVersion": strings.TrimSpace(header[0])}
+	for j, name := range cefHeaderFields {
+		event[name] = header[j+1]
+	}
+	event["msgId"] = event["signatureId"]
+	event["appName"] = event["deviceProduct"]
+
+	ext := ParseCEFExtensions(rest[i:])
+	if host, ok := ext["dvchost"].(string); ok && host != "" {
+		event["hostname"] = host
+	} else if host, ok := ext["shost"].(string); ok && host != "" {
+		event["hostname"] = host
+	}
+	event["cefExtensions"] = ext
+	return event, nil
+}
+
+// ParseCEFExtensions: "k1=v1 k2=v 2 ..." → map
+// 값에는 공백이 포함될 수 있으며, 다음 "key=" 직전까지가 값이다.
+// 이스케이프되지 않은 = 앞 토큰이 유효한 키가 아니면 값의 일부로 취급한다.
+func ParseCEFExtensions(s string) map[string]interface{} {
+	ext := make(map[string]interface{})
+	type pair struct{ keyStart, eq int }
+	var pairs []pair
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++ // 이스케이프 문자 건너뜀
+			continue
+		}
+		if s[i] != '=' {
+			continue
+		}
+		ks := strings.LastIndexByte(s[:i], ' ') + 1
+		if !isCEFKey(s[ks:i]) {
+			continue
+		}
+		pairs = append(pairs, pair{ks, i})
+	}
+	for n, p := range pairs {
+		end := len(s)
+		if n+1 < len(pairs) {
+			end = pairs[n+1].keyStart
+		}
+		key := s[p.keyStart:p.eq]
+		ext[key] = unescapeCEFValue(strings.TrimRight(s[p.eq+1:end], " "))
+	}
+	return ext
+}
+
+func isCEFKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-' || c == '[' || c == ']') {
+			return false
+		}
+	}
+	return true
+}
+
+func unescapeCEFValue(v string) string {
+	if !strings.Contains(v, "\\") {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+			switch v[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default: // \= \\ \|
+				b.WriteByte(v[i])
+			}
+			continue
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseCEF: 헤더 필드/이스케이프/syslog 접두사와 hostname 선택
+func TestParseCEF(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    map[string]interface{} // 확인할 최상위 필드 (cefExtensions 제외)
+		wantExt map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "basic",
+			line: "CEF:0|MarkAny|SafePC|5.0|MESSAGE_DEVICE_USAGE|Device|3|suid=u1 fname=a b.txt",
+			want: map[string]interface{}{
+				"cefVersion": "0", "deviceVendor": "MarkAny", "deviceProduct": "SafePC", "deviceVersion": "5.0",
+				"signatureId": "MESSAGE_DEVICE_USAGE", "name": "Device", "severity": "3",
+				"msgId": "MESSAGE_DEVICE_USAGE", "appName": "SafePC",
+			},
+			wantExt: map[string]interface{}{"suid": "u1", "fname": "a b.txt"},
+		},
+		{
+			name:    "syslog prefix ignored, dvchost wins over shost",
+			line:    "<134>Jan  1 00:00:00 host1 CEF:0|V|P|1|ID|N|5|shost=pc-2 dvchost=pc-1",
+			want:    map[string]interface{}{"msgId": "ID", "hostname": "pc-1"},
+			wantExt: map[string]interface{}{"shost": "pc-2", "dvchost": "pc-1"},
+		},
+		{
+			name:    "shost as hostname",
+			line:    "CEF:0|V|P|1|ID|N|5|shost=pc-2",
+			want:    map[string]interface{}{"hostname": "pc-2"},
+			wantExt: map[string]interface{}{"shost": "pc-2"},
+		},
+		{
+			name:    "escaped pipe and backslash in header",
+			line:    `CEF:0|V|P\|Q|1|ID|a\\b|5|k=v`,
+			want:    map[string]interface{}{"deviceProduct": "P|Q", "name": `a\b`, "appName": "P|Q"},
+			wantExt: map[string]interface{}{"k": "v"},
+		},
+		{
+			name:    "escaped equals, backslash and newline in extension",
+			line:    `CEF:0|V|P|1|ID|N|5|request=a\=b msg=line1\nline2 path=c:\\tmp`,
+			want:    map[string]interface{}{},
+			wantExt: map[string]interface{}{"request": "a=b", "msg": "line1\nline2", "path": `c:\tmp`},
+		},
+		{
+			name:    "equals inside value without valid key stays in value",
+			line:    "CEF:0|V|P|1|ID|N|5|msg=x =y",
+			want:    map[string]interface{}{},
+			wantExt: map[string]interface{}{"msg": "x =y"},
+		},
+		{
+			name:    "empty extension",
+			line:    "CEF:0|V|P|1|ID|N|5|",
+			want:    map[string]interface{}{"msgId": "ID"},
+			wantExt: map[string]interface{}{},
+		},
+		{name: "no prefix", line: "LEEF:1.0|V|P|1|ID|", wantErr: true},
+		{name: "short header", line: "CEF:0|V|P|1|ID|N", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseCEF(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCEF(%q) = %v, want error", tt.line, event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCEF(%q): %v", tt.line, err)
+			}
+			for k, v := range tt.want {
+				if event[k] != v {
+					t.Errorf("%s = %v, want %v", k, event[k], v)
+				}
+			}
+			if ext := event["cefExtensions"]; !reflect.DeepEqual(ext, tt.wantExt) {
+				t.Errorf("cefExtensions = %v, want %v", ext, tt.wantExt)
+			}
+		})
+	}
+}
+
+// TestExpandCEFLabels: csN/csNLabel 쌍을 label 이름(공백 제거)으로 추가
+func TestExpandCEFLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "label pair",
+			ext:  map[string]interface{}{"cs1Label": "Config Type", "cs1": "ipchange"},
+			want: map[string]interface{}{"cs1Label": "Config Type", "cs1": "ipchange", "ConfigType": "ipchange"},
+		},
+		{
+			name: "empty value skipped",
+			ext:  map[string]interface{}{"cs2Label": "Policy", "cs2": ""},
+			want: map[string]interface{}{"cs2Label": "Policy", "cs2": ""},
+		},
+		{
+			name: "label without value",
+			ext:  map[string]interface{}{"cn1Label": "Count"},
+			want: map[string]interface{}{"cn1Label": "Count"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ExpandCEFLabels(tt.ext)
+			if !reflect.DeepEqual(tt.ext, tt.want) {
+				t.Errorf("got %v, want %v", tt.ext, tt.want)
+			}
+		})
+	}
+}
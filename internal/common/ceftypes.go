@@ -0,0 +1,120 @@
+package common
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CEF 확장 값 타입
+const (
+	CEFTypeLong   = "long"
+	CEFTypeDouble = "double"
+)
+
+// cefStandardTypes: CEF 표준 확장 키 중 숫자 타입 (그 외 키는 문자열)
+var cefStandardTypes = map[string]string{
+	"cn1": CEFTypeLong, "cn2": CEFTypeLong, "cn3": CEFTypeLong,
+	"cfp1": CEFTypeDouble, "cfp2": CEFTypeDouble, "cfp3": CEFTypeDouble, "cfp4": CEFTypeDouble,
+	"cnt": CEFTypeLong, "fsize": CEFTypeLong, "oldFileSize": CEFTypeLong,
+	"in": CEFTypeLong, "out": CEFTypeLong,
+	"spt": CEFTypeLong, "dpt": CEFTypeLong, "sourceTranslatedPort": CEFTypeLong, "destinationTranslatedPort": CEFTypeLong,
+	"spid": CEFTypeLong, "dpid": CEFTypeLong, "dvcpid": CEFTypeLong,
+	"slong": CEFTypeDouble, "slat": CEFTypeDouble, "dlong": CEFTypeDouble, "dlat": CEFTypeDouble,
+}
+
+// CEFFieldType: 표준 확장 키의 숫자 타입 (숫자 키가 아니면 빈값)
+func CEFFieldType(key string) string {
+	return cefStandardTypes[key]
+}
+
+// CEFNumericKeys: 숫자 타입 표준 키 목록 (이름 순)
+func CEFNumericKeys() []string {
+	keys := make([]string, 0, len(cefStandardTypes))
+	for k := range cefStandardTypes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CEFValueType: cefExtensions 키의 숫자 타입 (표준 키 → label 확장 이름 → field-meta 순, 숫자가 아니면 빈값)
+// LogSink 타입 변환과 UEBA 쿼리 필드 경로가 같은 규칙을 쓰도록 공유한다
+func CEFValueType(key string, labelTypes, metaTypes map[string]string) string {
+	if typ := CEFFieldType(key); typ != "" {
+		return typ
+	}
+	if typ := labelTypes[key]; typ != "" {
+		return typ
+	}
+	return metaTypes[key]
+}
+
+// CEFLabelTypes: *Label로 확장된 이름 → 원본 키 타입 (예: cn1Label="File Count" → FileCount: long)
+// ExpandCEFLabels와 같은 규칙(공백 제거)으로 이름을 만든다
+func CEFLabelTypes(ext map[string]interface{}) map[string]string {
+	types := make(map[string]string)
+	for k, v := range ext {
+		if !strings.HasSuffix(k, "Label") {
+			continue
+		}
+		label, _ := v.(string)
+		if typ := CEFFieldType(strings.TrimSuffix(k, "Label")); typ != "" && label != "" {
+			types[strings.ReplaceAll(label, " ", "")] = typ
+		}
+	}
+	return types
+}
+
+// FieldMetaTypes: field-meta에서 inputType=number 로 정의된 필드 → double
+// 같은 필드명은 인덱스 매핑을 공유하므로 이벤트(msgId) 구분 없이 필드명 단위로 모은다
+func FieldMetaTypes(os Store, indexPrefix string) (map[string]string, error) {
+	doc, err := os.Get(FieldMetaIndex(indexPrefix), "meta-latest")
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string)
+	events, _ := doc["events"].(map[string]interface{})
+	for _, raw := range events {
+		ev, _ := raw.(map[string]interface{})
+		fields, _ := ev["fields"].(map[string]interface{})
+		for name, f := range fields {
+			fm, _ := f.(map[string]interface{})
+			if virtual, _ := fm["virtual"].(bool); virtual {
+				continue
+			}
+			if fm["inputType"] == "number" {
+				types[name] = CEFTypeDouble
+			}
+		}
+	}
+	return types, nil
+}
+
+// ParseCEFNumber: 타입에 맞게 숫자 변환. canonical=false면 원본 문자열과 표기가 달라 정보가 손실됨 (예: "0080")
+func ParseCEFNumber(s, typ string) (val interface{}, canonical bool, ok bool) {
+	s = strings.TrimSpace(s)
+	switch typ {
+	case CEFTypeLong:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, strconv.FormatInt(n, 10) == s, true
+		}
+		// "12.0" 등 정수값 실수 표기
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == float64(int64(f)) {
+			return int64(f), false, true
+		}
+	case CEFTypeDouble:
+		// NaN/Inf는 JSON으로 표현 불가
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f, !hasLeadingZero(s), true
+		}
+	}
+	return nil, false, false
+}
+
+// hasLeadingZero: "007", "-01.5" 처럼 숫자 변환 시 사라지는 앞자리 0
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] != '.'
+}
@@ -0,0 +1,65 @@
+package common
+
+import "testing"
+
+// TestParseCEFNumber: 타입별 변환과 원본 표기 보존 여부(canonical)
+func TestParseCEFNumber(t *testing.T) {
+	tests := []struct {
+		s, typ        string
+		want          interface{}
+		wantCanonical bool
+		wantOK        bool
+	}{
+		{"42", CEFTypeLong, int64(42), true, true},
+		{" -7 ", CEFTypeLong, int64(-7), true, true},
+		{"0080", CEFTypeLong, int64(80), false, true},
+		{"+5", CEFTypeLong, int64(5), false, true},
+		{"12.0", CEFTypeLong, int64(12), false, true},
+		{"12.5", CEFTypeLong, nil, false, false},
+		{"abc", CEFTypeLong, nil, false, false},
+		{"", CEFTypeLong, nil, false, false},
+		{"1.5", CEFTypeDouble, 1.5, true, true},
+		{"3", CEFTypeDouble, 3.0, true, true},
+		{"0.5", CEFTypeDouble, 0.5, true, true},
+		{"-01.5", CEFTypeDouble, -1.5, false, true},
+		{"NaN", CEFTypeDouble, nil, false, false},
+		{"Inf", CEFTypeDouble, nil, false, false},
+		{"1", "string", nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+"/"+tt.s, func(t *testing.T) {
+			got, canonical, ok := ParseCEFNumber(tt.s, tt.typ)
+			if got != tt.want || canonical != tt.wantCanonical || ok != tt.wantOK {
+				t.Errorf("ParseCEFNumber(%q, %s) = %#v, %v, %v; want %#v, %v, %v",
+					tt.s, tt.typ, got, canonical, ok, tt.want, tt.wantCanonical, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestCEFValueType: 표준 키 → label 확장 이름 → field-meta 순 우선
+func TestCEFValueType(t *testing.T) {
+	labelTypes := CEFLabelTypes(map[string]interface{}{
+		"cn1Label": "File Count", "cfp1Label": "Ratio", "cs1Label": "Policy", "cn2Label": "",
+	})
+	metaTypes := map[string]string{"Ratio": CEFTypeDouble, "fsize": CEFTypeDouble, "score": CEFTypeDouble}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"fsize", CEFTypeLong}, // 표준 키가 field-meta보다 우선
+		{"cfp1", CEFTypeDouble},
+		{"FileCount", CEFTypeLong},
+		{"Ratio", CEFTypeDouble},
+		{"Policy", ""}, // 문자열 키의 label
+		{"score", CEFTypeDouble},
+		{"suid", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := CEFValueType(tt.key, labelTypes, metaTypes); got != tt.want {
+				t.Errorf("CEFValueType(%s) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
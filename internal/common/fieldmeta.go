@@ -11,11 +11,11 @@ import (
 
 
 type FieldMetaController struct {
-	OS          *OSClient
+	OS          Store
 	IndexPrefix string
 }
 
-func NewFieldMetaController(os *OSClient, indexPrefix string) *FieldMetaController {
+func NewFieldMetaController(os Store, indexPrefix string) *FieldMetaController {
 	return &FieldMetaController{OS: os, IndexPrefix: indexPrefix}
 }
 
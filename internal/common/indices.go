@@ -40,6 +40,10 @@ func FieldMetaIndex(prefix string) string {
 	return fmt.Sprintf("%s-%s-common-field-meta", prefix, solution)
 }
 
+func RetentionAuditIndex(prefix string) string {
+	return fmt.Sprintf("%s-%s-common-retention-audit", prefix, solution)
+}
+
 func LogsIndexPattern(prefix string) string {
 	return fmt.Sprintf("%s-%s-event-logs-*", prefix, solution)
 }
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	iteratePageSize  = 1000
+	iterateKeepAlive = "1m"
+)
+
+// SearchHit: Iterate가 넘기는 문서 1건
+type SearchHit struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Source json.RawMessage `json:"_source"`
+	Sort   []interface{}   `json:"sort"`
+}
+
+// Iterate: query에 맞는 모든 문서를 PIT(point-in-time) + search_after로 순회
+//
+// size 제한 없이 끝까지 읽으며, 순회 중 색인된 문서는 보이지 않는다 (PIT 시점 기준).
+// PIT 페이지는 _shard_doc(문서 고유 순서 — _id 정렬처럼 fielddata를 쓰지 않음) 순이고,
+// PIT를 지원하지 않는 클러스터는 PIT 없이 _id 순 search_after로만 순회한다.
+// fn이 error를 반환하거나 ctx가 취소되면(진행 중인 요청 포함) 중단하고 그 error를 반환한다. query가 nil이면 match_all.
+func (c *OSClient) Iterate(ctx context.Context, index string, query interface{}, fn func(hit SearchHit) error) error {
+	if query == nil {
+		query = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	pitID, err := c.openPIT(ctx, index)
+	if err != nil {
+		return err
+	}
+	if pitID != "" {
+		// 응답마다 pit_id가 갱신될 수 있으므로 마지막 ID로 닫는다 (ctx가 취소됐어도 닫도록 ctx 없이)
+		defer func() {
+			c.do("close PIT", "DELETE", "/_search/point_in_time", "application/json",
+				[]byte(fmt.Sprintf(`{"pit_id":[%q]}`, pitID)))
+		}()
+	}
+
+	var searchAfter []interface{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body := map[string]interface{}{
+			"size":  iteratePageSize,
+			"query": query,
+			"sort":  []interface{}{map[string]interface{}{"_id": "asc"}},
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+		path := "/" + index + "/_search"
+		if pitID != "" {
+			// PIT 검색은 인덱스 없이 요청 (PIT가 대상 인덱스를 고정)
+			body["pit"] = map[string]interface{}{"id": pitID, "keep_alive": iterateKeepAlive}
+			body["sort"] = []interface{}{map[string]interface{}{"_shard_doc": "asc"}}
+			path = "/_search"
+		}
+		data, _ := json.Marshal(body)
+		_, resp, err := c.doCtx(ctx, "search", "POST", path, "application/json", data)
+		if errors.Is(err, ErrOSNotFound) && pitID == "" {
+			return nil // 인덱스 없음
+		}
+		if err != nil {
+			return err
+		}
+		var page struct {
+			PitID string `json:"pit_id"`
+			Hits  struct {
+				Hits []SearchHit `json:"hits"`
+			} `json:"hits"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return fmt.Errorf("OpenSearch 검색 응답 파싱 실패: %v", err)
+		}
+		if pitID != "" && page.PitID != "" {
+			pitID = page.PitID
+		}
+		for _, hit := range page.Hits.Hits {
+			if err := fn(hit); err != nil {
+				return err
+			}
+		}
+		if len(page.Hits.Hits) < iteratePageSize {
+			return nil
+		}
+		searchAfter = page.Hits.Hits[len(page.Hits.Hits)-1].Sort
+	}
+}
+
+// openPIT: PIT 생성 (인덱스가 없거나 PIT 미지원이면 빈 ID)
+func (c *OSClient) openPIT(ctx context.Context, index string) (string, error) {
+	_, resp, err := c.doCtx(ctx, "create PIT", "POST", "/"+index+"/_search/point_in_time?keep_alive="+iterateKeepAlive, "", nil)
+	if errors.Is(err, ErrOSRejected) || errors.Is(err, ErrOSNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		PitID string `json:"pit_id"`
+	}
+	json.Unmarshal(resp, &result)
+	return result.PitID, nil
+}
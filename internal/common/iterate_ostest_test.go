@@ -0,0 +1,133 @@
+package common_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/ostest"
+)
+
+// TestIterateAgainstOSTest: PIT + search_after로 페이지 크기를 넘는 문서를 빠짐없이 순회, 중단 시 error 반환과 PIT 정리
+// (OSClient와 MemoryStore가 같은 결과를 내야 함)
+func TestIterateAgainstOSTest(t *testing.T) {
+	srv := ostest.NewServer()
+	defer srv.Close()
+	client := srv.OSClient()
+
+	const total = 2500 // 페이지(1000건) 3개
+	var body bytes.Buffer
+	for i := 0; i < total; i++ {
+		kind := "even"
+		if i%2 == 1 {
+			kind = "odd"
+		}
+		fmt.Fprintf(&body, "{\"create\":{\"_index\":\"iter-test\",\"_id\":\"d%05d\"}}\n{\"seq\":%d,\"kind\":%q}\n", i, i, kind)
+	}
+	body.WriteString("{\"create\":{\"_index\":\"iter-other\",\"_id\":\"x1\"}}\n{\"kind\":\"even\"}\n")
+	if _, err := client.Bulk(body.Bytes()); err != nil {
+		t.Fatalf("Bulk: %v", err)
+	}
+	client.Refresh("iter-*")
+
+	errStop := errors.New("stop")
+	tests := []struct {
+		name      string
+		index     string
+		query     interface{}
+		stopAfter int // 0이면 끝까지
+		cancel    bool
+		want      int
+		wantErr   error
+	}{
+		{name: "match all across pages", index: "iter-test", want: total},
+		{name: "term query", index: "iter-test", query: map[string]interface{}{"term": map[string]interface{}{"kind": "odd"}}, want: total / 2},
+		{name: "index pattern", index: "iter-*", query: map[string]interface{}{"term": map[string]interface{}{"kind": "even"}}, want: total/2 + 1},
+		{name: "missing index", index: "iter-none", want: 0},
+		{name: "fn error stops", index: "iter-test", stopAfter: 1500, want: 1500, wantErr: errStop},
+		{name: "context canceled", index: "iter-test", cancel: true, wantErr: context.Canceled},
+	}
+	stores := []struct {
+		name  string
+		store common.Store
+	}{
+		{"opensearch", client},
+		{"memory", srv.Store()},
+	}
+	for _, st := range stores {
+		for _, tt := range tests {
+			t.Run(st.name+"/"+tt.name, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				var ids []string
+				seen := map[string]bool{}
+				err := st.store.Iterate(ctx, tt.index, tt.query, func(hit common.SearchHit) error {
+					if tt.stopAfter > 0 && len(ids) == tt.stopAfter {
+						return errStop
+					}
+					if seen[hit.Index+"/"+hit.ID] {
+						t.Errorf("duplicate hit %s/%s", hit.Index, hit.ID)
+					}
+					seen[hit.Index+"/"+hit.ID] = true
+					ids = append(ids, hit.ID)
+					if tt.cancel {
+						cancel()
+					}
+					return nil
+				})
+				if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Iterate err = %v, want %v", err, tt.wantErr)
+				}
+				if tt.cancel {
+					if len(ids) == 0 || len(ids) >= total {
+						t.Errorf("canceled after %d hits, want stop before the end", len(ids))
+					}
+				} else if len(ids) != tt.want {
+					t.Errorf("hits = %d, want %d", len(ids), tt.want)
+				}
+				if tt.index == "iter-test" && !sort.StringsAreSorted(ids) {
+					t.Errorf("hits not in document order")
+				}
+				if n := srv.OpenPITs(); n != 0 {
+					t.Errorf("open PITs = %d, want 0", n)
+				}
+			})
+		}
+	}
+}
+
+// TestSearchAll: Iterate 결과를 _source + "_id" 형태로 모두 수집
+func TestSearchAll(t *testing.T) {
+	s := common.NewMemoryStore()
+	for i := 0; i < 3; i++ {
+		s.Put("all-test", fmt.Sprintf("r%d", i), map[string]interface{}{"n": i})
+	}
+	tests := []struct {
+		name  string
+		index string
+		want  []string
+	}{
+		{"all docs", "all-test", []string{"r0", "r1", "r2"}},
+		{"missing index", "all-none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := common.SearchAll(context.Background(), s, tt.index, nil)
+			if err != nil {
+				t.Fatalf("SearchAll: %v", err)
+			}
+			if len(docs) != len(tt.want) {
+				t.Fatalf("docs = %d, want %d", len(docs), len(tt.want))
+			}
+			for i, doc := range docs {
+				if doc["_id"] != tt.want[i] || doc["n"] != float64(i) {
+					t.Errorf("doc %d = %v, want _id %s n %d", i, doc, tt.want[i], i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LEEFAliases: 벤더별 LEEF 속성 키 → cefExtensions 키
+// 키는 "Vendor" 또는 "Vendor|Product" (제품 항목이 벤더 항목보다, 벤더 항목이 기본 사전보다 우선)
+type LEEFAliases map[string]map[string]string
+
+// leefStandardKeys: LEEF 표준 속성 → CEF 확장 키 (CEP/UEBA 규칙이 보는 이름)
+var leefStandardKeys = map[string]string{
+	"src":           "src",
+	"dst":           "dst",
+	"srcPort":       "spt",
+	"dstPort":       "dpt",
+	"srcMAC":        "smac",
+	"dstMAC":        "dmac",
+	"usrName":       "suser",
+	"proto":         "proto",
+	"srcBytes":      "out",
+	"dstBytes":      "in",
+	"url":           "request",
+	"identHostName": "shost",
+	"domain":        "sntdom",
+	"cat":           "cat",
+}
+
+// LoadLEEFAliases: JSON 파일 ({"Vendor|Product": {"leefKey": "cefKey"}}), 경로가 빈값이면 nil
+func LoadLEEFAliases(path string) (LEEFAliases, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var aliases LEEFAliases
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return aliases, nil
+}
+
+// ParseRawEvent: CEF/LEEF 헤더 자동 판별 (먼저 나오는 접두사 기준)
+func ParseRawEvent(line string, aliases LEEFAliases) (map[string]interface{}, error) {
+	cef := strings.Index(line, "CEF:")
+	leef := strings.Index(line, "LEEF:")
+	if leef >= 0 && (cef < 0 || leef < cef) {
+		return ParseLEEF(line, aliases)
+	}
+	return ParseCEF(line)
+}
+
+// ParseLEEF: raw LEEF 1.0/2.0 문자열 → ParseCEF와 같은 이벤트 구조
+//
+//	LEEF:1.0|Vendor|Product|Version|EventID|k1=v1<TAB>k2=v2
+//	LEEF:2.0|Vendor|Product|Version|EventID|^|k1=v1^k2=v2   (구분자: 문자 1개 또는 0x5E/x5E, 빈값이면 탭)
+//	LEEF:2.0|Vendor|Product|Version|EventID|k1=v1<TAB>k2=v2 (구분자 필드 생략 — 탭)
+//
+// EventID → msgId, 속성은 표준 사전/벤더 별칭으로 CEF 키 이름으로 바꿔 cefExtensions에 담는다.
+// devTime은 devTimeFormat(Java 형식)으로 해석해 rt(epoch ms)로 넣는다.
+// 탭이 없는 LEEF 1.0(공백 구분으로 잘못 보내는 장비)은 CEF 확장 규칙으로 파싱한다.
+func ParseLEEF(line string, aliases LEEFAliases) (map[string]interface{}, error) {
+	start := strings.Index(line, "LEEF:")
+	if start < 0 {
+		return nil, fmt.Errorf("LEEF 접두사 없음")
+	}
+	rest := line[start+len("LEEF:"):]
+
+	// 헤더: Version|Vendor|Product|Version|EventID| (+ 2.0은 선택적 Delimiter|)
+	var header []string
+	i := 0
+	for ; i < len(rest) && len(header) < 5; i++ {
+		j := strings.IndexByte(rest[i:], '|')
+		if j < 0 {
+			break
+		}
+		header = append(header, rest[i:i+j])
+		i += j
+	}
+	if len(header) < 5 {
+		return nil, fmt.Errorf("LEEF 헤더 필드 부족 (%d/5)", len(header))
+	}
+
+	version := strings.TrimSpace(header[0])
+	delim := "\t"
+	if strings.HasPrefix(version, "2") {
+		if j := strings.IndexByte(rest[i:], '|'); j >= 0 && isLEEFDelimiterField(rest[i:i+j]) {
+			d, err := leefDelimiter(rest[i : i+j])
+			if err != nil {
+				return nil, err
+			}
+			delim = d
+			i += j + 1
+		}
+	}
+
+	attrs := rest[i:]
+	var raw map[string]interface{}
+	if delim == "\t" && !strings.Contains(attrs, "\t") && strings.Count(attrs, "=") > 1 {
+		raw = ParseCEFExtensions(attrs)
+	} else {
+		raw = parseLEEFAttributes(attrs, delim)
+	}
+
+	vendor, product := header[1], header[2]
+	event := map[string]interface{}{
+		"leefVersion":   version,
+		"deviceVendor":  vendor,
+		"deviceProduct": product,
+		"deviceVersion": header[3],
+		"signatureId":   header[4],
+		"msgId":         header[4],
+		"appName":       product,
+	}
+
+	keys := leefKeyMap(aliases, vendor, product)
+	ext := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		if mapped, ok := keys[k]; ok && mapped != "" {
+			ext[mapped] = v
+			continue
+		}
+		ext[k] = v
+	}
+	if sev, ok := raw["sev"].(string); ok && sev != "" {
+		event["severity"] = sev
+	}
+	if cat, ok := raw["cat"].(string); ok && cat != "" {
+		event["name"] = cat
+	}
+	if devTime, ok := raw["devTime"].(string); ok && devTime != "" {
+		if _, exists := ext["rt"]; !exists {
+			format, _ := raw["devTimeFormat"].(string)
+			ext["rt"] = leefDevTime(devTime, format)
+		}
+	}
+	if host, ok := ext["dvchost"].(string); ok && host != "" {
+		event["hostname"] = host
+	} else if host, ok := ext["shost"].(string); ok && host != "" {
+		event["hostname"] = host
+	}
+	event["cefExtensions"] = ext
+	return event, nil
+}
+
+// leefDelimiter: "^", "0x5E", "x5E" → 구분 문자 (빈값이면 탭)
+func leefDelimiter(s string) (string, error) {
+	switch {
+	case s == "":
+		return "\t", nil
+	case len(s) == 1:
+		return s, nil
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0x"), "x")
+	n, err := strconv.ParseUint(hex, 16, 8)
+	if err != nil || n == 0 {
+		return "", fmt.Errorf("잘못된 LEEF 구분자 '%s'", s)
+	}
+	return string(rune(n)), nil
+}
+
+// isLEEFDelimiterField: LEEF 2.0 헤더 6번째 필드가 구분자 형식인지 (빈값, 문자 1개, x../0x.. 16진)
+// 구분자 필드를 생략한 헤더는 이 자리에 첫 속성("k=v")이 온다
+func isLEEFDelimiterField(s string) bool {
+	if len(s) <= 1 {
+		return true
+	}
+	lower := strings.ToLower(s)
+	return len(s) <= 4 && (strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "x")) && !strings.Contains(s, "=")
+}
+
+// parseLEEFAttributes: 구분자로 나눈 "k=v" 목록 ('='가 없는 조각은 앞 값에 이어 붙임)
+func parseLEEFAttributes(s, delim string) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	last := ""
+	for _, part := range strings.Split(strings.TrimRight(s, "\r\n"), delim) {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok || !isCEFKey(k) {
+			if last != "" {
+				attrs[last] = attrs[last].(string) + delim + part
+			}
+			continue
+		}
+		attrs[k] = v
+		last = k
+	}
+	return attrs
+}
+
+// leefKeyMap: 기본 사전 + 벤더 + 벤더|제품 순으로 덮어쓴 키 매핑
+func leefKeyMap(aliases LEEFAliases, vendor, product string) map[string]string {
+	if aliases == nil {
+		return leefStandardKeys
+	}
+	vendorKeys, hasVendor := aliases[vendor]
+	productKeys, hasProduct := aliases[vendor+"|"+product]
+	if !hasVendor && !hasProduct {
+		return leefStandardKeys
+	}
+	keys := make(map[string]string, len(leefStandardKeys)+len(vendorKeys)+len(productKeys))
+	for k, v := range leefStandardKeys {
+		keys[k] = v
+	}
+	for k, v := range vendorKeys {
+		keys[k] = v
+	}
+	for k, v := range productKeys {
+		keys[k] = v
+	}
+	return keys
+}
+
+// javaLayoutTokens: Java SimpleDateFormat 토큰 → Go 레이아웃 (긴 토큰 우선)
+var javaLayoutTokens = []struct{ java, goLayout string }{
+	{"yyyy", "2006"}, {"yy", "06"},
+	{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"},
+	{"dd", "02"}, {"d", "2"},
+	{"HH", "15"}, {"hh", "03"}, {"mm", "04"}, {"ss", "05"},
+	{"SSS", "000"}, {"EEEE", "Monday"}, {"EEE", "Mon"},
+	{"a", "PM"}, {"zzz", "MST"}, {"z", "MST"}, {"XXX", "Z07:00"}, {"Z", "-0700"},
+}
+
+// leefDevTime: devTimeFormat으로 해석 가능하면 epoch ms 문자열, 아니면 원문 (타임스탬프 정규화 단계에서 재시도)
+func leefDevTime(value, javaFormat string) string {
+	if javaFormat == "" {
+		return value
+	}
+	var layout strings.Builder
+	for i := 0; i < len(javaFormat); {
+		// '...' 안은 리터럴 (예: yyyy-MM-dd'T'HH:mm:ss)
+		if javaFormat[i] == '\'' {
+			end := strings.IndexByte(javaFormat[i+1:], '\'')
+			if end < 0 {
+				end = len(javaFormat) - i - 1
+			}
+			layout.WriteString(javaFormat[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+		matched := false
+		for _, tok := range javaLayoutTokens {
+			if strings.HasPrefix(javaFormat[i:], tok.java) {
+				layout.WriteString(tok.goLayout)
+				i += len(tok.java)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			layout.WriteByte(javaFormat[i])
+			i++
+		}
+	}
+	t, err := time.ParseInLocation(layout.String(), value, Now().Location())
+	if err != nil {
+		return value
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestParseLEEF: 1.0/2.0 헤더와 구분자 필드, 표준 키/벤더 별칭, devTime 변환
+func TestParseLEEF(t *testing.T) {
+	InitTimezone("Asia/Seoul")
+	devTime := time.Date(2024, 1, 2, 9, 30, 0, 0, Now().Location())
+	aliases := LEEFAliases{
+		"Acme":      {"user": "suser", "host": "dvchost"},
+		"Acme|Edge": {"user": "duser"},
+	}
+	tests := []struct {
+		name    string
+		line    string
+		want    map[string]interface{} // 확인할 최상위 필드 (cefExtensions 제외)
+		wantExt map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "1.0 tab separated with standard keys",
+			line: "LEEF:1.0|Lancope|StealthWatch|1.0|41|src=10.0.0.1\tdstPort=443\tusrName=bob\tsev=5\tcat=Alarm\tidentHostName=pc-1",
+			want: map[string]interface{}{
+				"leefVersion": "1.0", "deviceVendor": "Lancope", "deviceProduct": "StealthWatch", "deviceVersion": "1.0",
+				"signatureId": "41", "msgId": "41", "appName": "StealthWatch", "severity": "5", "name": "Alarm", "hostname": "pc-1",
+			},
+			wantExt: map[string]interface{}{"src": "10.0.0.1", "dpt": "443", "suser": "bob", "sev": "5", "cat": "Alarm", "shost": "pc-1"},
+		},
+		{
+			name:    "1.0 space separated falls back to CEF extension rules",
+			line:    "<13>Jan  1 00:00:00 fw LEEF:1.0|V|P|1|E1|src=10.0.0.1 dst=10.0.0.2 msg=hello world",
+			want:    map[string]interface{}{"msgId": "E1"},
+			wantExt: map[string]interface{}{"src": "10.0.0.1", "dst": "10.0.0.2", "msg": "hello world"},
+		},
+		{
+			name:    "2.0 caret delimiter",
+			line:    "LEEF:2.0|V|P|1|E2|^|src=1.1.1.1^dst=2.2.2.2",
+			want:    map[string]interface{}{"leefVersion": "2.0", "msgId": "E2"},
+			wantExt: map[string]interface{}{"src": "1.1.1.1", "dst": "2.2.2.2"},
+		},
+		{
+			name:    "2.0 hex delimiter x5E",
+			line:    "LEEF:2.0|V|P|1|E2|x5E|src=1.1.1.1^msg=a^b",
+			wantExt: map[string]interface{}{"src": "1.1.1.1", "msg": "a^b"},
+		},
+		{
+			name:    "2.0 hex delimiter 0x7C",
+			line:    "LEEF:2.0|V|P|1|E2|0x7C|src=1.1.1.1|dst=2.2.2.2",
+			wantExt: map[string]interface{}{"src": "1.1.1.1", "dst": "2.2.2.2"},
+		},
+		{
+			name:    "2.0 empty delimiter field is tab",
+			line:    "LEEF:2.0|V|P|1|E2||src=1.1.1.1\tdst=2.2.2.2",
+			wantExt: map[string]interface{}{"src": "1.1.1.1", "dst": "2.2.2.2"},
+		},
+		{
+			name:    "2.0 without delimiter field",
+			line:    "LEEF:2.0|V|P|1|E2|src=1.1.1.1\tdst=2.2.2.2",
+			want:    map[string]interface{}{"msgId": "E2"},
+			wantExt: map[string]interface{}{"src": "1.1.1.1", "dst": "2.2.2.2"},
+		},
+		{
+			name:    "vendor and product aliases",
+			line:    "LEEF:1.0|Acme|Edge|1|E3|user=alice\thost=gw-1\tsrc=10.0.0.9",
+			want:    map[string]interface{}{"hostname": "gw-1"},
+			wantExt: map[string]interface{}{"duser": "alice", "dvchost": "gw-1", "src": "10.0.0.9"},
+		},
+		{
+			name:    "vendor alias only",
+			line:    "LEEF:1.0|Acme|Other|1|E3|user=alice",
+			wantExt: map[string]interface{}{"suser": "alice"},
+		},
+		{
+			name: "devTime with format",
+			line: "LEEF:1.0|V|P|1|E4|devTime=2024-01-02 09:30:00\tdevTimeFormat=yyyy-MM-dd HH:mm:ss",
+			wantExt: map[string]interface{}{
+				"devTime": "2024-01-02 09:30:00", "devTimeFormat": "yyyy-MM-dd HH:mm:ss",
+				"rt": strconv.FormatInt(devTime.UnixMilli(), 10),
+			},
+		},
+		{
+			name:    "devTime without format kept as is",
+			line:    "LEEF:1.0|V|P|1|E4|devTime=Jan 02 2024 09:30:00",
+			wantExt: map[string]interface{}{"devTime": "Jan 02 2024 09:30:00", "rt": "Jan 02 2024 09:30:00"},
+		},
+		{name: "no prefix", line: "CEF:0|V|P|1|E|N|1|", wantErr: true},
+		{name: "short header", line: "LEEF:1.0|V|P|src=1", wantErr: true},
+		{name: "bad hex delimiter", line: "LEEF:2.0|V|P|1|E|0xZZ|a=b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseLEEF(tt.line, aliases)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseLEEF(%q) = %v, want error", tt.line, event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLEEF(%q): %v", tt.line, err)
+			}
+			for k, want := range tt.want {
+				if event[k] != want {
+					t.Errorf("%s = %v, want %v", k, event[k], want)
+				}
+			}
+			if ext := event["cefExtensions"]; !reflect.DeepEqual(ext, tt.wantExt) {
+				t.Errorf("cefExtensions = %v, want %v", ext, tt.wantExt)
+			}
+		})
+	}
+}
+
+// TestParseRawEvent: 먼저 나오는 CEF:/LEEF: 접두사로 판별
+func TestParseRawEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantLEEF bool
+		wantErr  bool
+	}{
+		{"cef", "CEF:0|V|P|1|E|N|1|msg=LEEF:1.0", false, false},
+		{"leef", "LEEF:1.0|V|P|1|E|msg=CEF:0", true, false},
+		{"syslog prefixed leef", "<13>host LEEF:2.0|V|P|1|E|^|a=b", true, false},
+		{"neither", "plain text", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseRawEvent(tt.line, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRawEvent(%q) err = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if _, isLEEF := event["leefVersion"]; isLEEF != tt.wantLEEF {
+				t.Errorf("ParseRawEvent(%q) LEEF = %v, want %v", tt.line, isLEEF, tt.wantLEEF)
+			}
+		})
+	}
+}
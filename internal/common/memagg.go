@@ -0,0 +1,643 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memAggregate: aggs 정의를 문서 집합에 적용한 결과 (응답의 "aggregations"와 같은 형태)
+func memAggregate(defs map[string]interface{}, hits []*memHit) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(defs))
+	for name, raw := range defs {
+		def, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("집계 %s: 객체가 아님", name)
+		}
+		var kind string
+		var body map[string]interface{}
+		var sub map[string]interface{}
+		for k, v := range def {
+			switch k {
+			case "aggs", "aggregations":
+				sub, _ = v.(map[string]interface{})
+			case "meta":
+			default:
+				if kind != "" {
+					return nil, fmt.Errorf("집계 %s: 유형은 1개여야 함", name)
+				}
+				kind = k
+				body, _ = v.(map[string]interface{})
+			}
+		}
+		if body == nil {
+			return nil, fmt.Errorf("집계 %s: 유형 누락", name)
+		}
+		var result map[string]interface{}
+		var err error
+		switch kind {
+		case "terms":
+			result, err = aggTerms(body, sub, hits)
+		case "date_histogram":
+			result, err = aggDateHistogram(body, sub, hits)
+		case "composite":
+			result, err = aggComposite(body, sub, hits)
+		case "sum", "min", "max", "avg", "value_count", "cardinality":
+			result, err = aggMetric(kind, body, hits)
+		case "top_hits":
+			result, err = aggTopHits(body, hits)
+		default:
+			err = fmt.Errorf("지원하지 않는 집계: %s", kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("집계 %s: %v", name, err)
+		}
+		out[name] = result
+	}
+	return out, nil
+}
+
+// memBucket: 버킷 키와 소속 문서
+type memBucket struct {
+	key  interface{}
+	hits []*memHit
+}
+
+// bucketResult: 버킷 응답 (key/doc_count + 하위 집계)
+func bucketResult(key interface{}, hits []*memHit, sub map[string]interface{}) (map[string]interface{}, error) {
+	b := map[string]interface{}{"key": key, "doc_count": float64(len(hits))}
+	if sub != nil {
+		aggs, err := memAggregate(sub, hits)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range aggs {
+			b[k] = v
+		}
+	}
+	return b, nil
+}
+
+// groupKey: 버킷 구분용 문자열 (타입이 다르면 다른 키)
+func groupKey(v interface{}) string {
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+func intOption(body map[string]interface{}, key string, def int) int {
+	if v, ok := body[key].(float64); ok {
+		return int(v)
+	}
+	return def
+}
+
+func aggTerms(body, sub map[string]interface{}, hits []*memHit) (map[string]interface{}, error) {
+	field, _ := body["field"].(string)
+	if field == "" {
+		return nil, fmt.Errorf("terms: field 누락")
+	}
+	size := intOption(body, "size", 10)
+	minDocCount := intOption(body, "min_doc_count", 1)
+
+	index := map[string]*memBucket{}
+	var buckets []*memBucket
+	for _, h := range hits {
+		seen := map[string]bool{}
+		for _, v := range fieldValues(h.src, field) {
+			k := groupKey(v)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			b, ok := index[k]
+			if !ok {
+				b = &memBucket{key: v}
+				index[k] = b
+				buckets = append(buckets, b)
+			}
+			b.hits = append(b.hits, h)
+		}
+	}
+
+	// 기본 정렬: doc_count 내림차순, 같으면 key 오름차순
+	byKey, desc := false, true
+	if order, ok := body["order"].(map[string]interface{}); ok {
+		for k, v := range order {
+			switch k {
+			case "_count":
+			case "_key", "_term":
+				byKey = true
+			default:
+				return nil, fmt.Errorf("terms: order는 _count/_key만 지원")
+			}
+			desc = v == "desc"
+		}
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		a, b := buckets[i], buckets[j]
+		if !byKey && len(a.hits) != len(b.hits) {
+			if desc {
+				return len(a.hits) > len(b.hits)
+			}
+			return len(a.hits) < len(b.hits)
+		}
+		c, _ := memCompare(a.key, b.key)
+		if byKey && desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	out := []interface{}{}
+	other := 0
+	for _, b := range buckets {
+		if len(b.hits) < minDocCount {
+			continue
+		}
+		if len(out) >= size {
+			other += len(b.hits)
+			continue
+		}
+		res, err := bucketResult(b.key, b.hits, sub)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return map[string]interface{}{
+		"doc_count_error_upper_bound": 0.0,
+		"sum_other_doc_count":         float64(other),
+		"buckets":                     out,
+	}, nil
+}
+
+// dateInterval: date_histogram 구간 (calendar 단위 또는 고정 길이)
+type dateInterval struct {
+	loc      *time.Location
+	calendar string
+	fixed    time.Duration
+	layout   string // key_as_string 형식 ("" = 키를 문자열로 만들지 않음)
+}
+
+var calendarUnits = map[string]bool{
+	"minute": true, "1m": true, "hour": true, "1h": true, "day": true, "1d": true, "week": true, "1w": true,
+	"month": true, "1M": true, "quarter": true, "1q": true, "year": true, "1y": true,
+}
+
+func parseDateInterval(body map[string]interface{}, defaultLayout string) (*dateInterval, error) {
+	loc, err := memLocation(body["time_zone"])
+	if err != nil {
+		return nil, err
+	}
+	di := &dateInterval{loc: loc, layout: defaultLayout}
+	if format, ok := body["format"].(string); ok {
+		if di.layout, err = jodaLayout(format); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := body["calendar_interval"].(string); ok {
+		if !calendarUnits[v] {
+			return nil, fmt.Errorf("date_histogram: 잘못된 calendar_interval %s", v)
+		}
+		di.calendar = v
+		return di, nil
+	}
+	v, _ := body["fixed_interval"].(string)
+	if v == "" {
+		// 예전 interval 옵션: 단위 이름이면 calendar, 아니면 고정 길이
+		v, _ = body["interval"].(string)
+		if calendarUnits[v] {
+			di.calendar = v
+			return di, nil
+		}
+	}
+	if v == "" {
+		return nil, fmt.Errorf("date_histogram: calendar_interval/fixed_interval 누락")
+	}
+	if strings.HasSuffix(v, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("date_histogram: 잘못된 interval %s", v)
+		}
+		di.fixed = time.Duration(n) * 24 * time.Hour
+		return di, nil
+	}
+	if di.fixed, err = time.ParseDuration(v); err != nil || di.fixed <= 0 {
+		return nil, fmt.Errorf("date_histogram: 잘못된 interval %s", v)
+	}
+	return di, nil
+}
+
+// floor: t가 속한 구간의 시작 시각
+func (di *dateInterval) floor(t time.Time) time.Time {
+	t = t.In(di.loc)
+	if di.calendar != "" {
+		k, _ := floorTime(t, di.calendar)
+		return k
+	}
+	_, offset := t.Zone()
+	ms := t.UnixMilli() + int64(offset)*1000
+	d := di.fixed.Milliseconds()
+	k := int64(math.Floor(float64(ms)/float64(d))) * d
+	return time.UnixMilli(k - int64(offset)*1000).In(di.loc)
+}
+
+func (di *dateInterval) next(t time.Time) time.Time {
+	if di.calendar != "" {
+		return addUnit(t.In(di.loc), di.calendar, 1)
+	}
+	return t.Add(di.fixed)
+}
+
+func (di *dateInterval) keyAsString(t time.Time) string {
+	if di.layout == "epoch_millis" {
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	return t.In(di.loc).Format(di.layout)
+}
+
+// jodaLayout: OpenSearch 날짜 format(yyyy-MM-dd HH:mm 등) → Go 레이아웃
+func jodaLayout(format string) (string, error) {
+	switch format {
+	case "strict_date_optional_time", "date_optional_time", "strict_date_time", "date_time":
+		return "2006-01-02T15:04:05.000Z07:00", nil
+	case "epoch_millis":
+		return format, nil
+	}
+	tokens := map[string]string{
+		"yyyy": "2006", "yy": "06", "uuuu": "2006",
+		"MM": "01", "M": "1", "dd": "02", "d": "2",
+		"HH": "15", "hh": "03", "h": "3", "mm": "04", "m": "4", "ss": "05", "s": "5",
+		"SSS": "000", "a": "PM", "XXX": "Z07:00", "ZZ": "-07:00", "Z": "-0700",
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c == '\'' {
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				return "", fmt.Errorf("format 따옴표 짝 오류: %s", format)
+			}
+			b.WriteString(format[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		j := i
+		for j < len(format) && format[j] == c {
+			j++
+		}
+		layout, ok := tokens[format[i:j]]
+		if !ok {
+			return "", fmt.Errorf("지원하지 않는 format 패턴 %q: %s", format[i:j], format)
+		}
+		b.WriteString(layout)
+		i = j
+	}
+	return b.String(), nil
+}
+
+func aggDateHistogram(body, sub map[string]interface{}, hits []*memHit) (map[string]interface{}, error) {
+	field, _ := body["field"].(string)
+	if field == "" {
+		return nil, fmt.Errorf("date_histogram: field 누락")
+	}
+	di, err := parseDateInterval(body, "2006-01-02T15:04:05.000Z07:00")
+	if err != nil {
+		return nil, err
+	}
+	minDocCount := intOption(body, "min_doc_count", 0)
+
+	index := map[int64]*memBucket{}
+	for _, h := range hits {
+		seen := map[int64]bool{}
+		for _, v := range fieldValues(h.src, field) {
+			t, ok := toTime(v)
+			if !ok {
+				continue
+			}
+			k := di.floor(t)
+			ms := k.UnixMilli()
+			if seen[ms] {
+				continue
+			}
+			seen[ms] = true
+			b, ok := index[ms]
+			if !ok {
+				b = &memBucket{key: k}
+				index[ms] = b
+			}
+			b.hits = append(b.hits, h)
+		}
+	}
+
+	// min_doc_count 0이면 첫/마지막 버킷(extended_bounds 포함) 사이의 빈 구간도 채운다
+	var keys []time.Time
+	for _, b := range index {
+		keys = append(keys, b.key.(time.Time))
+	}
+	if minDocCount == 0 {
+		first, last := time.Time{}, time.Time{}
+		for _, k := range keys {
+			if first.IsZero() || k.Before(first) {
+				first = k
+			}
+			if last.IsZero() || k.After(last) {
+				last = k
+			}
+		}
+		if bounds, ok := body["extended_bounds"].(map[string]interface{}); ok {
+			for name, v := range bounds {
+				var t time.Time
+				switch x := v.(type) {
+				case string:
+					if t, err = parseDateMath(x, di.loc, false); err != nil {
+						return nil, fmt.Errorf("extended_bounds: %v", err)
+					}
+				case float64:
+					t = time.UnixMilli(int64(x))
+				default:
+					continue
+				}
+				t = di.floor(t)
+				if name == "min" && (first.IsZero() || t.Before(first)) {
+					first = t
+				}
+				if name == "max" && (last.IsZero() || t.After(last)) {
+					last = t
+				}
+			}
+		}
+		keys = nil
+		for k := first; !first.IsZero() && !k.After(last); k = di.next(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Before(keys[j]) })
+
+	out := []interface{}{}
+	for _, k := range keys {
+		var bucketHits []*memHit
+		if b, ok := index[k.UnixMilli()]; ok {
+			bucketHits = b.hits
+		}
+		if len(bucketHits) < minDocCount {
+			continue
+		}
+		res, err := bucketResult(float64(k.UnixMilli()), bucketHits, sub)
+		if err != nil {
+			return nil, err
+		}
+		res["key_as_string"] = di.keyAsString(k)
+		out = append(out, res)
+	}
+	return map[string]interface{}{"buckets": out}, nil
+}
+
+// compositeSource: composite sources 항목 1개 (terms 또는 date_histogram)
+type compositeSource struct {
+	name     string
+	field    string
+	desc     bool
+	interval *dateInterval
+}
+
+func (cs *compositeSource) values(h *memHit) []interface{} {
+	var out []interface{}
+	seen := map[string]bool{}
+	for _, v := range fieldValues(h.src, cs.field) {
+		if cs.interval != nil {
+			t, ok := toTime(v)
+			if !ok {
+				continue
+			}
+			k := cs.interval.floor(t)
+			if cs.interval.layout != "" {
+				v = cs.interval.keyAsString(k)
+			} else {
+				v = float64(k.UnixMilli())
+			}
+		}
+		if k := groupKey(v); !seen[k] {
+			seen[k] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func aggComposite(body, sub map[string]interface{}, hits []*memHit) (map[string]interface{}, error) {
+	size := intOption(body, "size", 10)
+	var sources []*compositeSource
+	for _, raw := range asList(body["sources"]) {
+		m, _ := raw.(map[string]interface{})
+		if len(m) != 1 {
+			return nil, fmt.Errorf("composite: sources 항목은 키 1개여야 함")
+		}
+		for name, v := range m {
+			def, _ := v.(map[string]interface{})
+			cs := &compositeSource{name: name}
+			var opts map[string]interface{}
+			if t, ok := def["terms"].(map[string]interface{}); ok {
+				opts = t
+			} else if d, ok := def["date_histogram"].(map[string]interface{}); ok {
+				opts = d
+				var err error
+				if cs.interval, err = parseDateInterval(d, ""); err != nil {
+					return nil, err
+				}
+			} else {
+				return nil, fmt.Errorf("composite: source %s는 terms/date_histogram만 지원", name)
+			}
+			cs.field, _ = opts["field"].(string)
+			if cs.field == "" {
+				return nil, fmt.Errorf("composite: source %s field 누락", name)
+			}
+			cs.desc = opts["order"] == "desc"
+			sources = append(sources, cs)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("composite: sources 누락")
+	}
+
+	type compositeBucket struct {
+		key  []interface{}
+		hits []*memHit
+	}
+	index := map[string]*compositeBucket{}
+	var buckets []*compositeBucket
+	for _, h := range hits {
+		// 다중 값 필드는 모든 조합을 버킷으로, 값이 없는 source가 있으면 제외 (missing_bucket 미지원)
+		combos := [][]interface{}{{}}
+		for _, cs := range sources {
+			var next [][]interface{}
+			for _, v := range cs.values(h) {
+				for _, c := range combos {
+					next = append(next, append(append([]interface{}{}, c...), v))
+				}
+			}
+			combos = next
+		}
+		for _, key := range combos {
+			var sb strings.Builder
+			for _, v := range key {
+				sb.WriteString(groupKey(v))
+				sb.WriteByte(0)
+			}
+			b, ok := index[sb.String()]
+			if !ok {
+				b = &compositeBucket{key: key}
+				index[sb.String()] = b
+				buckets = append(buckets, b)
+			}
+			b.hits = append(b.hits, h)
+		}
+	}
+
+	compare := func(a, b []interface{}) int {
+		for i, cs := range sources {
+			c, _ := memCompare(a[i], b[i])
+			if cs.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	sort.Slice(buckets, func(i, j int) bool { return compare(buckets[i].key, buckets[j].key) < 0 })
+
+	if after, ok := body["after"].(map[string]interface{}); ok {
+		afterKey := make([]interface{}, len(sources))
+		for i, cs := range sources {
+			afterKey[i] = after[cs.name]
+		}
+		i := sort.Search(len(buckets), func(i int) bool { return compare(buckets[i].key, afterKey) > 0 })
+		buckets = buckets[i:]
+	}
+	if len(buckets) > size {
+		buckets = buckets[:size]
+	}
+
+	keyMap := func(key []interface{}) map[string]interface{} {
+		m := make(map[string]interface{}, len(sources))
+		for i, cs := range sources {
+			m[cs.name] = key[i]
+		}
+		return m
+	}
+	out := []interface{}{}
+	for _, b := range buckets {
+		res, err := bucketResult(keyMap(b.key), b.hits, sub)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	result := map[string]interface{}{"buckets": out}
+	if len(buckets) > 0 {
+		result["after_key"] = keyMap(buckets[len(buckets)-1].key)
+	}
+	return result, nil
+}
+
+// metricValue: 집계용 숫자 (숫자/숫자 문자열, 날짜는 epoch millis)
+func metricValue(v interface{}) (float64, bool) {
+	if f, ok := toNumber(v); ok {
+		return f, true
+	}
+	if t, ok := toTime(v); ok {
+		return float64(t.UnixMilli()), true
+	}
+	return 0, false
+}
+
+func aggMetric(kind string, body map[string]interface{}, hits []*memHit) (map[string]interface{}, error) {
+	field, _ := body["field"].(string)
+	if field == "" {
+		return nil, fmt.Errorf("%s: field 누락", kind)
+	}
+	var values []interface{}
+	for _, h := range hits {
+		values = append(values, fieldValues(h.src, field)...)
+	}
+	switch kind {
+	case "value_count":
+		return map[string]interface{}{"value": float64(len(values))}, nil
+	case "cardinality":
+		seen := map[string]bool{}
+		for _, v := range values {
+			seen[groupKey(v)] = true
+		}
+		return map[string]interface{}{"value": float64(len(seen))}, nil
+	}
+
+	var nums []float64
+	for _, v := range values {
+		if f, ok := metricValue(v); ok {
+			nums = append(nums, f)
+		}
+	}
+	if kind == "sum" {
+		sum := 0.0
+		for _, f := range nums {
+			sum += f
+		}
+		return map[string]interface{}{"value": sum}, nil
+	}
+	if len(nums) == 0 {
+		return map[string]interface{}{"value": nil}, nil
+	}
+	result := nums[0]
+	for _, f := range nums[1:] {
+		switch kind {
+		case "min":
+			result = math.Min(result, f)
+		case "max":
+			result = math.Max(result, f)
+		case "avg":
+			result += f
+		}
+	}
+	if kind == "avg" {
+		result /= float64(len(nums))
+	}
+	return map[string]interface{}{"value": result}, nil
+}
+
+func aggTopHits(body map[string]interface{}, hits []*memHit) (map[string]interface{}, error) {
+	spec, err := parseMemSort(body["sort"])
+	if err != nil {
+		return nil, err
+	}
+	size := intOption(body, "size", 3)
+	sorted := sortMemHits(hits, spec)
+	if size < len(sorted) {
+		sorted = sorted[:size]
+	}
+	out := make([]interface{}, 0, len(sorted))
+	for _, h := range sorted {
+		hit := map[string]interface{}{"_index": h.index, "_id": h.id, "_score": 1.0}
+		if spec != nil {
+			hit["_score"] = nil
+			hit["sort"] = h.values
+		}
+		if src, ok := filterSource(h.src, body["_source"]); ok {
+			hit["_source"] = src
+		}
+		out = append(out, hit)
+	}
+	return map[string]interface{}{"hits": map[string]interface{}{
+		"total":     map[string]interface{}{"value": float64(len(hits)), "relation": "eq"},
+		"max_score": nil,
+		"hits":      out,
+	}}, nil
+}
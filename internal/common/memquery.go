@@ -0,0 +1,768 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// memMatcher: 문서가 쿼리 조건을 만족하는지
+type memMatcher func(h *memHit) bool
+
+// compileQuery: OpenSearch query DSL → memMatcher (nil은 match_all, 지원하지 않는 절은 error)
+func compileQuery(q interface{}) (memMatcher, error) {
+	if q == nil {
+		return func(*memHit) bool { return true }, nil
+	}
+	// []map[string]interface{} 같은 Go 타입으로 만든 쿼리도 JSON 왕복으로 맞춘다
+	if _, ok := q.(map[string]interface{}); !ok || !isJSONValue(q) {
+		data, err := json.Marshal(q)
+		if err != nil {
+			return nil, fmt.Errorf("쿼리 직렬화 실패: %v", err)
+		}
+		q = nil
+		json.Unmarshal(data, &q)
+	}
+	clause, ok := q.(map[string]interface{})
+	if !ok || len(clause) != 1 {
+		return nil, fmt.Errorf("쿼리 절은 키 1개인 객체여야 함: %v", q)
+	}
+	for kind, body := range clause {
+		switch kind {
+		case "match_all":
+			return func(*memHit) bool { return true }, nil
+		case "match_none":
+			return func(*memHit) bool { return false }, nil
+		case "bool":
+			return compileBool(body)
+		case "term", "terms", "prefix", "wildcard", "match", "range":
+			field, arg, err := fieldClause(kind, body)
+			if err != nil {
+				return nil, err
+			}
+			return compileFieldClause(kind, field, arg)
+		case "exists":
+			m, _ := body.(map[string]interface{})
+			field, _ := m["field"].(string)
+			if field == "" {
+				return nil, fmt.Errorf("exists: field 누락")
+			}
+			return func(h *memHit) bool { return len(fieldValues(h.src, field)) > 0 }, nil
+		case "ids":
+			m, _ := body.(map[string]interface{})
+			values, _ := m["values"].([]interface{})
+			ids := make(map[string]bool, len(values))
+			for _, v := range values {
+				ids[fmt.Sprint(v)] = true
+			}
+			return func(h *memHit) bool { return ids[h.id] }, nil
+		case "multi_match":
+			m, _ := body.(map[string]interface{})
+			fields := toStrings(asList(m["fields"]))
+			tokens := textTokens(m["query"])
+			return func(h *memHit) bool {
+				for _, f := range fields {
+					if matchTokens(fieldValues(h.src, strings.SplitN(f, "^", 2)[0]), tokens) {
+						return true
+					}
+				}
+				return false
+			}, nil
+		default:
+			return nil, fmt.Errorf("지원하지 않는 쿼리: %s", kind)
+		}
+	}
+	return nil, nil
+}
+
+// isJSONValue: json.Unmarshal 결과와 같은 타입(map/[]interface{}/float64/string/bool/nil)으로만 구성됐는지
+func isJSONValue(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, float64, string, bool:
+		return true
+	case map[string]interface{}:
+		for _, e := range x {
+			if !isJSONValue(e) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		for _, e := range x {
+			if !isJSONValue(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// fieldClause: {"field": arg} 형태 절에서 필드명과 인자 추출 (boost 등 부가 키 무시)
+func fieldClause(kind string, body interface{}) (string, interface{}, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("%s: 객체가 아님", kind)
+	}
+	var field string
+	var arg interface{}
+	for k, v := range m {
+		if k == "boost" || k == "_name" {
+			continue
+		}
+		if field != "" {
+			return "", nil, fmt.Errorf("%s: 필드는 1개여야 함", kind)
+		}
+		field, arg = k, v
+	}
+	if field == "" {
+		return "", nil, fmt.Errorf("%s: 필드 누락", kind)
+	}
+	return field, arg, nil
+}
+
+func compileFieldClause(kind, field string, arg interface{}) (memMatcher, error) {
+	switch kind {
+	case "term":
+		want := unwrapValue(arg, "value")
+		return func(h *memHit) bool {
+			for _, v := range fieldValues(h.src, field) {
+				if memEqual(v, want) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "terms":
+		wants, ok := arg.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("terms: %s 값은 배열이어야 함", field)
+		}
+		return func(h *memHit) bool {
+			for _, v := range fieldValues(h.src, field) {
+				for _, want := range wants {
+					if memEqual(v, want) {
+						return true
+					}
+				}
+			}
+			return false
+		}, nil
+	case "prefix":
+		prefix := fmt.Sprint(unwrapValue(arg, "value"))
+		return func(h *memHit) bool {
+			for _, v := range fieldValues(h.src, field) {
+				if s, ok := v.(string); ok && strings.HasPrefix(s, prefix) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "wildcard":
+		pattern := fmt.Sprint(unwrapValue(arg, "value"))
+		expr := regexp.QuoteMeta(pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		if m, ok := arg.(map[string]interface{}); ok && m["case_insensitive"] == true {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("wildcard: %v", err)
+		}
+		return func(h *memHit) bool {
+			for _, v := range fieldValues(h.src, field) {
+				if re.MatchString(fmt.Sprint(v)) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "match":
+		tokens := textTokens(unwrapValue(arg, "query"))
+		return func(h *memHit) bool { return matchTokens(fieldValues(h.src, field), tokens) }, nil
+	case "range":
+		m, ok := arg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("range: %s 조건은 객체여야 함", field)
+		}
+		return compileRange(field, m)
+	}
+	return nil, fmt.Errorf("지원하지 않는 쿼리: %s", kind)
+}
+
+func compileBool(body interface{}) (memMatcher, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("bool: 객체가 아님")
+	}
+	compileAll := func(key string) ([]memMatcher, error) {
+		var out []memMatcher
+		for _, q := range asList(m[key]) {
+			match, err := compileQuery(q)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, match)
+		}
+		return out, nil
+	}
+	must, err := compileAll("must")
+	if err != nil {
+		return nil, err
+	}
+	filter, err := compileAll("filter")
+	if err != nil {
+		return nil, err
+	}
+	should, err := compileAll("should")
+	if err != nil {
+		return nil, err
+	}
+	mustNot, err := compileAll("must_not")
+	if err != nil {
+		return nil, err
+	}
+	for key := range m {
+		switch key {
+		case "must", "filter", "should", "must_not", "minimum_should_match", "boost", "_name":
+		default:
+			return nil, fmt.Errorf("bool: 지원하지 않는 키 %s", key)
+		}
+	}
+	required := append(must, filter...)
+	minShould := 0
+	if len(should) > 0 && len(required) == 0 {
+		minShould = 1
+	}
+	switch v := m["minimum_should_match"].(type) {
+	case float64:
+		minShould = int(v)
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("bool: minimum_should_match는 정수만 지원: %s", v)
+		}
+		minShould = n
+	}
+	return func(h *memHit) bool {
+		for _, match := range required {
+			if !match(h) {
+				return false
+			}
+		}
+		for _, match := range mustNot {
+			if match(h) {
+				return false
+			}
+		}
+		if minShould > 0 {
+			n := 0
+			for _, match := range should {
+				if match(h) {
+					n++
+				}
+			}
+			return n >= minShould
+		}
+		return true
+	}, nil
+}
+
+// rangeBound: range 조건 1개 (op: gt/gte/lt/lte)
+type rangeBound struct {
+	op    string
+	value interface{} // float64 / string / time.Time
+}
+
+func compileRange(field string, m map[string]interface{}) (memMatcher, error) {
+	loc, err := memLocation(m["time_zone"])
+	if err != nil {
+		return nil, err
+	}
+	var bounds []rangeBound
+	for op, raw := range m {
+		switch op {
+		case "gt", "gte", "lt", "lte":
+		case "time_zone", "format", "boost":
+			continue
+		case "from", "to", "include_lower", "include_upper":
+			return nil, fmt.Errorf("range: from/to 대신 gt/gte/lt/lte 사용")
+		default:
+			return nil, fmt.Errorf("range: 지원하지 않는 키 %s", op)
+		}
+		if raw == nil {
+			continue
+		}
+		b := rangeBound{op: op, value: raw}
+		if s, ok := raw.(string); ok {
+			if _, err := strconv.ParseFloat(s, 64); err != nil {
+				// 숫자가 아닌 문자열: 날짜(수식)로 해석되면 시각 비교, 아니면 문자열 비교
+				if t, err := parseDateMath(s, loc, op == "gt" || op == "lte"); err == nil {
+					b.value = t
+				}
+			}
+		}
+		bounds = append(bounds, b)
+	}
+	return func(h *memHit) bool {
+		for _, v := range fieldValues(h.src, field) {
+			if inRange(v, bounds) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func inRange(v interface{}, bounds []rangeBound) bool {
+	for _, b := range bounds {
+		var c int
+		if t, ok := b.value.(time.Time); ok {
+			vt, ok := toTime(v)
+			if !ok {
+				return false
+			}
+			c = vt.Compare(t)
+		} else {
+			var ok bool
+			if c, ok = memCompare(v, b.value); !ok {
+				return false
+			}
+		}
+		switch b.op {
+		case "gt":
+			if c <= 0 {
+				return false
+			}
+		case "gte":
+			if c < 0 {
+				return false
+			}
+		case "lt":
+			if c >= 0 {
+				return false
+			}
+		case "lte":
+			if c > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fieldValues: 점 경로 필드의 모든 값 (배열은 펼치고 null은 제외, .keyword 접미사는 원본 필드)
+func fieldValues(src map[string]interface{}, field string) []interface{} {
+	var out []interface{}
+	collectField(src, strings.TrimSuffix(field, ".keyword"), &out)
+	return out
+}
+
+func collectField(v interface{}, field string, out *[]interface{}) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if val, ok := x[field]; ok {
+			flattenValue(val, out)
+		}
+		// "a.b.c" → a 객체의 "b.c", a.b 키의 "c" … 순으로 탐색
+		for i := 0; i < len(field); i++ {
+			if field[i] == '.' {
+				if sub, ok := x[field[:i]]; ok {
+					collectField(sub, field[i+1:], out)
+				}
+			}
+		}
+	case []interface{}:
+		for _, e := range x {
+			collectField(e, field, out)
+		}
+	}
+}
+
+func flattenValue(v interface{}, out *[]interface{}) {
+	switch x := v.(type) {
+	case nil:
+	case []interface{}:
+		for _, e := range x {
+			flattenValue(e, out)
+		}
+	default:
+		*out = append(*out, v)
+	}
+}
+
+// unwrapValue: {"value": x} 형태면 x, 아니면 그대로
+func unwrapValue(arg interface{}, key string) interface{} {
+	if m, ok := arg.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return arg
+}
+
+func asList(v interface{}) []interface{} {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return x
+	}
+	return []interface{}{v}
+}
+
+// memEqual: term 비교 (한쪽이 숫자면 숫자로, 그 외는 문자열로)
+func memEqual(a, b interface{}) bool {
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		fa, ok1 := toNumber(a)
+		fb, ok2 := toNumber(b)
+		return ok1 && ok2 && fa == fb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// memCompare: 정렬/범위 비교 (숫자 → 날짜 → 문자열 순으로 해석, 비교 불가면 ok=false)
+func memCompare(a, b interface{}) (int, bool) {
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		fa, ok1 := toNumber(a)
+		fb, ok2 := toNumber(b)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case ab == bb:
+			return 0, true
+		case !ab:
+			return -1, true
+		}
+		return 1, true
+	}
+	as, ok1 := a.(string)
+	bs, ok2 := b.(string)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	if ta, ok := toTime(as); ok {
+		if tb, ok := toTime(bs); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	return strings.Compare(as, bs), true
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case int:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toTime: 문서 값 → 시각 (문자열은 날짜 형식, 숫자는 epoch millis, 오프셋 없는 값은 UTC)
+func toTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, true
+	case float64:
+		return time.UnixMilli(int64(x)), true
+	case string:
+		t, _, ok := parseMemDate(x, time.UTC)
+		return t, ok
+	}
+	return time.Time{}, false
+}
+
+var memDateLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+}
+
+// parseMemDate: 날짜 문자열 파싱 (오프셋이 없으면 loc 기준), 날짜만 있으면 dateOnly
+func parseMemDate(s string, loc *time.Location) (t time.Time, dateOnly bool, ok bool) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, false, true
+	}
+	for _, layout := range memDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, false, true
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, true, true
+	}
+	return time.Time{}, false, false
+}
+
+// memLocation: time_zone 옵션 (IANA 이름 또는 +09:00 형식, 없으면 UTC)
+func memLocation(v interface{}) (*time.Location, error) {
+	tz, _ := v.(string)
+	if tz == "" || tz == "Z" || tz == "UTC" {
+		return time.UTC, nil
+	}
+	if tz[0] == '+' || tz[0] == '-' {
+		t, err := time.Parse("-07:00", tz)
+		if err != nil {
+			return nil, fmt.Errorf("time_zone 형식 오류: %s", tz)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(tz, offset), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("time_zone 형식 오류: %s", tz)
+	}
+	return loc, nil
+}
+
+// parseDateMath: now-7d/d, 2024-01-01||+1d 같은 날짜 수식 해석
+// roundUp(gt/lte)이면 반올림(/d)과 날짜만 있는 값을 해당 단위의 마지막 시각으로 본다
+func parseDateMath(expr string, loc *time.Location, roundUp bool) (time.Time, error) {
+	var t time.Time
+	rest := ""
+	switch {
+	case strings.HasPrefix(expr, "now"):
+		t, rest = time.Now().In(loc), expr[3:]
+	case strings.Contains(expr, "||"):
+		i := strings.Index(expr, "||")
+		anchor, _, ok := parseMemDate(expr[:i], loc)
+		if !ok {
+			return time.Time{}, fmt.Errorf("날짜 형식 오류: %s", expr)
+		}
+		t, rest = anchor.In(loc), expr[i+2:]
+	default:
+		d, dateOnly, ok := parseMemDate(expr, loc)
+		if !ok {
+			return time.Time{}, fmt.Errorf("날짜 형식 오류: %s", expr)
+		}
+		if dateOnly && roundUp {
+			d = d.AddDate(0, 0, 1).Add(-time.Millisecond)
+		}
+		return d, nil
+	}
+	for rest != "" {
+		op := rest[0]
+		rest = rest[1:]
+		n := 1
+		if op == '+' || op == '-' {
+			j := 0
+			for j < len(rest) && unicode.IsDigit(rune(rest[j])) {
+				j++
+			}
+			if j > 0 {
+				n, _ = strconv.Atoi(rest[:j])
+			}
+			rest = rest[j:]
+			if op == '-' {
+				n = -n
+			}
+		} else if op != '/' {
+			return time.Time{}, fmt.Errorf("날짜 수식 오류: %s", expr)
+		}
+		if rest == "" {
+			return time.Time{}, fmt.Errorf("날짜 수식 단위 누락: %s", expr)
+		}
+		unit := rest[0]
+		rest = rest[1:]
+		var err error
+		if op == '/' {
+			t, err = floorTime(t, string(unit))
+			if err == nil && roundUp {
+				t = addUnit(t, string(unit), 1).Add(-time.Millisecond)
+			}
+		} else {
+			if _, err = floorTime(t, string(unit)); err == nil {
+				t = addUnit(t, string(unit), n)
+			}
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("날짜 수식 단위 오류: %s", expr)
+		}
+	}
+	return t, nil
+}
+
+// floorTime: t를 단위 시작 시각으로 내림 (t의 location 기준)
+func floorTime(t time.Time, unit string) (time.Time, error) {
+	y, mo, d := t.Date()
+	switch unit {
+	case "y", "year", "1y":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	case "q", "quarter", "1q":
+		return time.Date(y, mo-(mo-1)%3, 1, 0, 0, 0, 0, t.Location()), nil
+	case "M", "month", "1M":
+		return time.Date(y, mo, 1, 0, 0, 0, 0, t.Location()), nil
+	case "w", "week", "1w":
+		day := time.Date(y, mo, d, 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil // 월요일 시작
+	case "d", "day", "1d":
+		return time.Date(y, mo, d, 0, 0, 0, 0, t.Location()), nil
+	case "h", "H", "hour", "1h":
+		return time.Date(y, mo, d, t.Hour(), 0, 0, 0, t.Location()), nil
+	case "m", "minute", "1m":
+		return time.Date(y, mo, d, t.Hour(), t.Minute(), 0, 0, t.Location()), nil
+	case "s", "second", "1s":
+		return time.Date(y, mo, d, t.Hour(), t.Minute(), t.Second(), 0, t.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("알 수 없는 단위: %s", unit)
+}
+
+func addUnit(t time.Time, unit string, n int) time.Time {
+	switch unit {
+	case "y", "year", "1y":
+		return t.AddDate(n, 0, 0)
+	case "q", "quarter", "1q":
+		return t.AddDate(0, 3*n, 0)
+	case "M", "month", "1M":
+		return t.AddDate(0, n, 0)
+	case "w", "week", "1w":
+		return t.AddDate(0, 0, 7*n)
+	case "d", "day", "1d":
+		return t.AddDate(0, 0, n)
+	case "h", "H", "hour", "1h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "m", "minute", "1m":
+		return t.Add(time.Duration(n) * time.Minute)
+	}
+	return t.Add(time.Duration(n) * time.Second)
+}
+
+// textTokens: match 쿼리용 소문자 토큰
+func textTokens(v interface{}) []string {
+	return strings.FieldsFunc(strings.ToLower(fmt.Sprint(v)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchTokens: 값들의 토큰 중 하나라도 질의 토큰과 같으면 true (match의 기본 OR 동작)
+func matchTokens(values []interface{}, tokens []string) bool {
+	for _, v := range values {
+		for _, tok := range textTokens(v) {
+			for _, want := range tokens {
+				if tok == want {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// memSortField: sort 조건 1개
+type memSortField struct {
+	field string
+	desc  bool
+}
+
+// sortedHit: 정렬 값이 계산된 문서
+type sortedHit struct {
+	*memHit
+	values []interface{}
+}
+
+// parseMemSort: "field" / {"field": "desc"} / {"field": {"order": "desc"}} 또는 그 배열
+func parseMemSort(spec interface{}) ([]memSortField, error) {
+	var fields []memSortField
+	for _, item := range asList(spec) {
+		switch x := item.(type) {
+		case string:
+			fields = append(fields, memSortField{field: x, desc: x == "_score"})
+		case map[string]interface{}:
+			for field, opt := range x {
+				order, _ := unwrapValue(opt, "order").(string)
+				switch order {
+				case "", "asc", "desc":
+				default:
+					return nil, fmt.Errorf("sort: 잘못된 order %s", order)
+				}
+				fields = append(fields, memSortField{field: field, desc: order == "desc"})
+			}
+		default:
+			return nil, fmt.Errorf("sort: 지원하지 않는 형식 %v", item)
+		}
+	}
+	return fields, nil
+}
+
+// sortMemHits: spec 순으로 정렬 (spec이 없으면 입력 순서 유지)
+func sortMemHits(hits []*memHit, spec []memSortField) []sortedHit {
+	out := make([]sortedHit, len(hits))
+	for i, h := range hits {
+		out[i] = sortedHit{memHit: h}
+		if spec == nil {
+			continue
+		}
+		out[i].values = make([]interface{}, len(spec))
+		for j, f := range spec {
+			switch f.field {
+			case "_id":
+				out[i].values[j] = h.id
+			case "_doc":
+				out[i].values[j] = float64(i)
+			case "_shard_doc":
+				out[i].values[j] = float64(h.seq)
+			case "_score":
+				out[i].values[j] = 1.0
+			default:
+				if vals := fieldValues(h.src, f.field); len(vals) > 0 {
+					out[i].values[j] = vals[0]
+				}
+			}
+		}
+	}
+	if spec != nil {
+		sort.SliceStable(out, func(i, j int) bool { return compareSortValues(out[i].values, out[j].values, spec) < 0 })
+	}
+	return out
+}
+
+// compareSortValues: 정렬 값 비교 (값 없는 문서는 항상 뒤)
+func compareSortValues(a, b []interface{}, spec []memSortField) int {
+	for i, f := range spec {
+		if i >= len(a) || i >= len(b) {
+			break
+		}
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		}
+		c, _ := memCompare(a[i], b[i])
+		if f.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,592 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore: 메모리 Store 구현 (단위 테스트/로컬 실행용)
+//
+// 쓰기는 즉시 검색에 반영된다 (Refresh는 아무 일도 하지 않음).
+// 쿼리는 term/terms/range/bool/match_all/exists/ids/prefix/wildcard/match/multi_match,
+// 집계는 composite/terms/date_histogram과 sum/min/max/avg/value_count/cardinality/top_hits만 지원하고
+// 그 외 절(script 등)은 ErrOSRejected로 거부한다. 필드명의 .keyword 접미사는 원본 필드로 해석한다.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	indices map[string]map[string]map[string]interface{} // index → _id → _source
+	seq     int
+}
+
+// memHit: 검색 대상 문서 (index/_id + 원본 _source)
+type memHit struct {
+	index string
+	id    string
+	src   map[string]interface{}
+	seq   int // 수집 순서 (인덱스명, _id 순) — _shard_doc 정렬 값
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{indices: make(map[string]map[string]map[string]interface{})}
+}
+
+func memRejected(op, format string, args ...interface{}) error {
+	return &OSError{Op: op, Status: 400, Body: fmt.Sprintf(format, args...)}
+}
+
+func memNotFound(op, format string, args ...interface{}) error {
+	return &OSError{Op: op, Status: 404, Body: fmt.Sprintf(format, args...)}
+}
+
+// toDoc: 문서를 JSON 왕복한 map으로 (OpenSearch 응답과 같은 타입: float64, []interface{})
+func toDoc(doc interface{}) (map[string]interface{}, error) {
+	var data []byte
+	switch d := doc.(type) {
+	case []byte:
+		data = d
+	case json.RawMessage:
+		data = d
+	default:
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	return m, nil
+}
+
+// copyValue: map/slice 깊은 복사 (반환한 문서를 호출측이 수정해도 저장본은 그대로)
+func copyValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i, e := range x {
+			s[i] = copyValue(e)
+		}
+		return s
+	}
+	return v
+}
+
+// mergeDoc: fields를 dst에 병합 (양쪽 모두 객체인 필드는 재귀 병합)
+func mergeDoc(dst, fields map[string]interface{}) {
+	for k, v := range fields {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if cur, ok := dst[k].(map[string]interface{}); ok {
+				mergeDoc(cur, sub)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// resolve: 인덱스 이름/패턴(쉼표 구분, * 와일드카드) → 존재하는 인덱스 목록
+// 와일드카드가 아닌 인덱스가 없으면 404 (allowMissing이면 무시). 호출측에서 lock 보유
+func (s *MemoryStore) resolve(op, pattern string, allowMissing bool) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, part := range strings.Split(pattern, ",") {
+		part = strings.TrimSpace(part)
+		if part == "_all" {
+			part = "*"
+		}
+		if !strings.ContainsAny(part, "*?") {
+			if _, ok := s.indices[part]; !ok {
+				if allowMissing {
+					continue
+				}
+				return nil, memNotFound(op, "index_not_found_exception: %s", part)
+			}
+			if !seen[part] {
+				seen[part] = true
+				names = append(names, part)
+			}
+			continue
+		}
+		for name := range s.indices {
+			if ok, _ := path.Match(part, name); ok && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// collect: 패턴에 해당하는 전체 문서 (index, _id 순). 호출측에서 lock 보유
+func (s *MemoryStore) collect(op, pattern string, allowMissing bool) ([]*memHit, error) {
+	names, err := s.resolve(op, pattern, allowMissing)
+	if err != nil {
+		return nil, err
+	}
+	var hits []*memHit
+	for _, name := range names {
+		docs := s.indices[name]
+		ids := make([]string, 0, len(docs))
+		for id := range docs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			hits = append(hits, &memHit{index: name, id: id, src: docs[id], seq: len(hits)})
+		}
+	}
+	return hits, nil
+}
+
+// Indices: 패턴에 해당하는 인덱스 목록 (이름 순). 와일드카드가 아닌 인덱스가 없으면 ErrOSNotFound
+func (s *MemoryStore) Indices(pattern string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resolve("indices", pattern, false)
+}
+
+// CreateIndex: 빈 인덱스 생성 (이미 있으면 false)
+func (s *MemoryStore) CreateIndex(index string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indices[index]; ok {
+		return false
+	}
+	s.indices[index] = make(map[string]map[string]interface{})
+	return true
+}
+
+// DeleteIndex: 인덱스와 문서 전체 삭제 (없으면 false)
+func (s *MemoryStore) DeleteIndex(index string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indices[index]; !ok {
+		return false
+	}
+	delete(s.indices, index)
+	return true
+}
+
+func (s *MemoryStore) Get(index, docID string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	src, ok := s.indices[index][docID]
+	if !ok {
+		return nil, nil
+	}
+	doc := copyValue(src).(map[string]interface{})
+	doc["_id"] = docID
+	return doc, nil
+}
+
+func (s *MemoryStore) Put(index, docID string, doc interface{}) error {
+	src, err := toDoc(doc)
+	if err != nil {
+		return memRejected("PUT", "문서 직렬화 실패: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(index, docID, src)
+	return nil
+}
+
+// put: 호출측에서 lock 보유
+func (s *MemoryStore) put(index, docID string, src map[string]interface{}) {
+	docs, ok := s.indices[index]
+	if !ok {
+		docs = make(map[string]map[string]interface{})
+		s.indices[index] = docs
+	}
+	docs[docID] = src
+}
+
+// nextID: 자동 _id (호출측에서 lock 보유)
+func (s *MemoryStore) nextID() string {
+	s.seq++
+	return fmt.Sprintf("mem-%08d", s.seq)
+}
+
+func (s *MemoryStore) Index(index string, doc interface{}) (string, error) {
+	src, err := toDoc(doc)
+	if err != nil {
+		return "", memRejected("Index", "문서 직렬화 실패: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID()
+	s.put(index, id, src)
+	return id, nil
+}
+
+func (s *MemoryStore) Update(index, docID string, fields map[string]interface{}) error {
+	patch, err := toDoc(fields)
+	if err != nil {
+		return memRejected("update", "문서 직렬화 실패: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	src, ok := s.indices[index][docID]
+	if !ok {
+		return memNotFound("update", "document_missing_exception: %s/%s", index, docID)
+	}
+	mergeDoc(src, patch)
+	return nil
+}
+
+func (s *MemoryStore) Delete(index, docID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.indices[index], docID)
+	return nil
+}
+
+func (s *MemoryStore) Refresh(index string) error { return nil }
+
+func (s *MemoryStore) Count(index string, query interface{}) (int, error) {
+	match, err := compileQuery(query)
+	if err != nil {
+		return 0, memRejected("count", "%v", err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hits, err := s.collect("count", index, false)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, h := range hits {
+		if match(h) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) Search(index string, body interface{}) ([]map[string]interface{}, error) {
+	result, err := s.SearchRaw(index, body)
+	if err != nil {
+		return nil, err
+	}
+	return searchDocs(result), nil
+}
+
+// SearchRaw: query/sort/search_after/from/size/_source/aggs를 해석해 _search와 같은 형태로 응답
+func (s *MemoryStore) SearchRaw(index string, body interface{}) (map[string]interface{}, error) {
+	req := map[string]interface{}{}
+	if body != nil {
+		var err error
+		if req, err = toDoc(body); err != nil {
+			return nil, memRejected("search", "검색 본문 직렬화 실패: %v", err)
+		}
+	}
+	match, err := compileQuery(req["query"])
+	if err != nil {
+		return nil, memRejected("search", "%v", err)
+	}
+	sortSpec, err := parseMemSort(req["sort"])
+	if err != nil {
+		return nil, memRejected("search", "%v", err)
+	}
+	size, from := 10, 0
+	if v, ok := req["size"].(float64); ok {
+		size = int(v)
+	}
+	if v, ok := req["from"].(float64); ok {
+		from = int(v)
+	}
+	aggDefs, _ := req["aggs"].(map[string]interface{})
+	if aggDefs == nil {
+		aggDefs, _ = req["aggregations"].(map[string]interface{})
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all, err := s.collect("search", index, false)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*memHit
+	for _, h := range all {
+		if match(h) {
+			matched = append(matched, h)
+		}
+	}
+
+	result := map[string]interface{}{
+		"took":      0.0,
+		"timed_out": false,
+	}
+	if aggDefs != nil {
+		aggs, err := memAggregate(aggDefs, matched)
+		if err != nil {
+			return nil, memRejected("search", "%v", err)
+		}
+		result["aggregations"] = aggs
+	}
+
+	page := sortMemHits(matched, sortSpec)
+	if after, ok := req["search_after"].([]interface{}); ok && sortSpec != nil {
+		i := sort.Search(len(page), func(i int) bool { return compareSortValues(page[i].values, after, sortSpec) > 0 })
+		page = page[i:]
+	}
+	if from > len(page) {
+		from = len(page)
+	}
+	page = page[from:]
+	if size >= 0 && size < len(page) {
+		page = page[:size]
+	}
+	hits := make([]interface{}, 0, len(page))
+	for _, h := range page {
+		hit := map[string]interface{}{"_index": h.index, "_id": h.id}
+		if sortSpec != nil {
+			hit["_score"] = nil
+			hit["sort"] = h.values
+		} else {
+			hit["_score"] = 1.0
+		}
+		if src, ok := filterSource(h.src, req["_source"]); ok {
+			hit["_source"] = src
+		}
+		hits = append(hits, hit)
+	}
+	result["hits"] = map[string]interface{}{
+		"total":     map[string]interface{}{"value": float64(len(matched)), "relation": "eq"},
+		"max_score": nil,
+		"hits":      hits,
+	}
+	return result, nil
+}
+
+// Iterate: query에 맞는 문서를 _id 순으로 순회 (없는 인덱스는 빈 결과)
+func (s *MemoryStore) Iterate(ctx context.Context, index string, query interface{}, fn func(hit SearchHit) error) error {
+	match, err := compileQuery(query)
+	if err != nil {
+		return memRejected("search", "%v", err)
+	}
+	// 스냅샷을 떠서 lock 없이 fn 호출 (fn 안에서 Store에 써도 교착 없음)
+	s.mu.RLock()
+	all, err := s.collect("search", index, true)
+	var hits []SearchHit
+	for _, h := range all {
+		if match(h) {
+			src, _ := json.Marshal(h.src)
+			hits = append(hits, SearchHit{Index: h.index, ID: h.id, Source: src, Sort: []interface{}{h.id}})
+		}
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].ID < hits[j].ID })
+	for _, hit := range hits {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Validate(index string, body interface{}) error {
+	req, err := toDoc(body)
+	if err != nil {
+		return memRejected("validate", "검색 본문 직렬화 실패: %v", err)
+	}
+	if _, err := compileQuery(req["query"]); err != nil {
+		return memRejected("validate", "%v", err)
+	}
+	return nil
+}
+
+// Bulk: index/create/update/delete 항목을 순서대로 적용 (_bulk 응답 형식)
+func (s *MemoryStore) Bulk(body []byte) (map[string]interface{}, error) {
+	lines := bytes.Split(body, []byte("\n"))
+	items := []interface{}{}
+	hasErrors := false
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < len(lines); i++ {
+		line := bytes.TrimSpace(lines[i])
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]map[string]interface{}
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			return nil, memRejected("Bulk", "잘못된 action 줄: %s", line)
+		}
+		for op, meta := range action {
+			index, _ := meta["_index"].(string)
+			id, _ := meta["_id"].(string)
+			var src map[string]interface{}
+			if op != "delete" {
+				i++
+				if i >= len(lines) {
+					return nil, memRejected("Bulk", "%s 문서 줄 누락", op)
+				}
+				var err error
+				if src, err = toDoc(bytes.TrimSpace(lines[i])); err != nil {
+					return nil, memRejected("Bulk", "문서 파싱 실패: %v", err)
+				}
+			}
+			item := s.bulkOp(op, index, id, src)
+			if _, failed := item["error"]; failed {
+				hasErrors = true
+			}
+			items = append(items, map[string]interface{}{op: item})
+		}
+	}
+	return map[string]interface{}{"took": 0.0, "errors": hasErrors, "items": items}, nil
+}
+
+// bulkOp: 항목 1건 적용 후 응답 항목 반환 (호출측에서 lock 보유)
+func (s *MemoryStore) bulkOp(op, index, id string, src map[string]interface{}) map[string]interface{} {
+	item := map[string]interface{}{"_index": index, "_id": id}
+	fail := func(status int, typ, reason string) map[string]interface{} {
+		item["status"] = float64(status)
+		item["error"] = map[string]interface{}{"type": typ, "reason": reason}
+		return item
+	}
+	if index == "" {
+		return fail(400, "action_request_validation_exception", "index is missing")
+	}
+	_, exists := s.indices[index][id]
+	switch op {
+	case "index", "create":
+		if id == "" {
+			id = s.nextID()
+			item["_id"] = id
+			exists = false
+		}
+		if op == "create" && exists {
+			return fail(409, "version_conflict_engine_exception", fmt.Sprintf("[%s]: document already exists", id))
+		}
+		s.put(index, id, src)
+		if exists {
+			item["status"], item["result"] = float64(200), "updated"
+		} else {
+			item["status"], item["result"] = float64(201), "created"
+		}
+	case "update":
+		if !exists {
+			return fail(404, "document_missing_exception", fmt.Sprintf("[%s]: document missing", id))
+		}
+		patch, _ := src["doc"].(map[string]interface{})
+		mergeDoc(s.indices[index][id], patch)
+		item["status"], item["result"] = float64(200), "updated"
+	case "delete":
+		if !exists {
+			item["status"], item["result"] = float64(404), "not_found"
+			return item
+		}
+		delete(s.indices[index], id)
+		item["status"], item["result"] = float64(200), "deleted"
+	default:
+		return fail(400, "illegal_argument_exception", "unknown bulk action "+op)
+	}
+	return item
+}
+
+// searchDocs: _search 응답의 hits → _source + "_id" 목록
+func searchDocs(result map[string]interface{}) []map[string]interface{} {
+	hitsObj, ok := result["hits"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	hitArr, ok := hitsObj["hits"].([]interface{})
+	if !ok {
+		return nil
+	}
+	docs := make([]map[string]interface{}, 0, len(hitArr))
+	for _, h := range hitArr {
+		hit, _ := h.(map[string]interface{})
+		if doc, ok := hit["_source"].(map[string]interface{}); ok {
+			doc["_id"] = hit["_id"]
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// filterSource: _source 옵션(false / 필드 목록 / includes·excludes) 적용, false면 ok=false
+func filterSource(src map[string]interface{}, spec interface{}) (map[string]interface{}, bool) {
+	var includes, excludes []string
+	switch v := spec.(type) {
+	case nil:
+	case bool:
+		if !v {
+			return nil, false
+		}
+	case string:
+		includes = []string{v}
+	case []interface{}:
+		includes = toStrings(v)
+	case map[string]interface{}:
+		inc, _ := v["includes"].([]interface{})
+		exc, _ := v["excludes"].([]interface{})
+		includes, excludes = toStrings(inc), toStrings(exc)
+	}
+	out := copyValue(src).(map[string]interface{})
+	if len(includes) > 0 {
+		picked := map[string]interface{}{}
+		for _, field := range includes {
+			copyPath(picked, out, strings.Split(field, "."))
+		}
+		out = picked
+	}
+	for _, field := range excludes {
+		delete(out, field)
+	}
+	return out, true
+}
+
+// copyPath: src의 점 경로 필드를 dst의 같은 위치로 복사
+func copyPath(dst, src map[string]interface{}, keys []string) {
+	v, ok := src[keys[0]]
+	if !ok {
+		return
+	}
+	if len(keys) == 1 {
+		dst[keys[0]] = v
+		return
+	}
+	sub, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	next, ok := dst[keys[0]].(map[string]interface{})
+	if !ok {
+		next = map[string]interface{}{}
+		dst[keys[0]] = next
+	}
+	copyPath(next, sub, keys[1:])
+}
+
+func toStrings(values []interface{}) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package common
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// seedMemoryStore: 일별 인덱스 2개에 걸친 테스트 문서 (@timestamp는 KST 기준 자정 근처 포함)
+func seedMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	s := NewMemoryStore()
+	docs := []struct {
+		index, id string
+		doc       map[string]interface{}
+	}{
+		{"siem-test-event-logs-2024.01.01", "d1", map[string]interface{}{
+			"msgId": "A", "userId": "u1", "n": 5, "tags": []string{"x", "y"}, "@timestamp": "2024-01-01T10:00:00+09:00"}},
+		{"siem-test-event-logs-2024.01.01", "d2", map[string]interface{}{
+			"msgId": "B", "userId": "u1", "n": 15, "@timestamp": "2024-01-01T23:30:00+09:00"}},
+		{"siem-test-event-logs-2024.01.02", "d3", map[string]interface{}{
+			"msgId": "A", "userId": "u2", "n": 25, "@timestamp": "2024-01-02T01:00:00+09:00"}},
+		{"siem-test-event-logs-2024.01.02", "d4", map[string]interface{}{
+			"msgId": "C", "userId": "u3", "@timestamp": "2024-01-02T12:00:00+09:00"}},
+	}
+	for _, d := range docs {
+		if err := s.Put(d.index, d.id, d.doc); err != nil {
+			t.Fatalf("Put %s: %v", d.id, err)
+		}
+	}
+	return s
+}
+
+func searchIDs(t *testing.T, s *MemoryStore, query interface{}) []string {
+	t.Helper()
+	docs, err := s.Search("siem-test-event-logs-*", map[string]interface{}{"size": 100, "query": query})
+	if err != nil {
+		t.Fatalf("Search %v: %v", query, err)
+	}
+	ids := []string{}
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"].(string))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestMemoryStoreQueries(t *testing.T) {
+	s := seedMemoryStore(t)
+	type m = map[string]interface{}
+	tests := []struct {
+		name  string
+		query interface{}
+		want  []string
+	}{
+		{"term keyword", m{"term": m{"msgId.keyword": "A"}}, []string{"d1", "d3"}},
+		{"term value object", m{"term": m{"msgId": m{"value": "B"}}}, []string{"d2"}},
+		{"term array field", m{"term": m{"tags": "y"}}, []string{"d1"}},
+		{"terms", m{"terms": m{"msgId.keyword": []string{"A", "C"}}}, []string{"d1", "d3", "d4"}},
+		{"range numeric", m{"range": m{"n": m{"gte": 10, "lt": 25}}}, []string{"d2"}},
+		{"range date math with time_zone", m{"range": m{"@timestamp": m{
+			"gte": "2024-01-02", "lt": "2024-01-02||+1d", "time_zone": "Asia/Seoul"}}}, []string{"d3", "d4"}},
+		{"range date UTC day", m{"range": m{"@timestamp": m{
+			"gte": "2024-01-01", "lt": "2024-01-02"}}}, []string{"d1", "d2", "d3"}},
+		{"bool must + must_not", m{"bool": m{
+			"must":     []interface{}{m{"term": m{"userId.keyword": "u1"}}},
+			"must_not": []interface{}{m{"term": m{"msgId.keyword": "B"}}},
+		}}, []string{"d1"}},
+		{"bool filter + should", m{"bool": m{
+			"filter":               []interface{}{m{"range": m{"n": m{"gt": 0}}}},
+			"should":               []interface{}{m{"term": m{"msgId": "A"}}, m{"term": m{"msgId": "C"}}},
+			"minimum_should_match": 1,
+		}}, []string{"d1", "d3"}},
+		{"bool should only", m{"bool": m{
+			"should": []interface{}{m{"term": m{"userId": "u2"}}, m{"term": m{"userId": "u3"}}},
+		}}, []string{"d3", "d4"}},
+		{"bool filter without should match", m{"bool": m{
+			"filter": m{"terms": m{"userId": []string{"u1", "u3"}}},
+			"should": []interface{}{m{"term": m{"msgId": "Z"}}},
+		}}, []string{"d1", "d2", "d4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchIDs(t, s, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreCompositePaging(t *testing.T) {
+	s := seedMemoryStore(t)
+	type m = map[string]interface{}
+	var keys []string
+	counts := map[string]float64{}
+	var after interface{}
+	for page := 0; page < 10; page++ {
+		composite := m{
+			"size": 2,
+			"sources": []interface{}{
+				m{"user": m{"terms": m{"field": "userId.keyword"}}},
+				m{"msg": m{"terms": m{"field": "msgId.keyword"}}},
+			},
+		}
+		if after != nil {
+			composite["after"] = after
+		}
+		result, err := s.SearchRaw("siem-test-event-logs-*", m{"size": 0, "aggs": m{"pairs": m{
+			"composite": composite,
+			"aggs":      m{"total": m{"sum": m{"field": "n"}}},
+		}}})
+		if err != nil {
+			t.Fatalf("SearchRaw: %v", err)
+		}
+		pairs := result["aggregations"].(m)["pairs"].(m)
+		buckets := pairs["buckets"].([]interface{})
+		for _, raw := range buckets {
+			b := raw.(m)
+			key := b["key"].(m)
+			k := key["user"].(string) + "/" + key["msg"].(string)
+			keys = append(keys, k)
+			counts[k] = b["total"].(m)["value"].(float64)
+		}
+		if len(buckets) < 2 {
+			break
+		}
+		after = pairs["after_key"]
+	}
+	wantKeys := []string{"u1/A", "u1/B", "u2/A", "u3/C"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Fatalf("composite keys %v, want %v", keys, wantKeys)
+	}
+	if counts["u1/B"] != 15 || counts["u2/A"] != 25 || counts["u3/C"] != 0 {
+		t.Errorf("sum sub-aggregation %v", counts)
+	}
+}
+
+func TestMemoryStoreDateHistogram(t *testing.T) {
+	s := seedMemoryStore(t)
+	type m = map[string]interface{}
+	result, err := s.SearchRaw("siem-test-event-logs-*", m{
+		"size":  0,
+		"query": m{"term": m{"userId.keyword": "u1"}},
+		"aggs": m{
+			"daily": m{"date_histogram": m{
+				"field": "@timestamp", "calendar_interval": "day", "format": "MM-dd",
+				"time_zone": "Asia/Seoul", "min_doc_count": 0,
+				"extended_bounds": m{"min": "2023-12-30", "max": "2024-01-02"},
+			}},
+			"hourly": m{"date_histogram": m{
+				"field": "@timestamp", "calendar_interval": "hour", "format": "HH", "time_zone": "Asia/Seoul",
+				"min_doc_count": 1,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SearchRaw: %v", err)
+	}
+	aggs := result["aggregations"].(m)
+	bucketCounts := func(name string) map[string]float64 {
+		out := map[string]float64{}
+		for _, raw := range aggs[name].(m)["buckets"].([]interface{}) {
+			b := raw.(m)
+			out[b["key_as_string"].(string)] = b["doc_count"].(float64)
+		}
+		return out
+	}
+	// KST 기준 일자: 두 문서 모두 01-01, extended_bounds로 빈 일자도 채워짐
+	wantDaily := map[string]float64{"12-30": 0, "12-31": 0, "01-01": 2, "01-02": 0}
+	if got := bucketCounts("daily"); !reflect.DeepEqual(got, wantDaily) {
+		t.Errorf("daily %v, want %v", got, wantDaily)
+	}
+	wantHourly := map[string]float64{"10": 1, "23": 1}
+	if got := bucketCounts("hourly"); !reflect.DeepEqual(got, wantHourly) {
+		t.Errorf("hourly %v, want %v", got, wantHourly)
+	}
+
+	// composite date_histogram source (일자 × 사용자)
+	result, err = s.SearchRaw("siem-test-event-logs-*", m{"size": 0, "aggs": m{"days": m{"composite": m{
+		"sources": []interface{}{
+			m{"day": m{"date_histogram": m{"field": "@timestamp", "calendar_interval": "day", "format": "yyyy-MM-dd", "time_zone": "Asia/Seoul"}}},
+			m{"user": m{"terms": m{"field": "userId.keyword"}}},
+		},
+	}}}})
+	if err != nil {
+		t.Fatalf("SearchRaw composite date_histogram: %v", err)
+	}
+	var got []string
+	for _, raw := range result["aggregations"].(m)["days"].(m)["buckets"].([]interface{}) {
+		key := raw.(m)["key"].(m)
+		got = append(got, key["day"].(string)+"/"+key["user"].(string))
+	}
+	want := []string{"2024-01-01/u1", "2024-01-02/u2", "2024-01-02/u3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("composite date_histogram %v, want %v", got, want)
+	}
+}
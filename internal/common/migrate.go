@@ -0,0 +1,356 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrateOptions: `siem migrate` 옵션
+type MigrateOptions struct {
+	Family  string   // 특정 인덱스 계열만 (빈값이면 전체)
+	DryRun  bool     // 변경 없이 계획만 출력
+	Reindex bool     // drift 인덱스를 새 매핑으로 재색인
+	Active  []string // 일별 인덱스 중 재색인에서 제외할 날짜 접미사 (오늘 + 지연 이벤트가 도착할 수 있는 최근 일자)
+}
+
+// IndexDrift: 템플릿과 다른 기존 인덱스
+type IndexDrift struct {
+	Index      string
+	Version    int      // 인덱스 생성 시 템플릿 버전 (0: 템플릿 없이 생성)
+	Mismatches []string // "field: 실제 → 기대"
+}
+
+// MigrateResult: 템플릿별 처리 결과
+type MigrateResult struct {
+	Template  string
+	Action    string // created / updated / unchanged / skipped
+	Drifts    []IndexDrift
+	Reindexed []string
+	Errors    []string
+}
+
+// Migrate: 템플릿 설치(멱등) + 기존 인덱스 drift 점검 (+ 선택적 재색인)
+func Migrate(os *OSClient, prefix string, opts MigrateOptions) ([]MigrateResult, error) {
+	var results []MigrateResult
+	for _, tpl := range IndexTemplates(prefix) {
+		if opts.Family != "" && opts.Family != tpl.Family {
+			continue
+		}
+		res := MigrateResult{Template: tpl.Name}
+
+		action, err := installTemplate(os, tpl, opts.DryRun)
+		if err != nil {
+			return results, fmt.Errorf("%s: %v", tpl.Name, err)
+		}
+		res.Action = action
+
+		drifts, err := detectDrift(os, tpl)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		res.Drifts = drifts
+
+		if opts.Reindex && !opts.DryRun {
+			for _, d := range drifts {
+				if tpl.Daily && isActiveDaily(d.Index, opts.Active) {
+					res.Errors = append(res.Errors, fmt.Sprintf("%s: 쓰기 중인 최근 일별 인덱스는 재색인 제외 (쓰기 차단 시 지연 이벤트 색인 거부, 이후 재실행)", d.Index))
+					continue
+				}
+				if err := reindexInPlace(os, prefix, d.Index, tpl); err != nil {
+					res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", d.Index, err))
+					continue
+				}
+				res.Reindexed = append(res.Reindexed, d.Index)
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// isActiveDaily: 일별 인덱스가 active 날짜 접미사 중 하나로 끝나면 true
+func isActiveDaily(index string, active []string) bool {
+	for _, day := range active {
+		if day != "" && strings.HasSuffix(index, day) {
+			return true
+		}
+	}
+	return false
+}
+
+// installTemplate: 템플릿이 없거나 버전이 낮으면 PUT (높은 버전이 설치돼 있으면 건드리지 않음)
+func installTemplate(os *OSClient, tpl IndexTemplate, dryRun bool) (string, error) {
+	status, result, err := os.Request("GET", "/_index_template/"+tpl.Name, nil)
+	if err != nil {
+		return "", err
+	}
+	action := "created"
+	if status == 200 {
+		installed := 0
+		if list, ok := result["index_templates"].([]interface{}); ok && len(list) > 0 {
+			entry, _ := list[0].(map[string]interface{})
+			body, _ := entry["index_template"].(map[string]interface{})
+			if v, ok := body["version"].(float64); ok {
+				installed = int(v)
+			}
+		}
+		switch {
+		case installed == tpl.Version:
+			return "unchanged", nil
+		case installed > tpl.Version:
+			log.Printf("[migrate] %s: 설치된 버전(v%d)이 더 높아 건너뜀 (v%d)", tpl.Name, installed, tpl.Version)
+			return "skipped", nil
+		}
+		action = "updated"
+	} else if status != 404 {
+		return "", fmt.Errorf("템플릿 조회 실패: %d %v", status, result["error"])
+	}
+	if dryRun {
+		return action + " (dry-run)", nil
+	}
+
+	status, result, err = os.Request("PUT", "/_index_template/"+tpl.Name, map[string]interface{}{
+		"index_patterns": tpl.Patterns,
+		"version":        tpl.Version,
+		"priority":       100,
+		"template":       map[string]interface{}{"mappings": tpl.Mappings},
+		"_meta":          map[string]interface{}{"managedBy": "siem migrate"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if status >= 400 {
+		return "", fmt.Errorf("템플릿 저장 실패: %d %v", status, result["error"])
+	}
+	return action, nil
+}
+
+// detectDrift: 패턴에 해당하는 기존 인덱스의 매핑을 템플릿 명시 필드와 비교
+func detectDrift(os *OSClient, tpl IndexTemplate) ([]IndexDrift, error) {
+	want := flattenMapping(tpl.Mappings["properties"], "")
+	var drifts []IndexDrift
+	for _, pattern := range tpl.Patterns {
+		status, result, err := os.Request("GET", "/"+pattern+"/_mapping", nil)
+		if err != nil {
+			return drifts, err
+		}
+		if status == 404 {
+			continue
+		}
+		if status >= 400 {
+			return drifts, fmt.Errorf("%s 매핑 조회 실패: %d", pattern, status)
+		}
+		indices := make([]string, 0, len(result))
+		for index := range result {
+			indices = append(indices, index)
+		}
+		sort.Strings(indices)
+		for _, index := range indices {
+			entry, _ := result[index].(map[string]interface{})
+			mappings, _ := entry["mappings"].(map[string]interface{})
+			d := IndexDrift{Index: index, Version: mappingVersion(mappings)}
+			have := flattenMapping(mappings["properties"], "")
+			for _, field := range sortedKeys(want) {
+				if actual, ok := have[field]; ok && actual != want[field] {
+					d.Mismatches = append(d.Mismatches, fmt.Sprintf("%s: %s → %s", field, actual, want[field]))
+				}
+			}
+			if d.Version < tpl.Version || len(d.Mismatches) > 0 {
+				drifts = append(drifts, d)
+			}
+		}
+	}
+	return drifts, nil
+}
+
+func mappingVersion(mappings map[string]interface{}) int {
+	meta, _ := mappings["_meta"].(map[string]interface{})
+	v, _ := meta["version"].(float64)
+	return int(v)
+}
+
+// flattenMapping: properties → "a.b" : type (하위 필드는 "a.keyword")
+func flattenMapping(props interface{}, parent string) map[string]string {
+	out := make(map[string]string)
+	m, _ := props.(map[string]interface{})
+	for name, raw := range m {
+		def, _ := raw.(map[string]interface{})
+		path := name
+		if parent != "" {
+			path = parent + "." + name
+		}
+		typ, _ := def["type"].(string)
+		if sub, ok := def["properties"]; ok {
+			if typ == "" {
+				typ = "object"
+			}
+			for k, v := range flattenMapping(sub, path) {
+				out[k] = v
+			}
+		}
+		if typ == "" {
+			typ = "object"
+		}
+		out[path] = typ
+		if fields, ok := def["fields"].(map[string]interface{}); ok {
+			for sub, sraw := range fields {
+				sdef, _ := sraw.(map[string]interface{})
+				styp, _ := sdef["type"].(string)
+				out[path+"."+sub] = styp
+			}
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reindexInPlace: 같은 이름을 유지한 채 새 템플릿 매핑으로 재생성 (원본 이름은 alias로 전환)
+//
+//  1. 패턴 밖 새 인덱스(<prefix>-siem-migrate-<name>-v<버전>)를 새 매핑으로 생성 후 원본 → 새 인덱스 재색인 (쓰기 계속 허용)
+//  2. 원본 쓰기 차단 후 증분 재색인 (1단계 중 들어온 문서), 건수 확인
+//  3. _aliases 한 요청으로 원본 삭제 + 원본 이름 alias → 새 인덱스 (원자적 전환)
+//
+// 원본 이름이 사라지는 구간이 없으므로 CEP/UEBA 조회·쓰기가 404를 받거나, 이벤트 시각 기준 색인이
+// 같은 이름의 인덱스를 새로 만들 수 없다. 2단계 동안 원본 쓰기는 거부(403)되므로 짧게 유지한다.
+// 새 인덱스는 조회 패턴(*-event-logs-* 등)에 걸리지 않으므로 전환 전 중복 조회가 없다.
+func reindexInPlace(os *OSClient, prefix, index string, tpl IndexTemplate) error {
+	name, err := logicalIndexName(os, index, tpl)
+	if err != nil {
+		return err
+	}
+	target := fmt.Sprintf("%s-%s-migrate-%s-v%d", prefix, solution, strings.TrimPrefix(name, prefix+"-"+solution+"-"), tpl.Version)
+	if target == index {
+		return fmt.Errorf("이미 v%d 인덱스로 전환됨", tpl.Version)
+	}
+	matchAll := map[string]interface{}{"match_all": map[string]interface{}{}}
+
+	os.Request("DELETE", "/"+target, nil)
+	status, result, err := os.Request("PUT", "/"+target, map[string]interface{}{"mappings": tpl.Mappings})
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return fmt.Errorf("새 인덱스 생성 실패: %v", result["error"])
+	}
+	if err := runReindex(os, index, target); err != nil {
+		return fmt.Errorf("새 인덱스 재색인 실패 (원본 유지, %s 확인 필요): %v", target, err)
+	}
+
+	if err := setWriteBlock(os, index, true); err != nil {
+		return fmt.Errorf("원본 쓰기 차단 실패 (원본 유지): %v", err)
+	}
+	swapped := false
+	defer func() {
+		if !swapped {
+			setWriteBlock(os, index, false)
+		}
+	}()
+	if err := runReindex(os, index, target); err != nil {
+		return fmt.Errorf("증분 재색인 실패 (원본 유지, %s 확인 필요): %v", target, err)
+	}
+	os.Refresh(index)
+	os.Refresh(target)
+	srcCount, err := os.Count(index, matchAll)
+	if err != nil {
+		return err
+	}
+	if n, _ := os.Count(target, matchAll); n != srcCount {
+		return fmt.Errorf("새 인덱스 건수 불일치 %d/%d (원본 유지, %s 확인 필요)", n, srcCount, target)
+	}
+
+	status, result, err = os.Request("POST", "/_aliases", map[string]interface{}{"actions": []interface{}{
+		map[string]interface{}{"add": map[string]interface{}{"index": target, "alias": name}},
+		map[string]interface{}{"remove_index": map[string]interface{}{"index": index}},
+	}})
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return fmt.Errorf("alias 전환 실패 (원본 유지, %s 확인 필요): %v", target, result["error"])
+	}
+	swapped = true
+	log.Printf("[migrate] %s 재색인 완료 (%d건, %s → %s)", name, srcCount, name, target)
+	return nil
+}
+
+// logicalIndexName: 조회/쓰기에 쓰는 이름 (이전 마이그레이션으로 alias가 된 경우 패턴에 맞는 alias)
+func logicalIndexName(os *OSClient, index string, tpl IndexTemplate) (string, error) {
+	status, result, err := os.Request("GET", "/"+index+"/_alias", nil)
+	if err != nil {
+		return "", err
+	}
+	if status >= 400 {
+		return index, nil
+	}
+	entry, _ := result[index].(map[string]interface{})
+	aliases, _ := entry["aliases"].(map[string]interface{})
+	for alias := range aliases {
+		for _, pattern := range tpl.Patterns {
+			if ok, _ := path.Match(pattern, alias); ok {
+				return alias, nil
+			}
+		}
+	}
+	return index, nil
+}
+
+// setWriteBlock: index.blocks.write 설정/해제
+func setWriteBlock(os *OSClient, index string, block bool) error {
+	status, result, err := os.Request("PUT", "/"+index+"/_settings", map[string]interface{}{"index.blocks.write": block})
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return fmt.Errorf("%d %v", status, result["error"])
+	}
+	return nil
+}
+
+// runReindex: 비동기 _reindex 후 task 완료까지 대기 (대용량 인덱스의 HTTP 타임아웃 회피)
+func runReindex(os *OSClient, src, dst string) error {
+	status, result, err := os.Request("POST", "/_reindex?wait_for_completion=false", map[string]interface{}{
+		"source":    map[string]interface{}{"index": src},
+		"dest":      map[string]interface{}{"index": dst, "op_type": "create"},
+		"conflicts": "proceed", // 이미 옮긴 문서(증분 재색인)는 건너뜀
+	})
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return fmt.Errorf("%d %v", status, result["error"])
+	}
+	task, _ := result["task"].(string)
+	for {
+		time.Sleep(2 * time.Second)
+		status, result, err := os.Request("GET", "/_tasks/"+task, nil)
+		if err != nil {
+			return err
+		}
+		if status >= 400 {
+			return fmt.Errorf("task 조회 실패: %d", status)
+		}
+		if done, _ := result["completed"].(bool); !done {
+			continue
+		}
+		if taskErr, ok := result["error"]; ok {
+			return fmt.Errorf("%v", taskErr)
+		}
+		resp, _ := result["response"].(map[string]interface{})
+		if failures, _ := resp["failures"].([]interface{}); len(failures) > 0 {
+			return fmt.Errorf("%d건 실패: %v", len(failures), failures[0])
+		}
+		return nil
+	}
+}
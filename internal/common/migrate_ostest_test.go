@@ -0,0 +1,80 @@
+package common_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/ostest"
+)
+
+// TestMigrateAgainstOSTest: 템플릿 설치 멱등성, 이전 매핑 인덱스 drift 검출, 최근 일별 인덱스 재색인 제외
+// (ostest는 common을 import하므로 외부 테스트 패키지에서 실행)
+func TestMigrateAgainstOSTest(t *testing.T) {
+	srv := ostest.NewServer()
+	defer srv.Close()
+	client := srv.OSClient()
+
+	// 템플릿 설치 전 생성된 인덱스: fsize가 keyword로 잘못 매핑됨
+	recent := common.DailyLogsIndex("test", "2024.01.02")
+	status, _, err := client.Request("PUT", "/"+recent, map[string]interface{}{"mappings": map[string]interface{}{
+		"properties": map[string]interface{}{"cefExtensions": map[string]interface{}{"properties": map[string]interface{}{
+			"fsize": map[string]interface{}{"type": "keyword"},
+		}}},
+	}})
+	if err != nil || status >= 400 {
+		t.Fatalf("PUT %s: %d %v", recent, status, err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       common.MigrateOptions
+		wantAction string
+		wantDrift  bool
+		wantSkip   bool // 재색인 제외 오류
+	}{
+		{"dry run does not install", common.MigrateOptions{Family: "event-logs", DryRun: true}, "created (dry-run)", true, false},
+		{"install", common.MigrateOptions{Family: "event-logs"}, "created", true, false},
+		{"idempotent", common.MigrateOptions{Family: "event-logs"}, "unchanged", true, false},
+		{"recent daily index not reindexed", common.MigrateOptions{Family: "event-logs", Reindex: true, Active: []string{"2024.01.02"}}, "unchanged", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := common.Migrate(client, "test", tt.opts)
+			if err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("results = %+v, want only event-logs", results)
+			}
+			res := results[0]
+			if res.Action != tt.wantAction {
+				t.Errorf("action = %s, want %s", res.Action, tt.wantAction)
+			}
+			drifted := len(res.Drifts) == 1 && res.Drifts[0].Index == recent && res.Drifts[0].Version == 0 &&
+				len(res.Drifts[0].Mismatches) == 1 && strings.HasPrefix(res.Drifts[0].Mismatches[0], "cefExtensions.fsize: keyword → long")
+			if drifted != tt.wantDrift {
+				t.Errorf("drifts = %+v, want drift on %s fsize", res.Drifts, recent)
+			}
+			skipped := len(res.Errors) == 1 && strings.HasPrefix(res.Errors[0], recent+": 쓰기 중인 최근 일별 인덱스")
+			if skipped != tt.wantSkip || len(res.Reindexed) != 0 {
+				t.Errorf("errors = %v, reindexed = %v, want skip=%v", res.Errors, res.Reindexed, tt.wantSkip)
+			}
+		})
+	}
+
+	// 템플릿 설치 후 생성된 인덱스는 drift 없음
+	fresh := common.DailyLogsIndex("test", "2024.01.03")
+	if err := client.Put(fresh, "d1", map[string]interface{}{"msgId": "USB", "cefExtensions": map[string]interface{}{"fsize": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	results, err := common.Migrate(client, "test", common.MigrateOptions{Family: "event-logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range results[0].Drifts {
+		if d.Index == fresh {
+			t.Errorf("fresh index drifted: %+v", d)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFlattenMapping: 중첩 properties/하위 fields를 점 표기 경로 → 타입으로
+func TestFlattenMapping(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		want  map[string]string
+	}{
+		{
+			name:  "flat",
+			props: map[string]interface{}{"msgId": keywordField(), "@timestamp": dateField()},
+			want:  map[string]string{"msgId": "keyword", "msgId.keyword": "keyword", "@timestamp": "date"},
+		},
+		{
+			name: "object and sub fields",
+			props: map[string]interface{}{
+				"cefExtensions": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+					"fsize": typedField("long"),
+					"fname": textField(),
+				}},
+			},
+			want: map[string]string{"cefExtensions": "object", "cefExtensions.fsize": "long", "cefExtensions.fname": "text", "cefExtensions.fname.keyword": "keyword"},
+		},
+		{
+			name:  "object without explicit type",
+			props: map[string]interface{}{"geo": map[string]interface{}{"properties": map[string]interface{}{"lat": typedField("double")}}},
+			want:  map[string]string{"geo": "object", "geo.lat": "double"},
+		},
+		{name: "empty", props: nil, want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var props interface{}
+			if tt.props != nil {
+				props = tt.props
+			}
+			if got := flattenMapping(props, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flattenMapping = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsActiveDaily: 최근 일자 접미사로 끝나는 일별 인덱스만 재색인 제외
+func TestIsActiveDaily(t *testing.T) {
+	active := []string{"2024.01.02", "2024.01.01"}
+	tests := []struct {
+		index string
+		want  bool
+	}{
+		{"test-siem-event-logs-2024.01.02", true},
+		{"test-siem-event-logs-2024.01.01", true},
+		{"test-siem-event-logs-2023.12.31", false},
+		{"test-siem-event-logs-2024.01.02-restored", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.index, func(t *testing.T) {
+			if got := isActiveDaily(tt.index, active); got != tt.want {
+				t.Errorf("isActiveDaily(%s) = %v, want %v", tt.index, got, tt.want)
+			}
+		})
+	}
+	if isActiveDaily("test-siem-event-logs-2024.01.02", []string{""}) {
+		t.Error("empty active suffix must not match")
+	}
+}
@@ -2,126 +2,332 @@ package common
 
 import (
 	"bytes"
+	"context"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"strconv"
 	"time"
+
+	"github.com/markany/safepc-siem/config"
 )
 
 var Client = &http.Client{Timeout: 30 * time.Second}
 
+// OpenSearch 오류 분류 — errors.Is(err, ErrOSNotFound) 등으로 판별
+var (
+	ErrOSNotFound  = errors.New("opensearch: not found")         // 404
+	ErrOSTransient = errors.New("opensearch: transient failure") // 연결 오류, 429, 5xx (재시도 후에도 실패)
+	ErrOSRejected  = errors.New("opensearch: request rejected")  // 그 외 4xx (요청/인증/매핑 오류)
+)
+
+// OSError: OpenSearch 요청 실패 (Status 0은 전송 오류)
+type OSError struct {
+	Op     string
+	Status int
+	Body   string
+	Err    error // 전송 오류 원인
+}
+
+func (e *OSError) Error() string {
+	if e.Status == 0 {
+		return fmt.Sprintf("OpenSearch %s failed: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("OpenSearch %s failed: %d %s", e.Op, e.Status, e.Body)
+}
+
+func (e *OSError) Unwrap() error { return e.Err }
+
+func (e *OSError) Is(target error) bool {
+	switch target {
+	case ErrOSNotFound:
+		return e.Status == 404
+	case ErrOSTransient:
+		return isTransientStatus(e.Status)
+	case ErrOSRejected:
+		return e.Status >= 400 && e.Status != 404 && !isTransientStatus(e.Status)
+	}
+	return false
+}
+
+// isTransientStatus: 0(전송 오류), 429, 5xx
+func isTransientStatus(status int) bool {
+	return status == 0 || status == 429 || status >= 500
+}
+
+// OSOptions: 인증/TLS/타임아웃/재시도 설정
+type OSOptions struct {
+	Username   string        // basic auth 사용자
+	Password   string        // basic auth 비밀번호
+	APIKey     string        // "Authorization: ApiKey <key>" (basic auth보다 우선)
+	CAFile     string        // 사설 CA PEM
+	CertFile   string        // mTLS 클라이언트 인증서
+	KeyFile    string        // mTLS 클라이언트 개인키
+	Insecure   bool          // 서버 인증서 검증 생략 (테스트 환경 전용)
+	Timeout    time.Duration // 요청 타임아웃 (기본 30초)
+	MaxRetries int           // 연결 오류/429/5xx 재시도 횟수
+	Backoff    time.Duration // 첫 재시도 대기 (지수 증가 + jitter)
+}
+
 type OSClient struct {
-	BaseURL string
+	BaseURL    string
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
 }
 
+// NewOSClient: 인증 없는 기본 클라이언트 (재시도 3회)
 func NewOSClient(url string) *OSClient {
-	return &OSClient{BaseURL: url}
+	return &OSClient{BaseURL: url, http: Client, maxRetries: 3, backoff: 200 * time.Millisecond}
 }
 
-func (c *OSClient) Search(index string, body interface{}) ([]map[string]interface{}, error) {
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", c.BaseURL+"/"+index+"/_search", bytes.NewReader(data))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := Client.Do(req)
+// NewOSClientFromConfig: OPENSEARCH_* 설정으로 생성 (인증서 파일 오류는 error)
+func NewOSClientFromConfig(cfg config.OpenSearchConfig) (*OSClient, error) {
+	return NewOSClientWithOptions(cfg.URL, OSOptions{
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		APIKey:     cfg.APIKey,
+		CAFile:     cfg.CAFile,
+		CertFile:   cfg.CertFile,
+		KeyFile:    cfg.KeyFile,
+		Insecure:   cfg.Insecure,
+		Timeout:    cfg.Timeout,
+		MaxRetries: cfg.MaxRetries,
+		Backoff:    cfg.Backoff,
+	})
+}
+
+func NewOSClientWithOptions(url string, opts OSOptions) (*OSClient, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("CA 파일 읽기 실패: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 파일에 PEM 인증서 없음: %s", opts.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("클라이언트 인증서 로드 실패: %v", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	base, err := neturl.Parse(url)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("잘못된 OpenSearch URL: %v", err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("search failed: %d", resp.StatusCode)
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
 	}
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	hitsObj, ok := result["hits"].(map[string]interface{})
-	if !ok {
-		return nil, nil
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = 200 * time.Millisecond
 	}
-	hitArr, ok := hitsObj["hits"].([]interface{})
-	if !ok {
-		return nil, nil
+	return &OSClient{
+		BaseURL: url,
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: &authTransport{base: transport, host: base.Host, username: opts.Username, password: opts.Password, apiKey: opts.APIKey},
+		},
+		maxRetries: opts.MaxRetries,
+		backoff:    backoff,
+	}, nil
+}
+
+// HTTPClient: 인증/TLS가 적용된 http.Client (OSClient 메서드로 다루지 않는 직접 호출용, 재시도 없음)
+func (c *OSClient) HTTPClient() *http.Client {
+	return c.http
+}
+
+// authTransport: OpenSearch 호스트로 가는 요청에만 인증 헤더 추가 (같은 클라이언트로 다른 서버를 호출해도 자격 증명이 새지 않도록)
+type authTransport struct {
+	base               http.RoundTripper
+	host               string
+	username, password string
+	apiKey             string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if (t.apiKey == "" && t.username == "") || req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+t.apiKey)
+	} else {
+		req.SetBasicAuth(t.username, t.password)
 	}
-	docs := make([]map[string]interface{}, 0, len(hitArr))
-	for _, h := range hitArr {
-		hit, _ := h.(map[string]interface{})
-		if doc, ok := hit["_source"].(map[string]interface{}); ok {
-			doc["_id"] = hit["_id"]
-			docs = append(docs, doc)
+	return t.base.RoundTrip(req)
+}
+
+// do: 요청 전송 (연결 오류/429/5xx는 jitter 백오프로 재시도), 2xx가 아니면 응답 본문을 담은 *OSError
+// 성공 시 응답 본문 바이트 반환
+func (c *OSClient) do(op, method, path, contentType string, body []byte) (int, []byte, error) {
+	return c.doCtx(context.Background(), op, method, path, contentType, body)
+}
+
+// doCtx: ctx가 끝나면 진행 중인 요청과 재시도 대기를 중단하는 do (Err는 ctx.Err())
+func (c *OSClient) doCtx(ctx context.Context, op, method, path, contentType string, body []byte) (int, []byte, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+		if err != nil {
+			return 0, nil, &OSError{Op: op, Err: err}
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		status, data, wait, err := c.send(req)
+		if err == nil && !isTransientStatus(status) {
+			if status >= 400 {
+				return status, data, &OSError{Op: op, Status: status, Body: string(data)}
+			}
+			return status, data, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, nil, &OSError{Op: op, Err: ctxErr}
+		}
+		if attempt >= c.maxRetries {
+			if err != nil {
+				return 0, nil, &OSError{Op: op, Err: err}
+			}
+			return status, data, &OSError{Op: op, Status: status, Body: string(data)}
+		}
+		// full jitter: [backoff/2, backoff) — Retry-After가 더 길면 그만큼 대기
+		sleep := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if wait > sleep {
+			sleep = wait
+		}
+		if err != nil {
+			log.Printf("[OpenSearch] %s 전송 실패, %s 후 재시도 (%d/%d): %v", op, sleep, attempt+1, c.maxRetries, err)
+		} else {
+			log.Printf("[OpenSearch] %s 응답 %d, %s 후 재시도 (%d/%d)", op, status, sleep, attempt+1, c.maxRetries)
+		}
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, &OSError{Op: op, Err: ctx.Err()}
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > 10*time.Second {
+			backoff = 10 * time.Second
 		}
 	}
-	return docs, nil
 }
 
-func (c *OSClient) Put(index, docID string, doc interface{}) error {
-	data, _ := json.Marshal(doc)
-	url := fmt.Sprintf("%s/%s/_doc/%s", c.BaseURL, index, docID)
-	req, _ := http.NewRequest("PUT", url, bytes.NewReader(data))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := Client.Do(req)
+// send: 1회 전송, 429/503의 Retry-After(초) 반환
+func (c *OSClient) send(req *http.Request) (int, []byte, time.Duration, error) {
+	resp, err := c.http.Do(req)
 	if err != nil {
-		return err
+		return 0, nil, 0, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("OpenSearch PUT failed: %s", body)
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, 0, err
 	}
-	return nil
+	var wait time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		wait = time.Duration(secs) * time.Second
+	}
+	return resp.StatusCode, data, wait, nil
 }
 
-func (c *OSClient) Refresh(index string) {
-	http.Post(c.BaseURL+"/"+index+"/_refresh", "application/json", nil)
+// doJSON: JSON 본문 요청 후 응답을 map으로 (본문이 JSON이 아니면 nil map)
+func (c *OSClient) doJSON(op, method, path string, body interface{}) (int, map[string]interface{}, error) {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	status, resp, err := c.do(op, method, path, "application/json", data)
+	var result map[string]interface{}
+	if len(resp) > 0 {
+		json.Unmarshal(resp, &result)
+	}
+	return status, result, err
 }
 
-func (c *OSClient) Update(index, docID string, fields map[string]interface{}) error {
-	body, _ := json.Marshal(map[string]interface{}{"doc": fields})
-	req, _ := http.NewRequest("POST", c.BaseURL+"/"+index+"/_update/"+docID, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := Client.Do(req)
+func (c *OSClient) Search(index string, body interface{}) ([]map[string]interface{}, error) {
+	_, result, err := c.doJSON("search", "POST", "/"+index+"/_search", body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-	return nil
+	return searchDocs(result), nil
+}
+
+func (c *OSClient) Put(index, docID string, doc interface{}) error {
+	_, _, err := c.doJSON("PUT", "PUT", "/"+index+"/_doc/"+docID, doc)
+	return err
+}
+
+// Refresh: 인덱스 refresh (방금 쓴 문서를 검색에 반영)
+func (c *OSClient) Refresh(index string) error {
+	_, _, err := c.do("refresh", "POST", "/"+index+"/_refresh", "", nil)
+	return err
+}
+
+func (c *OSClient) Update(index, docID string, fields map[string]interface{}) error {
+	_, _, err := c.doJSON("update", "POST", "/"+index+"/_update/"+docID, map[string]interface{}{"doc": fields})
+	return err
 }
 
 func (c *OSClient) Delete(index, docID string) error {
-	req, _ := http.NewRequest("DELETE", c.BaseURL+"/"+index+"/_doc/"+docID, nil)
-	resp, err := Client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 && resp.StatusCode != 404 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("OpenSearch Delete failed: %s", body)
+	_, _, err := c.do("Delete", "DELETE", "/"+index+"/_doc/"+docID, "", nil)
+	if errors.Is(err, ErrOSNotFound) {
+		return nil
 	}
-	return nil
+	return err
 }
 
-func (c *OSClient) Index(index string, doc interface{}) error {
-	data, _ := json.Marshal(doc)
-	resp, err := Client.Post(c.BaseURL+"/"+index+"/_doc", "application/json", bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("OpenSearch Index failed: %s", body)
+// Index: 새 _id로 색인 후 _id 반환
+//
+// POST /_doc(서버 자동 _id)는 응답만 유실된 요청을 재시도하면 같은 문서가 두 번 색인되므로,
+// _id를 클라이언트에서 만들고 _create로 보낸다. 재시도가 409면 앞선 시도가 이미 색인한 것.
+func (c *OSClient) Index(index string, doc interface{}) (string, error) {
+	id := NewDocID()
+	status, _, err := c.doJSON("Index", "PUT", "/"+index+"/_create/"+id, doc)
+	if err != nil && status != 409 {
+		return "", err
 	}
-	return nil
+	return id, nil
+}
+
+// NewDocID: 자동 _id와 같은 형식의 20자 URL-safe 임의 ID
+func NewDocID() string {
+	b := make([]byte, 15)
+	crand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
 }
 
 func (c *OSClient) Count(index string, query interface{}) (int, error) {
-	data, _ := json.Marshal(map[string]interface{}{"query": query})
-	resp, err := Client.Post(c.BaseURL+"/"+index+"/_count", "application/json", bytes.NewReader(data))
+	_, result, err := c.doJSON("count", "POST", "/"+index+"/_count", map[string]interface{}{"query": query})
 	if err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
 	if cnt, ok := result["count"].(float64); ok {
 		return int(cnt), nil
 	}
@@ -129,26 +335,72 @@ func (c *OSClient) Count(index string, query interface{}) (int, error) {
 }
 
 func (c *OSClient) SearchRaw(index string, body interface{}) (map[string]interface{}, error) {
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", c.BaseURL+"/"+index+"/_search", bytes.NewReader(data))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := Client.Do(req)
+	_, result, err := c.doJSON("search", "POST", "/"+index+"/_search", body)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
 	return result, nil
 }
 
+// Validate: _validate/query로 검색 본문 검증 (무효 쿼리는 error)
+func (c *OSClient) Validate(index string, body interface{}) error {
+	_, result, err := c.doJSON("validate", "POST", "/"+index+"/_validate/query", body)
+	if err != nil {
+		return err
+	}
+	if valid, _ := result["valid"].(bool); !valid {
+		msg, _ := result["error"].(string)
+		if msg == "" {
+			msg = "쿼리가 유효하지 않음"
+		}
+		return &OSError{Op: "validate", Status: 400, Body: msg}
+	}
+	return nil
+}
+
 func (c *OSClient) GetMapping(index string) (map[string]interface{}, error) {
-	resp, err := Client.Get(c.BaseURL + "/" + index + "/_mapping")
+	_, result, err := c.doJSON("mapping", "GET", "/"+index+"/_mapping", nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Bulk: NDJSON 본문으로 _bulk 요청 후 응답 전체 반환 (항목별 결과는 호출측에서 해석)
+func (c *OSClient) Bulk(body []byte) (map[string]interface{}, error) {
+	_, data, err := c.do("Bulk", "POST", "/_bulk", "application/x-ndjson", body)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("OpenSearch Bulk 응답 파싱 실패: %v", err)
+	}
 	return result, nil
 }
+
+// Get: 문서 단건 조회 (_source + _id), 문서/인덱스가 없으면 nil, nil
+func (c *OSClient) Get(index, docID string) (map[string]interface{}, error) {
+	_, result, err := c.doJSON("Get", "GET", "/"+index+"/_doc/"+docID, nil)
+	if errors.Is(err, ErrOSNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, _ := result["_source"].(map[string]interface{})
+	if doc != nil {
+		doc["_id"] = result["_id"]
+	}
+	return doc, nil
+}
+
+// Request: 임의 API 호출 (관리 작업용). 전송 오류만 error로 반환하고 HTTP 상태는 호출측에서 판단
+func (c *OSClient) Request(method, path string, body interface{}) (int, map[string]interface{}, error) {
+	status, result, err := c.doJSON(method+" "+path, method, path, body)
+	var osErr *OSError
+	if errors.As(err, &osErr) && osErr.Status != 0 {
+		return status, result, nil
+	}
+	return status, result, err
+}
@@ -0,0 +1,293 @@
+package common
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOSErrorIs: 상태 코드 → not-found / transient / rejected 분류
+func TestOSErrorIs(t *testing.T) {
+	tests := []struct {
+		status                            int
+		wantNotFound, wantTrans, wantRejd bool
+	}{
+		{0, false, true, false},
+		{400, false, false, true},
+		{401, false, false, true},
+		{404, true, false, false},
+		{409, false, false, true},
+		{429, false, true, false},
+		{500, false, true, false},
+		{503, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			var err error = &OSError{Op: "test", Status: tt.status, Err: io.ErrUnexpectedEOF}
+			if got := errors.Is(err, ErrOSNotFound); got != tt.wantNotFound {
+				t.Errorf("Is(NotFound) = %v, want %v", got, tt.wantNotFound)
+			}
+			if got := errors.Is(err, ErrOSTransient); got != tt.wantTrans {
+				t.Errorf("Is(Transient) = %v, want %v", got, tt.wantTrans)
+			}
+			if got := errors.Is(err, ErrOSRejected); got != tt.wantRejd {
+				t.Errorf("Is(Rejected) = %v, want %v", got, tt.wantRejd)
+			}
+		})
+	}
+}
+
+// TestOSClientRetry: 연결 끊김/429/5xx만 재시도 (본문 재전송), 재시도 소진 시 transient, 4xx는 즉시 반환
+func TestOSClientRetry(t *testing.T) {
+	const reset = -1 // 응답 없이 연결 끊기
+	tests := []struct {
+		name         string
+		statuses     []int // 시도별 응답 (마지막 값이 이후 반복)
+		maxRetries   int
+		wantAttempts int
+		wantErr      error // nil이면 성공
+	}{
+		{"ok", []int{200}, 3, 1, nil},
+		{"503 then ok", []int{503, 503, 200}, 3, 3, nil},
+		{"429 then ok", []int{429, 200}, 3, 2, nil},
+		{"connection reset then ok", []int{reset, 200}, 3, 2, nil},
+		{"retries exhausted", []int{502}, 2, 3, ErrOSTransient},
+		{"reset exhausted", []int{reset}, 1, 2, ErrOSTransient},
+		{"rejected not retried", []int{400}, 3, 1, ErrOSRejected},
+		{"not found not retried", []int{404}, 3, 1, ErrOSNotFound},
+		{"no retries", []int{500}, 0, 1, ErrOSTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				status := tt.statuses[len(tt.statuses)-1]
+				if attempts < len(tt.statuses) {
+					status = tt.statuses[attempts]
+				}
+				attempts++
+				data, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(data))
+				mu.Unlock()
+				if status == reset {
+					conn, _, _ := w.(http.Hijacker).Hijack()
+					conn.Close()
+					return
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(`{"result":"x"}`))
+			}))
+			defer srv.Close()
+
+			c, err := NewOSClientWithOptions(srv.URL, OSOptions{MaxRetries: tt.maxRetries, Backoff: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.Put("idx", "1", map[string]interface{}{"a": 1})
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Put err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			for i, b := range bodies {
+				if b != `{"a":1}` {
+					t.Errorf("attempt %d body = %q, want resent body", i, b)
+				}
+			}
+		})
+	}
+}
+
+// TestOSClientRetryCancel: ctx가 끝나면 재시도 대기를 중단
+func TestOSClientRetryCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer srv.Close()
+	c, err := NewOSClientWithOptions(srv.URL, OSOptions{MaxRetries: 5, Backoff: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err = c.doCtx(ctx, "test", "GET", "/", "", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %v, want prompt return", elapsed)
+	}
+}
+
+// TestOSClientAuth: API 키가 basic auth보다 우선, 다른 호스트로 가는 요청에는 자격 증명 없음
+func TestOSClientAuth(t *testing.T) {
+	var mu sync.Mutex
+	var osAuth, otherAuth string
+	osSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		osAuth = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer osSrv.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		otherAuth = r.Header.Get("Authorization")
+		mu.Unlock()
+	}))
+	defer other.Close()
+
+	tests := []struct {
+		name string
+		opts OSOptions
+		want string
+	}{
+		{"none", OSOptions{}, ""},
+		{"basic", OSOptions{Username: "admin", Password: "pw"}, "Basic YWRtaW46cHc="},
+		{"api key", OSOptions{APIKey: "k1"}, "ApiKey k1"},
+		{"api key wins over basic", OSOptions{Username: "admin", Password: "pw", APIKey: "k1"}, "ApiKey k1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewOSClientWithOptions(osSrv.URL, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Refresh("idx"); err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+			resp, err := c.HTTPClient().Get(other.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			mu.Lock()
+			defer mu.Unlock()
+			if osAuth != tt.want {
+				t.Errorf("OpenSearch Authorization = %q, want %q", osAuth, tt.want)
+			}
+			if otherAuth != "" {
+				t.Errorf("other host Authorization = %q, want none", otherAuth)
+			}
+		})
+	}
+}
+
+// TestOSClientTLS: 사설 CA 파일로 서버 인증서 검증, CA/인증서 파일 오류는 생성 시 error
+func TestOSClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"count":7}`))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ca := writeFile("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	notPEM := writeFile("bad.pem", []byte("not a certificate"))
+
+	tests := []struct {
+		name       string
+		opts       OSOptions
+		wantNewErr bool
+		wantReqErr bool
+	}{
+		{name: "private CA", opts: OSOptions{CAFile: ca}},
+		{name: "insecure", opts: OSOptions{Insecure: true}},
+		{name: "unknown CA", opts: OSOptions{}, wantReqErr: true},
+		{name: "missing CA file", opts: OSOptions{CAFile: filepath.Join(dir, "none.pem")}, wantNewErr: true},
+		{name: "CA without PEM", opts: OSOptions{CAFile: notPEM}, wantNewErr: true},
+		{name: "bad client cert", opts: OSOptions{CertFile: notPEM, KeyFile: notPEM}, wantNewErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewOSClientWithOptions(srv.URL, tt.opts)
+			if (err != nil) != tt.wantNewErr {
+				t.Fatalf("NewOSClientWithOptions err = %v, wantErr %v", err, tt.wantNewErr)
+			}
+			if err != nil {
+				return
+			}
+			n, err := c.Count("idx", nil)
+			if (err != nil) != tt.wantReqErr {
+				t.Fatalf("Count err = %v, wantErr %v", err, tt.wantReqErr)
+			}
+			if err == nil && n != 7 {
+				t.Errorf("Count = %d, want 7", n)
+			}
+		})
+	}
+}
+
+// TestOSClientNotFound: Get/Delete는 404를 없는 문서로, Request는 HTTP 오류를 상태 코드로 반환
+func TestOSClientNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/idx/_doc/found":
+			w.Write([]byte(`{"_id":"found","_source":{"a":"b"}}`))
+		case "/bad":
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":"bad request"}`))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"found":false}`))
+		}
+	}))
+	defer srv.Close()
+	c, err := NewOSClientWithOptions(srv.URL, OSOptions{Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() (interface{}, error)
+		want interface{}
+	}{
+		{"get found", func() (interface{}, error) {
+			doc, err := c.Get("idx", "found")
+			return doc["a"], err
+		}, "b"},
+		{"get missing", func() (interface{}, error) {
+			doc, err := c.Get("idx", "missing")
+			return doc == nil, err
+		}, true},
+		{"delete missing", func() (interface{}, error) { return nil, c.Delete("idx", "missing") }, nil},
+		{"request 400 status", func() (interface{}, error) {
+			status, result, err := c.Request("GET", "/bad", nil)
+			return [2]interface{}{status, result["error"]}, err
+		}, [2]interface{}{400, "bad request"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := c.Search("idx", nil); !errors.Is(err, ErrOSNotFound) {
+		t.Errorf("Search on missing index err = %v, want ErrOSNotFound", err)
+	}
+}
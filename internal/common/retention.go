@@ -0,0 +1,333 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// retentionDocID: settings 인덱스 내 보존 정책 문서 ID
+const retentionDocID = "retention"
+
+// 보존 정책 대상 일별 인덱스 계열
+var retentionFamilies = map[string]func(prefix string) string{
+	"logs":   LogsIndexPattern,
+	"alerts": AlertsIndexPattern,
+	"scores": ScoresIndexPattern,
+}
+
+// RetentionPolicy: 계열별 보존 기간 (Days 이하 경과분 유지, 0이면 비활성) + 만료 시 동작 (delete/close)
+type RetentionPolicy struct {
+	Days   int    `json:"days"`
+	Action string `json:"action"`
+}
+
+// expired: 경과 일수(오늘 0일)가 Days를 넘으면 만료 — Days=90이면 오늘 포함 91일치 보존
+func (p RetentionPolicy) expired(age int) bool {
+	return p.Days > 0 && age > p.Days
+}
+
+// retentionAge: 인덱스 일자부터 오늘까지 달력 기준 경과 일수 (DST 전환일의 23/25시간에 영향받지 않음)
+func retentionAge(today, day time.Time) int {
+	t := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	d := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(d).Hours() / 24)
+}
+
+// RetentionCandidate: 만료 대상 인덱스
+type RetentionCandidate struct {
+	Index     string `json:"index"`
+	Family    string `json:"family"`
+	IndexDate string `json:"indexDate"`
+	AgeDays   int    `json:"ageDays"`
+	Days      int    `json:"policyDays"`
+	Action    string `json:"action"`
+	Result    string `json:"result,omitempty"`
+}
+
+// RetentionManager: 일별 인덱스 만료 처리 + 감사 기록
+type RetentionManager struct {
+	OS          *OSClient
+	IndexPrefix string
+	Defaults    map[string]RetentionPolicy // 환경변수 기본값 (settings 문서로 계열별 덮어쓰기)
+	Hour        int
+
+	mu      sync.Mutex
+	lastRun string // 스케줄 실행 일자
+}
+
+func NewRetentionManager(os *OSClient, indexPrefix string, defaults map[string]RetentionPolicy, hour int) *RetentionManager {
+	return &RetentionManager{OS: os, IndexPrefix: indexPrefix, Defaults: defaults, Hour: hour}
+}
+
+// Policies: 기본값 + settings 문서 병합 결과
+// 정책 문서를 읽지 못하면 error (저장된 정책보다 짧을 수 있는 기본값으로 삭제하지 않도록)
+func (m *RetentionManager) Policies() (map[string]RetentionPolicy, error) {
+	policies := make(map[string]RetentionPolicy, len(m.Defaults))
+	for k, v := range m.Defaults {
+		policies[k] = v
+	}
+	doc, err := m.OS.Get(SettingsIndex(m.IndexPrefix), retentionDocID)
+	if err != nil {
+		return nil, fmt.Errorf("보존 정책 문서 조회 실패: %v", err)
+	}
+	for family := range retentionFamilies {
+		p, ok := doc[family].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		policy := policies[family]
+		if days, ok := p["days"].(float64); ok {
+			policy.Days = int(days)
+		}
+		if action, ok := p["action"].(string); ok && action != "" {
+			policy.Action = action
+		}
+		policies[family] = policy
+	}
+	return policies, nil
+}
+
+// SavePolicies: 계열별 정책 검증 후 settings 인덱스 저장
+func (m *RetentionManager) SavePolicies(policies map[string]RetentionPolicy) error {
+	doc := map[string]interface{}{"updated_at": Now().Format(time.RFC3339)}
+	for family, p := range policies {
+		if _, ok := retentionFamilies[family]; !ok {
+			return fmt.Errorf("잘못된 계열 '%s' (logs/alerts/scores)", family)
+		}
+		if p.Days < 0 {
+			return fmt.Errorf("%s: days는 0 이상 필수", family)
+		}
+		if p.Action != "delete" && p.Action != "close" {
+			return fmt.Errorf("%s: 잘못된 action '%s' (delete/close)", family, p.Action)
+		}
+		doc[family] = p
+	}
+	if err := m.OS.Put(SettingsIndex(m.IndexPrefix), retentionDocID, doc); err != nil {
+		return err
+	}
+	m.OS.Refresh(SettingsIndex(m.IndexPrefix))
+	return nil
+}
+
+// Preview: 현재 정책 기준 만료 대상 (오늘/미래 날짜 인덱스는 대상 아님)
+func (m *RetentionManager) Preview() ([]RetentionCandidate, error) {
+	policies, err := m.Policies()
+	if err != nil {
+		return nil, err
+	}
+	now := Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var candidates []RetentionCandidate
+	for family, patternFn := range retentionFamilies {
+		policy := policies[family]
+		if policy.Days <= 0 {
+			continue
+		}
+		pattern := patternFn(m.IndexPrefix)
+		states, err := m.indexStates(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for index, st := range states {
+			day, err := time.ParseInLocation("2006.01.02", strings.TrimPrefix(st.name, strings.TrimSuffix(pattern, "*")), now.Location())
+			if err != nil {
+				continue
+			}
+			age := retentionAge(today, day)
+			if !policy.expired(age) {
+				continue
+			}
+			if policy.Action == "close" && st.state == "close" {
+				continue
+			}
+			candidates = append(candidates, RetentionCandidate{
+				Index: index, Family: family, IndexDate: day.Format("2006-01-02"),
+				AgeDays: age, Days: policy.Days, Action: policy.Action,
+			})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Index < candidates[j].Index })
+	return candidates, nil
+}
+
+// retentionIndex: 실제 인덱스의 상태와 날짜 판단용 이름
+type retentionIndex struct {
+	state string // open / close
+	name  string // `siem migrate --reindex`로 alias가 된 인덱스는 패턴에 맞는 alias, 그 외는 인덱스 이름
+}
+
+// indexStates: 패턴에 해당하는 인덱스(닫힌 인덱스, 재색인으로 이름이 바뀐 인덱스 포함) → 상태
+func (m *RetentionManager) indexStates(pattern string) (map[string]retentionIndex, error) {
+	migrated := fmt.Sprintf("%s-%s-migrate-%s", m.IndexPrefix, solution, strings.TrimPrefix(pattern, m.IndexPrefix+"-"+solution+"-"))
+	status, result, err := m.OS.Request("GET", "/_cluster/state/metadata/"+pattern+","+migrated+
+		"?expand_wildcards=all&filter_path=metadata.indices.*.state,metadata.indices.*.aliases", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, fmt.Errorf("%s 인덱스 조회 실패: %d", pattern, status)
+	}
+	states := make(map[string]retentionIndex)
+	metadata, _ := result["metadata"].(map[string]interface{})
+	indices, _ := metadata["indices"].(map[string]interface{})
+	for index, raw := range indices {
+		entry, _ := raw.(map[string]interface{})
+		st := retentionIndex{name: index}
+		st.state, _ = entry["state"].(string)
+		for _, a := range asList(entry["aliases"]) {
+			if alias, _ := a.(string); alias != "" {
+				if ok, _ := path.Match(pattern, alias); ok {
+					st.name = alias
+				}
+			}
+		}
+		if ok, _ := path.Match(pattern, st.name); ok {
+			states[index] = st
+		}
+	}
+	return states, nil
+}
+
+// Run: 만료 인덱스 삭제/닫기 + 건별 감사 기록
+func (m *RetentionManager) Run(trigger string) ([]RetentionCandidate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	candidates, err := m.Preview()
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		var status int
+		var result map[string]interface{}
+		if c.Action == "close" {
+			status, result, err = m.OS.Request("POST", "/"+c.Index+"/_close", nil)
+		} else {
+			status, result, err = m.OS.Request("DELETE", "/"+c.Index, nil)
+		}
+		switch {
+		case err != nil:
+			c.Result = err.Error()
+		case status >= 400:
+			c.Result = fmt.Sprintf("status %d: %v", status, result["error"])
+		default:
+			c.Result = "ok"
+		}
+		log.Printf("[Retention] %s %s (%d일 경과, 정책 %d일): %s", c.Action, c.Index, c.AgeDays, c.Days, c.Result)
+		m.audit(*c, trigger)
+	}
+	return candidates, nil
+}
+
+func (m *RetentionManager) audit(c RetentionCandidate, trigger string) {
+	_, err := m.OS.Index(RetentionAuditIndex(m.IndexPrefix), map[string]interface{}{
+		"@timestamp": Now().Format(time.RFC3339),
+		"index":      c.Index,
+		"family":     c.Family,
+		"indexDate":  c.IndexDate,
+		"ageDays":    c.AgeDays,
+		"policyDays": c.Days,
+		"action":     c.Action,
+		"result":     c.Result,
+		"trigger":    trigger,
+	})
+	if err != nil {
+		log.Printf("[Retention] 감사 기록 실패 (%s): %v", c.Index, err)
+	}
+}
+
+// Start: 매일 Hour 시에 1회 실행 (재시작 시 당일 시각이 지났으면 다음 확인 주기에 실행, 실패 시 10분 후 재시도)
+func (m *RetentionManager) Start() {
+	log.Printf("[Retention] 스케줄 시작: 매일 %02d시", m.Hour)
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		now := Now()
+		today := now.Format("2006-01-02")
+		if now.Hour() < m.Hour || m.lastRun == today {
+			continue
+		}
+		// 실패(정책 조회 오류 등)하면 다음 확인 주기에 다시 시도
+		if _, err := m.Run("schedule"); err != nil {
+			log.Printf("[Retention] 실행 중단: %v", err)
+			continue
+		}
+		m.lastRun = today
+	}
+}
+
+// RetentionController: 보존 정책 조회/저장, 만료 대상 미리보기, 수동 실행, 감사 기록 조회
+type RetentionController struct {
+	M *RetentionManager
+}
+
+func NewRetentionController(m *RetentionManager) *RetentionController {
+	return &RetentionController{M: m}
+}
+
+// Get - 현재 적용 정책
+func (c *RetentionController) Get(ctx echo.Context) error {
+	policies, err := c.M.Policies()
+	if err != nil {
+		return ctx.JSON(500, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(200, map[string]interface{}{"policies": policies, "hour": c.M.Hour})
+}
+
+// Put - 계열별 정책 저장 (예: {"logs":{"days":90,"action":"delete"}})
+func (c *RetentionController) Put(ctx echo.Context) error {
+	var policies map[string]RetentionPolicy
+	if err := ctx.Bind(&policies); err != nil {
+		return ctx.JSON(400, map[string]string{"error": "invalid JSON"})
+	}
+	if err := c.M.SavePolicies(policies); err != nil {
+		return ctx.JSON(400, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(200, map[string]string{"status": "ok"})
+}
+
+// Preview - 지금 실행하면 삭제/닫기될 인덱스
+func (c *RetentionController) Preview(ctx echo.Context) error {
+	candidates, err := c.M.Preview()
+	if err != nil {
+		return ctx.JSON(500, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(200, map[string]interface{}{"candidates": candidates, "count": len(candidates)})
+}
+
+// Run - 즉시 실행
+func (c *RetentionController) Run(ctx echo.Context) error {
+	results, err := c.M.Run("api")
+	if err != nil {
+		return ctx.JSON(500, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(200, map[string]interface{}{"results": results, "count": len(results)})
+}
+
+// Audit - 최근 처리 기록
+func (c *RetentionController) Audit(ctx echo.Context) error {
+	size := 100
+	fmt.Sscanf(ctx.QueryParam("size"), "%d", &size)
+	docs, err := c.M.OS.Search(RetentionAuditIndex(c.M.IndexPrefix), map[string]interface{}{
+		"size": size,
+		"sort": []map[string]string{{"@timestamp": "desc"}},
+	})
+	if errors.Is(err, ErrOSNotFound) {
+		// 아직 처리 기록이 없음 (감사 인덱스 미생성)
+		return ctx.JSON(200, map[string]interface{}{"records": []interface{}{}})
+	}
+	if err != nil {
+		return ctx.JSON(500, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(200, map[string]interface{}{"records": docs})
+}
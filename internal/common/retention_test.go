@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRetentionAge: 달력 기준 경과 일수와 보존 경계 (Days 이하 유지, 초과 만료)
+func TestRetentionAge(t *testing.T) {
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+	newYork, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		name        string
+		today, day  time.Time
+		days        int
+		wantAge     int
+		wantExpired bool
+	}{
+		{"today", time.Date(2024, 4, 1, 0, 0, 0, 0, seoul), time.Date(2024, 4, 1, 0, 0, 0, 0, seoul), 1, 0, false},
+		{"age equals days kept", time.Date(2024, 4, 1, 0, 0, 0, 0, seoul), time.Date(2024, 1, 2, 0, 0, 0, 0, seoul), 90, 90, false},
+		{"age over days expired", time.Date(2024, 4, 1, 0, 0, 0, 0, seoul), time.Date(2024, 1, 1, 0, 0, 0, 0, seoul), 90, 91, true},
+		{"future index", time.Date(2024, 4, 1, 0, 0, 0, 0, seoul), time.Date(2024, 4, 3, 0, 0, 0, 0, seoul), 1, -2, false},
+		{"disabled policy", time.Date(2024, 4, 1, 0, 0, 0, 0, seoul), time.Date(2020, 1, 1, 0, 0, 0, 0, seoul), 0, 1552, false},
+		// DST 시작일(23시간)을 지나도 하루로 계산
+		{"across dst start", time.Date(2024, 3, 11, 0, 0, 0, 0, newYork), time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), 0, 1, false},
+		{"across dst start boundary", time.Date(2024, 3, 12, 0, 0, 0, 0, newYork), time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), 1, 2, true},
+		{"across dst end", time.Date(2024, 11, 4, 0, 0, 0, 0, newYork), time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), 1, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			age := retentionAge(tt.today, tt.day)
+			if age != tt.wantAge {
+				t.Errorf("retentionAge = %d, want %d", age, tt.wantAge)
+			}
+			if got := (RetentionPolicy{Days: tt.days, Action: "delete"}).expired(age); got != tt.wantExpired {
+				t.Errorf("expired(%d) with days %d = %v, want %v", age, tt.days, got, tt.wantExpired)
+			}
+		})
+	}
+}
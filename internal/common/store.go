@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Store: 문서 저장소 (OpenSearch 또는 메모리)
+//
+// CEP/UEBA/LogSink는 이 인터페이스에만 의존한다. 인덱스 생성/템플릿/reindex 같은
+// 클러스터 관리 작업은 포함하지 않으며 *OSClient를 직접 사용한다.
+// 오류는 OSError로 반환하므로 errors.Is(err, ErrOSNotFound) 등으로 판별한다.
+type Store interface {
+	// Get: 문서 단건 (_source + "_id"), 없으면 nil, nil
+	Get(index, docID string) (map[string]interface{}, error)
+	Put(index, docID string, doc interface{}) error
+	// Index: 자동 _id로 색인 후 _id 반환
+	Index(index string, doc interface{}) (string, error)
+	// Update: 부분 갱신 (객체 필드는 병합)
+	Update(index, docID string, fields map[string]interface{}) error
+	// Delete: 없는 문서는 무시
+	Delete(index, docID string) error
+	Refresh(index string) error
+	Count(index string, query interface{}) (int, error)
+	// Search: hits의 _source + "_id" 목록
+	Search(index string, body interface{}) ([]map[string]interface{}, error)
+	// SearchRaw: _search 응답 전체 (hits, aggregations)
+	SearchRaw(index string, body interface{}) (map[string]interface{}, error)
+	// Iterate: query에 맞는 전체 문서를 순회 (OpenSearch PIT는 _shard_doc 순, 그 외는 _id 순)
+	Iterate(ctx context.Context, index string, query interface{}, fn func(hit SearchHit) error) error
+	// Validate: 검색 본문({"query": ...}) 검증, 유효하지 않으면 error
+	Validate(index string, body interface{}) error
+	// Bulk: NDJSON _bulk 요청, 응답 전체 반환 (항목별 결과는 호출측에서 해석)
+	Bulk(body []byte) (map[string]interface{}, error)
+}
+
+var (
+	_ Store = (*OSClient)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// SearchAll: Iterate로 query에 맞는 전체 문서 수집 (Search와 같은 형태: _source + "_id")
+func SearchAll(ctx context.Context, s Store, index string, query interface{}) ([]map[string]interface{}, error) {
+	var docs []map[string]interface{}
+	err := s.Iterate(ctx, index, query, func(hit SearchHit) error {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(hit.Source, &doc); err != nil || doc == nil {
+			return nil
+		}
+		doc["_id"] = hit.ID
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
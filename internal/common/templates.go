@@ -0,0 +1,242 @@
+package common
+
+import "fmt"
+
+// IndexTemplate: 인덱스 계열별 index template 정의
+// Version을 올리면 `siem migrate`가 템플릿을 갱신하고, 이전 버전으로 생성된 인덱스를 drift로 보고한다.
+type IndexTemplate struct {
+	Name     string   // 템플릿 이름
+	Family   string   // 인덱스 계열 (--index 필터용)
+	Patterns []string // index_patterns
+	Daily    bool     // 일별 인덱스 계열 (최근 일자 인덱스는 재색인 대상에서 제외)
+	Version  int
+	Mappings map[string]interface{}
+}
+
+// 템플릿 매핑 공통 조각
+//
+// 문자열 식별자는 keyword + ".keyword" 하위 필드로 매핑한다.
+// 기존 코드의 `term userId`와 `userId.keyword` 조회가 어느 인덱스에서나 같은 결과를 내도록 하기 위함.
+func keywordField() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "keyword", "ignore_above": 1024,
+		"fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256}},
+	}
+}
+
+// textField: 검색용 문자열 (multi_match 대상) + 정렬/집계용 .keyword
+func textField() map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "text",
+		"fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256}},
+	}
+}
+
+func typedField(typ string) map[string]interface{} {
+	return map[string]interface{}{"type": typ}
+}
+
+// dateField: RFC3339, "yyyy-MM-dd", Flink TIMESTAMP 문자열, epoch millis
+func dateField() map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "date",
+		"format": "strict_date_optional_time||yyyy-MM-dd HH:mm:ss.SSS||yyyy-MM-dd HH:mm:ss||yyyy-MM-dd||epoch_millis",
+	}
+}
+
+// disabledField: 저장만 하고 색인하지 않는 객체 (값 타입이 섞이는 규칙 조건, field-meta 정의 등)
+func disabledField() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "enabled": false}
+}
+
+func storedString() map[string]interface{} {
+	return map[string]interface{}{"type": "keyword", "index": false, "doc_values": false}
+}
+
+// dynamicTemplates: 명시되지 않은 필드의 동적 매핑 (문자열 → keyword+.keyword, 정수 → long, 실수 → double)
+func dynamicTemplates() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"strings_as_keyword": map[string]interface{}{
+			"match_mapping_type": "string",
+			"mapping":            keywordField(),
+		}},
+		map[string]interface{}{"longs": map[string]interface{}{
+			"match_mapping_type": "long",
+			"mapping":            typedField("long"),
+		}},
+		map[string]interface{}{"doubles": map[string]interface{}{
+			"match_mapping_type": "double",
+			"mapping":            typedField("double"),
+		}},
+	}
+}
+
+func mapping(version int, props map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"_meta":             map[string]interface{}{"managedBy": "siem migrate", "version": version},
+		"date_detection":    false,
+		"dynamic_templates": dynamicTemplates(),
+		"properties":        props,
+	}
+}
+
+// cefDynamicTemplates: cefExtensions 아래 동적 숫자 필드는 정수로 보여도 double
+// label 확장 이름(cfp1Label)/field-meta 숫자 필드는 LogSink가 float64로 변환하는데, 정수값 실수(3.0)는 JSON에 "3"으로
+// 직렬화되어 기본 동적 매핑이면 처음 본 값 기준 long으로 고정되고 이후 소수값이 잘린다.
+func cefDynamicTemplates() []interface{} {
+	return append([]interface{}{
+		map[string]interface{}{"cef_numbers_as_double": map[string]interface{}{
+			"path_match":         "cefExtensions.*",
+			"match_mapping_type": "long",
+			"mapping":            typedField("double"),
+		}},
+	}, dynamicTemplates()...)
+}
+
+// cefExtensionsMapping: 표준 숫자 키는 LogSink 타입 사전과 동일하게 고정, 나머지는 동적 매핑
+func cefExtensionsMapping() map[string]interface{} {
+	props := map[string]interface{}{
+		"suid":  keywordField(),
+		"suser": keywordField(),
+		"src":   keywordField(),
+		"dst":   keywordField(),
+		"rt":    keywordField(), // 원본 표기 보존 (정규화된 시각은 @timestamp)
+	}
+	for key, typ := range cefStandardTypes {
+		props[key] = typedField(typ)
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// IndexTemplates: indices.go의 모든 인덱스 계열 템플릿
+func IndexTemplates(prefix string) []IndexTemplate {
+	name := func(family string) string { return fmt.Sprintf("%s-%s-%s", prefix, solution, family) }
+	logsMapping := mapping(3, map[string]interface{}{
+		"@timestamp":    dateField(),
+		"ingestTime":    dateField(),
+		"originalTime":  dateField(),
+		"timeSource":    keywordField(),
+		"futureEvent":   typedField("boolean"),
+		"fingerprint":   keywordField(),
+		"duplicate":     typedField("boolean"),
+		"msgId":         keywordField(),
+		"hostname":      keywordField(),
+		"appName":       keywordField(),
+		"severity":      keywordField(),
+		"name":          textField(),
+		"message":       textField(),
+		"cefExtensions": cefExtensionsMapping(),
+	})
+	logsMapping["dynamic_templates"] = cefDynamicTemplates()
+	return []IndexTemplate{
+		{
+			Name: name("event-logs"), Family: "event-logs", Daily: true, Version: 3,
+			Patterns: []string{LogsIndexPattern(prefix)},
+			Mappings: logsMapping,
+		},
+		{
+			Name: name("cep-alerts"), Family: "cep-alerts", Daily: true, Version: 2,
+			Patterns: []string{AlertsIndexPattern(prefix)},
+			Mappings: mapping(2, map[string]interface{}{
+				"@timestamp": dateField(),
+				"ts":         dateField(),
+				"ruleId":     keywordField(),
+				"ruleName":   textField(),
+				"severity":   keywordField(),
+				"userId":     keywordField(),
+				"hostname":   keywordField(),
+				"userIp":     keywordField(),
+				"cnt":        typedField("long"),
+				"replayed":   typedField("boolean"),
+			}),
+		},
+		{
+			Name: name("ueba-scores"), Family: "ueba-scores", Daily: true, Version: 1,
+			Patterns: []string{ScoresIndexPattern(prefix)},
+			Mappings: mapping(1, map[string]interface{}{
+				"@timestamp":   dateField(),
+				"userId":       keywordField(),
+				"riskScore":    typedField("double"),
+				"riskLevel":    keywordField(),
+				"status":       keywordField(),
+				"ruleScore":    typedField("double"),
+				"anomalyScore": typedField("double"),
+				"dailyScore":   typedField("double"),
+				"decayedPrev":  typedField("double"),
+				"prevScore":    typedField("double"),
+				// 규칙 이름/msgId가 키인 맵 — 값은 동적 템플릿으로 숫자 매핑
+				"ruleScores":  typedField("object"),
+				"eventCounts": typedField("object"),
+				"eventValues": typedField("object"),
+			}),
+		},
+		{
+			Name: name("common-rules"), Family: "common-rules", Version: 1,
+			Patterns: []string{RulesIndex(prefix)},
+			Mappings: mapping(1, map[string]interface{}{
+				"ruleId":       keywordField(),
+				"name":         textField(),
+				"category":     keywordField(),
+				"enabled":      typedField("boolean"),
+				"weight":       typedField("double"),
+				"match":        disabledField(),
+				"aggregate":    disabledField(),
+				"sql":          storedString(),
+				"jobId":        keywordField(),
+				"jobStatus":    keywordField(),
+				"jobStartedAt": dateField(),
+				"createdAt":    dateField(),
+				"updatedAt":    dateField(),
+				"cep": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+					"enabled":  typedField("boolean"),
+					"severity": keywordField(),
+				}},
+				"ueba": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+					"enabled": typedField("boolean"),
+				}},
+			}),
+		},
+		{
+			Name: name("common-settings"), Family: "common-settings", Version: 1,
+			Patterns: []string{SettingsIndex(prefix)},
+			Mappings: mapping(1, map[string]interface{}{
+				"updatedAt":  keywordField(), // LogSink 파이프라인 버전 비교용 원문 (RFC3339Nano)
+				"updated_at": dateField(),
+				"pipeline":   storedString(),
+			}),
+		},
+		{
+			Name: name("ueba-baselines"), Family: "ueba-baselines", Version: 1,
+			Patterns: []string{BaselinesIndex(prefix)},
+			Mappings: mapping(1, map[string]interface{}{
+				"mean":        typedField("double"),
+				"stddev":      typedField("double"),
+				"sampleDays":  typedField("integer"),
+				"lastUpdated": dateField(),
+			}),
+		},
+		{
+			Name: name("common-field-meta"), Family: "common-field-meta", Version: 1,
+			Patterns: []string{FieldMetaIndex(prefix)},
+			Mappings: mapping(1, map[string]interface{}{
+				"migratedAt": dateField(),
+				"events":     disabledField(),
+			}),
+		},
+		{
+			Name: name("common-retention-audit"), Family: "common-retention-audit", Version: 1,
+			Patterns: []string{RetentionAuditIndex(prefix)},
+			Mappings: mapping(1, map[string]interface{}{
+				"@timestamp": dateField(),
+				"index":      keywordField(),
+				"family":     keywordField(),
+				"indexDate":  dateField(),
+				"ageDays":    typedField("integer"),
+				"policyDays": typedField("integer"),
+				"action":     keywordField(),
+				"result":     keywordField(),
+				"trigger":    keywordField(),
+			}),
+		},
+	}
+}
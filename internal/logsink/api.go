@@ -0,0 +1,222 @@
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// PipelineController: 변환 파이프라인 조회/저장/dry-run API
+type PipelineController struct {
+	transformer *transformer
+	normalizer  *normalizer // nil이면 dry-run 결과에 정규화 문서 생략
+	redactor    *redactor
+}
+
+func NewPipelineController(t *transformer, n *normalizer, r *redactor) *PipelineController {
+	return &PipelineController{transformer: t, normalizer: n, redactor: r}
+}
+
+// Get - 현재 적용 중인 파이프라인
+func (c *PipelineController) Get(ctx echo.Context) error {
+	p := c.transformer.Get()
+	if p == nil {
+		p = &PipelineConfig{Topics: map[string][]TransformStep{}}
+	}
+	c.transformer.mu.Lock()
+	version := c.transformer.version
+	c.transformer.mu.Unlock()
+	source := "settings"
+	if version == "" {
+		source = "file"
+	}
+	return ctx.JSON(200, map[string]interface{}{
+		"pipeline":  p,
+		"source":    source,
+		"file":      c.transformer.file,
+		"updatedAt": version,
+	})
+}
+
+// Put - 파이프라인 검증 후 settings 인덱스 저장 (즉시 적용)
+func (c *PipelineController) Put(ctx echo.Context) error {
+	var p PipelineConfig
+	if err := ctx.Bind(&p); err != nil {
+		return ctx.JSON(400, map[string]string{"error": "invalid JSON"})
+	}
+	if err := p.compile(); err != nil {
+		return ctx.JSON(400, map[string]string{"error": err.Error()})
+	}
+	if err := c.transformer.Save(&p); err != nil {
+		return ctx.JSON(500, map[string]string{"error": err.Error()})
+	}
+	log.Printf("[LogSink] 파이프라인 저장 (%d개 토픽)", len(p.Topics))
+	return ctx.JSON(200, map[string]string{"status": "ok"})
+}
+
+// DryRun - 샘플 이벤트에 파이프라인 적용 결과 (before/after)
+// pipeline 미지정 시 현재 적용 중인 파이프라인 사용
+func (c *PipelineController) DryRun(ctx echo.Context) error {
+	var req struct {
+		Topic    string                 `json:"topic"`
+		Event    map[string]interface{} `json:"event"`
+		Pipeline *PipelineConfig        `json:"pipeline"`
+	}
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(400, map[string]string{"error": "invalid JSON"})
+	}
+	if req.Event == nil {
+		return ctx.JSON(400, map[string]string{"error": "event 필수"})
+	}
+	p := req.Pipeline
+	if p != nil {
+		if err := p.compile(); err != nil {
+			return ctx.JSON(400, map[string]string{"error": err.Error()})
+		}
+	} else {
+		p = c.transformer.Get()
+	}
+
+	// 실제 처리와 같이 CEF label 변환 후 파이프라인 적용
+	if ext, ok := req.Event["cefExtensions"].(map[string]interface{}); ok {
+		common.ExpandCEFLabels(ext)
+	}
+	after := copyEvent(req.Event)
+	errs := p.Apply(req.Topic, after)
+	if errs == nil {
+		errs = []string{}
+	}
+	resp := map[string]interface{}{
+		"before": req.Event,
+		"after":  after,
+		"errors": errs,
+	}
+	if c.normalizer != nil {
+		resp["normalized"] = c.normalizer.Normalize(req.Topic, after)
+	}
+	if c.redactor != nil && c.redactor.Enabled() {
+		resp["redacted"] = map[string]interface{}{
+			"opensearch": c.redactor.Apply(after, c.redactor.cfg.OpenSearch),
+			"kafka":      c.redactor.Apply(after, c.redactor.cfg.Kafka),
+		}
+	}
+	return ctx.JSON(200, resp)
+}
+
+// copyEvent: JSON 왕복으로 깊은 복사
+func copyEvent(event map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(event)
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// adminAuth: 관리 API 인증 — 관리 키(LOGSINK_ADMIN_KEY), 관리 키가 없으면 수집 키 중 하나
+// 둘 다 없으면 HTTP 수집도 비활성이므로 내부망 전용으로 보고 인증하지 않는다.
+func adminAuth(adminKey string, ingestKeys map[string]string) echo.MiddlewareFunc {
+	keys := ingestKeys
+	if adminKey != "" {
+		keys = map[string]string{"admin": adminKey}
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if len(keys) == 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			if matchKey(requestKey(c), keys) == "" {
+				return c.JSON(401, map[string]string{"error": "유효하지 않은 API 키"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// startAPI: LogSink 관리/수집 API 서버 (ctx 종료 시 graceful shutdown)
+// 인증서가 설정되면 HTTPS로 기동한다. /api/health 외 관리 API는 adminAuth 적용.
+func startAPI(ctx context.Context, cfg *config.Config, sink *Sink) {
+	port := cfg.Server.Port
+	if port == "" {
+		return
+	}
+	pipelineCtrl := NewPipelineController(sink.transformer, sink.normalizer, sink.redactor)
+	auth := adminAuth(cfg.LogSink.AdminKey, cfg.LogSink.IngestKeys)
+
+	e := echo.New()
+	e.HideBanner = true
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+
+	e.GET("/api/health", func(c echo.Context) error {
+		return c.JSON(200, map[string]string{"status": "ok"})
+	})
+
+	// 변환 파이프라인 API
+	e.GET("/api/pipeline", pipelineCtrl.Get, auth)
+	e.PUT("/api/pipeline", pipelineCtrl.Put, auth)
+	e.POST("/api/pipeline/dry-run", pipelineCtrl.DryRun, auth)
+
+	// 정규화 매핑 테이블
+	e.GET("/api/normalize", func(c echo.Context) error {
+		if sink.normalizer == nil {
+			return c.JSON(200, map[string]interface{}{"enabled": false})
+		}
+		return c.JSON(200, map[string]interface{}{
+			"enabled": true,
+			"output":  sink.normalizer.output,
+			"topic":   sink.normalizer.topic,
+			"profile": sink.normalizer.profile,
+		})
+	}, auth)
+
+	// HTTP 수집 (출처별 API 키가 설정된 경우만)
+	if len(cfg.LogSink.IngestKeys) > 0 {
+		ingestCtrl := NewIngestController(sink, cfg.LogSink.IngestKeys, cfg.LogSink.IngestMaxBytes)
+		e.POST("/api/ingest", ingestCtrl.Ingest)
+		log.Printf("[LogSink] HTTP 수집 활성: POST /api/ingest (출처 %d개)", len(cfg.LogSink.IngestKeys))
+	}
+
+	// 변환 토픽 파티션별 키 분포
+	e.GET("/api/partitions", func(c echo.Context) error {
+		return c.JSON(200, sink.partitions.Snapshot())
+	}, auth)
+
+	// 출처별 수집량 상태 (?dimension=topic|msgId|host&state=silent|spike|normal|learning)
+	e.GET("/api/volume", func(c echo.Context) error {
+		sources := sink.volume.Status(c.QueryParam("dimension"), c.QueryParam("state"))
+		return c.JSON(200, map[string]interface{}{
+			"interval": cfg.LogSink.VolumeInterval.String(),
+			"sources":  sources,
+		})
+	}, auth)
+
+	// 보강 참조 데이터 상태
+	e.GET("/api/enrichment", func(c echo.Context) error {
+		return c.JSON(200, sink.enricher.Status())
+	}, auth)
+
+	go func() {
+		var err error
+		if cfg.LogSink.TLSCert != "" {
+			err = e.StartTLS(port, cfg.LogSink.TLSCert, cfg.LogSink.TLSKey)
+		} else {
+			err = e.Start(port)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("[LogSink] API 서버 실패: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		e.Shutdown(shutdownCtx)
+	}()
+	log.Printf("[LogSink] API 서버 시작: %s", port)
+}
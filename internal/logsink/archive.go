@@ -0,0 +1,523 @@
+package logsink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// 이벤트 시각 일자 세그먼트를 이 시간 동안 쓰지 않으면 닫는다 (지연 도착 이벤트가 드문 과거 일자)
+const archiveIdleClose = 10 * time.Minute
+
+const archiveManifest = "manifest.json"
+
+// ArchiveManifest: 일자 디렉터리별 닫힌 세그먼트 목록
+type ArchiveManifest struct {
+	Day      string           `json:"day"`
+	Segments []ArchiveSegment `json:"segments"`
+}
+
+// ArchiveSegment: 세그먼트 파일 1개 (SHA256은 압축 파일 전체 기준)
+type ArchiveSegment struct {
+	File     string `json:"file"`
+	Events   int    `json:"events"`
+	Bytes    int64  `json:"bytes"`
+	SHA256   string `json:"sha256"`
+	OpenedAt string `json:"openedAt"`
+	ClosedAt string `json:"closedAt"`
+}
+
+// archiveSegment: 쓰기 중인 세그먼트
+type archiveSegment struct {
+	day      string
+	name     string
+	file     *os.File
+	gz       *gzip.Writer
+	hash     hash.Hash
+	size     *countingWriter
+	events   int
+	pending  [][]byte // 마지막 flush 이후 기록분 (디스크 기록 실패 시 새 세그먼트에 다시 쓴다)
+	openedAt time.Time
+	lastAt   time.Time
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// archiver: 변환된 이벤트를 <dir>/<yyyy-mm-dd>/events-<yyyy-mm-dd>-<seq>.ndjson.gz 에 기록
+//
+// 일자는 이벤트 시각(@timestamp) 기준으로 event-logs 일별 인덱스와 같다.
+// 세그먼트는 압축 크기가 maxBytes를 넘으면 다음 번호로 넘어가며, 닫을 때 manifest에 건수/크기/SHA256을 기록한다.
+// 재기동 시 기존 파일에 이어 쓰지 않고 항상 새 번호로 시작한다 (비정상 종료로 잘린 gzip 보호).
+//
+// 쓰기/flush가 실패한(디스크 가득 참, EIO 등) 세그먼트는 manifest 없이 버리고, 마지막 flush 이후 기록분을
+// 새 세그먼트에 다시 쓴다. 그것도 실패하면 error를 반환하고 기록분은 다음 Write/Flush 때 다시 시도한다.
+// bulk 색인은 Flush 성공 후에만 진행되므로 ack된 이벤트는 아카이브에도 있다.
+type archiver struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	segments map[string]*archiveSegment // 일자 → 쓰기 중인 세그먼트
+	orphans  map[string][][]byte        // 일자 → 세그먼트를 잃고 아직 다시 쓰지 못한 기록분
+}
+
+func newArchiver(dir string, maxBytes int64) *archiver {
+	return &archiver{
+		dir: dir, maxBytes: maxBytes,
+		segments: make(map[string]*archiveSegment),
+		orphans:  make(map[string][][]byte),
+	}
+}
+
+// Write: 이벤트 1건 추가 (day: yyyy-mm-dd)
+func (a *archiver) Write(day string, doc []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// 쓰기 실패 시 세그먼트를 버리고 새 세그먼트에 한 번 더 시도
+	for attempt := 0; ; attempt++ {
+		seg, err := a.current(day)
+		if err != nil {
+			return err
+		}
+		seg.lastAt = time.Now()
+		if _, err := seg.gz.Write(append(doc, '\n')); err != nil {
+			a.abandon(seg, err)
+			if attempt == 0 {
+				continue
+			}
+			return fmt.Errorf("아카이브 기록 실패 (%s): %v", day, err)
+		}
+		seg.pending = append(seg.pending, doc)
+		seg.events++
+		return nil
+	}
+}
+
+// current: 일자의 쓰기 세그먼트 (orphans 재기록, 크기 초과 시 다음 번호로) (a.mu 보유)
+func (a *archiver) current(day string) (*archiveSegment, error) {
+	if err := a.rewriteOrphans(day); err != nil {
+		return nil, err
+	}
+	seg := a.segments[day]
+	if seg != nil && seg.size.n >= a.maxBytes {
+		a.closeSegment(seg)
+		if err := a.rewriteOrphans(day); err != nil {
+			return nil, err
+		}
+		seg = a.segments[day]
+	}
+	if seg == nil {
+		var err error
+		if seg, err = a.openSegment(day); err != nil {
+			return nil, err
+		}
+		a.segments[day] = seg
+	}
+	return seg, nil
+}
+
+// Flush: 압축 버퍼를 파일로 내보냄 (bulk 전송 전에 호출 → ack된 이벤트는 아카이브에도 기록됨)
+// 실패하면 error — 호출측(bulk)은 전송하지 않고 재시도한다
+func (a *archiver) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var firstErr error
+	for _, seg := range a.segments {
+		if err := seg.gz.Flush(); err != nil {
+			a.abandon(seg, err)
+			continue
+		}
+		seg.pending = nil
+	}
+	for day := range a.orphans {
+		if err := a.rewriteOrphans(day); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// abandon: 기록 실패 세그먼트 버리기 (manifest 미등록 → 복원 시 읽을 수 있는 데까지), 기록분은 orphans로 (a.mu 보유)
+func (a *archiver) abandon(seg *archiveSegment, cause error) {
+	delete(a.segments, seg.day)
+	seg.gz.Close()
+	seg.file.Close()
+	if len(seg.pending) > 0 {
+		a.orphans[seg.day] = append(a.orphans[seg.day], seg.pending...)
+	}
+	log.Printf("[LogSink] 아카이브 세그먼트 기록 실패, 새 세그먼트로 전환 (%s/%s, 다시 쓸 이벤트 %d건): %v",
+		seg.day, seg.name, len(a.orphans[seg.day]), cause)
+}
+
+// rewriteOrphans: 일자의 orphans를 새 세그먼트에 기록 후 flush (a.mu 보유)
+func (a *archiver) rewriteOrphans(day string) error {
+	docs := a.orphans[day]
+	if len(docs) == 0 {
+		return nil
+	}
+	seg, err := a.openSegment(day)
+	if err != nil {
+		return fmt.Errorf("아카이브 세그먼트 생성 실패: %v", err)
+	}
+	for _, doc := range docs {
+		if _, err = seg.gz.Write(append(doc, '\n')); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = seg.gz.Flush()
+	}
+	if err != nil {
+		// orphans는 그대로 두고 세그먼트만 버림
+		seg.gz.Close()
+		seg.file.Close()
+		return fmt.Errorf("아카이브 기록 실패 (%s/%s): %v", day, seg.name, err)
+	}
+	seg.events = len(docs)
+	delete(a.orphans, day)
+	if cur := a.segments[day]; cur != nil {
+		// 같은 일자에 쓰기 중인 세그먼트가 있으면 닫고 새 세그먼트로 교체
+		a.closeSegment(cur)
+	}
+	a.segments[day] = seg
+	return nil
+}
+
+// Run: 유휴 세그먼트 정리, done 시 전체 닫기
+func (a *archiver) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			a.Close()
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			for _, seg := range a.segments {
+				if time.Since(seg.lastAt) >= archiveIdleClose {
+					a.closeSegment(seg)
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+func (a *archiver) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, seg := range a.segments {
+		a.closeSegment(seg)
+	}
+}
+
+func (a *archiver) openSegment(day string) (*archiveSegment, error) {
+	dayDir := filepath.Join(a.dir, day)
+	if err := os.MkdirAll(dayDir, 0o755); err != nil {
+		return nil, err
+	}
+	seq := 1
+	existing, _ := filepath.Glob(filepath.Join(dayDir, "events-"+day+"-*.ndjson.gz"))
+	for _, path := range existing {
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(filepath.Base(path), "events-"+day+"-"), "%d", &n); err == nil && n >= seq {
+			seq = n + 1
+		}
+	}
+	name := fmt.Sprintf("events-%s-%04d.ndjson.gz", day, seq)
+	f, err := os.OpenFile(filepath.Join(dayDir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	size := &countingWriter{w: io.MultiWriter(f, h)}
+	return &archiveSegment{
+		day: day, name: name, file: f, gz: gzip.NewWriter(size), hash: h, size: size,
+		openedAt: common.Now(), lastAt: time.Now(),
+	}, nil
+}
+
+// closeSegment: gzip 종료 + manifest 등록 (a.mu 보유 상태에서 호출)
+func (a *archiver) closeSegment(seg *archiveSegment) {
+	if err := seg.gz.Close(); err != nil {
+		a.abandon(seg, err)
+		return
+	}
+	delete(a.segments, seg.day)
+	seg.file.Close()
+
+	entry := ArchiveSegment{
+		File:     seg.name,
+		Events:   seg.events,
+		Bytes:    seg.size.n,
+		SHA256:   hex.EncodeToString(seg.hash.Sum(nil)),
+		OpenedAt: seg.openedAt.Format(time.RFC3339),
+		ClosedAt: common.Now().Format(time.RFC3339),
+	}
+	path := filepath.Join(a.dir, seg.day, archiveManifest)
+	manifest, _ := readArchiveManifest(path)
+	if manifest == nil {
+		manifest = &ArchiveManifest{Day: seg.day}
+	}
+	manifest.Segments = append(manifest.Segments, entry)
+	if err := writeArchiveManifest(path, manifest); err != nil {
+		log.Printf("[LogSink] 아카이브 manifest 기록 실패 (%s): %v", path, err)
+		return
+	}
+	log.Printf("[LogSink] 아카이브 세그먼트 종료: %s/%s (%d건, %dKB)", seg.day, seg.name, seg.events, seg.size.n/1024)
+}
+
+func readArchiveManifest(path string) (*ArchiveManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m ArchiveManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// writeArchiveManifest: 임시 파일 기록 후 rename (기록 중 중단돼도 이전 manifest 유지)
+func writeArchiveManifest(path string, m *ArchiveManifest) error {
+	data, _ := json.MarshalIndent(m, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RestoreOptions: 아카이브 복원 옵션
+type RestoreOptions struct {
+	Dir    string
+	From   string // yyyy-mm-dd (포함)
+	To     string // yyyy-mm-dd (포함)
+	DryRun bool   // 색인 없이 검증/집계만
+}
+
+// RestoreResult: 일자별 복원 결과
+type RestoreResult struct {
+	Day      string
+	Index    string
+	Files    int
+	Events   int
+	Indexed  int
+	Existing int // 이미 복원된 문서 (동일 _id)
+	Failed   int
+	Errors   []string // 체크섬 불일치, 손상 파일 등
+}
+
+// RestoreArchive: 기간 내 아카이브를 event-logs-<yyyy.mm.dd> 인덱스로 재색인
+//
+// 문서 _id는 실시간 색인과 같은 eventId이고 op_type=create로 색인하므로 같은 기간을 여러 번 복원하거나
+// 이미 색인된 기간을 복원해도, 버려진 세그먼트와 다시 쓴 사본이 함께 있어도 중복되지 않는다.
+// eventId가 없는 이전 형식 아카이브는 지문, 그것도 없으면 "세그먼트 파일명:줄 번호"의 해시를 쓰므로 반복 복원만 중복이 없다.
+// manifest의 체크섬과 다른 세그먼트는 건너뛰고, manifest에 없는 세그먼트(비정상 종료 시 쓰기 중이던 파일)는 읽을 수 있는 데까지 복원한다.
+func RestoreArchive(osClient common.Store, prefix string, opts RestoreOptions, batchSize int) ([]RestoreResult, error) {
+	from, err := time.Parse("2006-01-02", opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("잘못된 --from '%s' (yyyy-mm-dd)", opts.From)
+	}
+	to, err := time.Parse("2006-01-02", opts.To)
+	if err != nil {
+		return nil, fmt.Errorf("잘못된 --to '%s' (yyyy-mm-dd)", opts.To)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("--to가 --from보다 이전")
+	}
+
+	var results []RestoreResult
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := d.Format("2006-01-02")
+		res := RestoreResult{Day: day, Index: common.DailyLogsIndex(prefix, d.Format("2006.01.02"))}
+		files, errs := archiveDayFiles(filepath.Join(opts.Dir, day))
+		res.Errors = append(res.Errors, errs...)
+		if len(files) == 0 {
+			if len(res.Errors) > 0 {
+				results = append(results, res)
+			}
+			continue
+		}
+		for _, path := range files {
+			res.Files++
+			if err := restoreSegment(osClient, path, &res, opts.DryRun, batchSize); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			}
+		}
+		if !opts.DryRun {
+			osClient.Refresh(res.Index)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// archiveDayFiles: 복원할 세그먼트 경로 (체크섬 검증 통과분 + manifest 미등록분)
+func archiveDayFiles(dayDir string) ([]string, []string) {
+	paths, _ := filepath.Glob(filepath.Join(dayDir, "*.ndjson.gz"))
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	listed := make(map[string]ArchiveSegment)
+	if m, err := readArchiveManifest(filepath.Join(dayDir, archiveManifest)); err == nil {
+		for _, s := range m.Segments {
+			listed[s.File] = s
+		}
+	}
+
+	var files, errs []string
+	for _, path := range paths {
+		name := filepath.Base(path)
+		seg, ok := listed[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: manifest 미등록 (쓰기 중 종료된 세그먼트, 읽을 수 있는 데까지 복원)", name))
+			files = append(files, path)
+			continue
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if sum != seg.SHA256 {
+			errs = append(errs, fmt.Sprintf("%s: 체크섬 불일치 (건너뜀)", name))
+			continue
+		}
+		files = append(files, path)
+	}
+	return files, errs
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func restoreSegment(osClient common.Store, path string, res *RestoreResult, dryRun bool, batchSize int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	name := filepath.Base(path)
+	var body bytes.Buffer
+	pending := 0
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		defer func() { body.Reset(); pending = 0 }()
+		if dryRun {
+			return nil
+		}
+		result, err := osClient.Bulk(body.Bytes())
+		if err != nil {
+			return err
+		}
+		items, _ := result["items"].([]interface{})
+		for _, raw := range items {
+			entry, _ := raw.(map[string]interface{})
+			action, _ := entry["create"].(map[string]interface{})
+			status, _ := action["status"].(float64)
+			switch {
+			case status >= 200 && status < 300:
+				res.Indexed++
+			case status == 409:
+				res.Existing++
+			default:
+				res.Failed++
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		doc := scanner.Bytes()
+		if len(doc) == 0 {
+			continue
+		}
+		res.Events++
+		action, _ := json.Marshal(map[string]interface{}{"create": map[string]interface{}{"_index": res.Index, "_id": archiveDocID(doc, name, line)}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+		pending++
+		if pending >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	// 잘린 gzip(unexpected EOF)은 읽은 데까지 복원 후 오류로 보고
+	return scanner.Err()
+}
+
+// archiveDocID: 실시간 색인과 같은 _id (eventId → 이전 형식의 fingerprint, 이미 색인된 문서는 409), 없으면 "파일명:줄 번호" 해시
+func archiveDocID(doc []byte, file string, line int) string {
+	var meta struct {
+		EventID     string `json:"eventId"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	if json.Unmarshal(doc, &meta) == nil {
+		if meta.EventID != "" {
+			return meta.EventID
+		}
+		if meta.Fingerprint != "" {
+			return meta.Fingerprint
+		}
+	}
+	id := sha1.Sum([]byte(fmt.Sprintf("%s:%d", file, line)))
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,196 @@
+package logsink
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// TestArchiveDocID: eventId → fingerprint → "파일명:줄 번호" 해시 순
+func TestArchiveDocID(t *testing.T) {
+	lineHash := archiveDocID([]byte(`{"msgId":"USB"}`), "events-2024-01-01-0001.ndjson.gz", 3)
+	tests := []struct {
+		name string
+		doc  string
+		file string
+		line int
+		want string // 빈값이면 lineHash와 달라야 함
+	}{
+		{"eventId first", `{"eventId":"e1","fingerprint":"f1"}`, "a.ndjson.gz", 1, "e1"},
+		{"fingerprint for old archives", `{"fingerprint":"f1"}`, "a.ndjson.gz", 1, "f1"},
+		{"same file and line same hash", `{"msgId":"PRINT"}`, "events-2024-01-01-0001.ndjson.gz", 3, lineHash},
+		{"invalid json uses file and line", `not json`, "events-2024-01-01-0001.ndjson.gz", 3, lineHash},
+		{"other line differs", `{"msgId":"USB"}`, "events-2024-01-01-0001.ndjson.gz", 4, ""},
+		{"other file differs", `{"msgId":"USB"}`, "events-2024-01-01-0002.ndjson.gz", 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := archiveDocID([]byte(tt.doc), tt.file, tt.line)
+			if tt.want == "" {
+				if got == lineHash {
+					t.Errorf("archiveDocID(%s:%d) = %s, want different from %s", tt.file, tt.line, got, lineHash)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("archiveDocID(%s) = %s, want %s", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRestoreArchive: 반복 복원/이미 색인된 이벤트는 같은 _id라 중복되지 않음, dry-run은 색인하지 않음
+func TestRestoreArchive(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	dir := t.TempDir()
+	a := newArchiver(dir, 1<<20)
+	for i := 1; i <= 3; i++ {
+		if err := a.Write("2024-01-01", []byte(fmt.Sprintf(`{"eventId":"e%d","msgId":"USB"}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Write("2024-01-02", []byte(`{"msgId":"PRINT"}`)); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	store := common.NewMemoryStore()
+	day1 := common.DailyLogsIndex("test", "2024.01.01")
+	day2 := common.DailyLogsIndex("test", "2024.01.02")
+	// 실시간 색인으로 이미 들어간 이벤트
+	if err := store.Put(day1, "e2", map[string]interface{}{"eventId": "e2", "msgId": "USB"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		dryRun       bool
+		wantIndexed  map[string]int
+		wantExisting map[string]int
+		wantDocs     map[string]int
+	}{
+		{
+			name:         "dry run",
+			dryRun:       true,
+			wantIndexed:  map[string]int{"2024-01-01": 0, "2024-01-02": 0},
+			wantExisting: map[string]int{"2024-01-01": 0, "2024-01-02": 0},
+			wantDocs:     map[string]int{day1: 1, day2: 0},
+		},
+		{
+			name:         "first restore skips live event",
+			wantIndexed:  map[string]int{"2024-01-01": 2, "2024-01-02": 1},
+			wantExisting: map[string]int{"2024-01-01": 1, "2024-01-02": 0},
+			wantDocs:     map[string]int{day1: 3, day2: 1},
+		},
+		{
+			name:         "second restore adds nothing",
+			wantIndexed:  map[string]int{"2024-01-01": 0, "2024-01-02": 0},
+			wantExisting: map[string]int{"2024-01-01": 3, "2024-01-02": 1},
+			wantDocs:     map[string]int{day1: 3, day2: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := RestoreArchive(store, "test", RestoreOptions{Dir: dir, From: "2023-12-31", To: "2024-01-03", DryRun: tt.dryRun}, 2)
+			if err != nil {
+				t.Fatalf("RestoreArchive: %v", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("results = %+v, want 2 days", results)
+			}
+			for _, res := range results {
+				if len(res.Errors) > 0 || res.Failed > 0 {
+					t.Errorf("%s: errors = %v, failed = %d", res.Day, res.Errors, res.Failed)
+				}
+				if res.Indexed != tt.wantIndexed[res.Day] || res.Existing != tt.wantExisting[res.Day] {
+					t.Errorf("%s: indexed/existing = %d/%d, want %d/%d",
+						res.Day, res.Indexed, res.Existing, tt.wantIndexed[res.Day], tt.wantExisting[res.Day])
+				}
+			}
+			for index, want := range tt.wantDocs {
+				if n, _ := store.Count(index, nil); n != want {
+					t.Errorf("%s docs = %d, want %d", index, n, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRestoreArchiveOptions: 잘못된 기간은 오류
+func TestRestoreArchiveOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+	}{
+		{"bad from", "2024/01/01", "2024-01-02"},
+		{"bad to", "2024-01-01", "tomorrow"},
+		{"to before from", "2024-01-02", "2024-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RestoreArchive(common.NewMemoryStore(), "test", RestoreOptions{Dir: t.TempDir(), From: tt.from, To: tt.to}, 10); err == nil {
+				t.Errorf("RestoreArchive(%s, %s) = nil error, want error", tt.from, tt.to)
+			}
+		})
+	}
+}
+
+// TestArchiveDayFiles: 체크섬 불일치는 건너뛰고, manifest 미등록 세그먼트는 오류로 알리고 복원 대상에 포함
+func TestArchiveDayFiles(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	tests := []struct {
+		name      string
+		damage    func(t *testing.T, dayDir string)
+		wantFiles int
+		wantErr   string
+	}{
+		{"intact", func(t *testing.T, dayDir string) {}, 2, ""},
+		{"checksum mismatch", func(t *testing.T, dayDir string) {
+			path := filepath.Join(dayDir, "events-2024-01-01-0001.ndjson.gz")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)-1] ^= 0xff
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, 1, "체크섬 불일치"},
+		{"unlisted segment", func(t *testing.T, dayDir string) {
+			if err := os.Remove(filepath.Join(dayDir, archiveManifest)); err != nil {
+				t.Fatal(err)
+			}
+		}, 2, "manifest 미등록"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// maxBytes 1 → 이벤트마다 새 세그먼트
+			a := newArchiver(dir, 1)
+			for i := 0; i < 2; i++ {
+				if err := a.Write("2024-01-01", []byte(fmt.Sprintf(`{"eventId":"e%d"}`, i))); err != nil {
+					t.Fatal(err)
+				}
+				if err := a.Flush(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			a.Close()
+			dayDir := filepath.Join(dir, "2024-01-01")
+			tt.damage(t, dayDir)
+
+			files, errs := archiveDayFiles(dayDir)
+			if len(files) != tt.wantFiles {
+				t.Errorf("files = %v, want %d", files, tt.wantFiles)
+			}
+			joined := strings.Join(errs, "\n")
+			if tt.wantErr == "" && len(errs) > 0 || tt.wantErr != "" && !strings.Contains(joined, tt.wantErr) {
+				t.Errorf("errors = %v, want %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
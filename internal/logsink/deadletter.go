@@ -0,0 +1,342 @@
+package logsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// dead-letter 실패 단계
+const (
+	StageDecode  = "decode"  // 입력 파싱 실패
+	StagePublish = "publish" // 변환 토픽 발행 실패 (재시도 불가 오류)
+	StageIndex   = "index"   // OpenSearch 색인 거부/재시도 초과
+)
+
+// Source: 이벤트 출처 (Kafka 메시지 또는 syslog 프레임)
+// syslog 입력은 Topic이 "syslog/udp" 형태이며 Partition/Offset은 -1
+type Source struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Raw       []byte
+}
+
+// DeadLetter: dead-letter 토픽/파일에 기록되는 레코드
+// Original은 원본 바이트 그대로 (JSON 직렬화 시 base64)
+// 색인 단계 레코드는 변환 토픽 발행까지 끝난 이벤트라 색인 대상(Index/DocID/Document)을 함께 기록한다
+type DeadLetter struct {
+	SourceTopic string          `json:"sourceTopic"`
+	Partition   int32           `json:"partition"`
+	Offset      int64           `json:"offset"`
+	Stage       string          `json:"stage"`
+	Error       string          `json:"error"`
+	FailedAt    string          `json:"failedAt"`
+	Original    []byte          `json:"original"`
+	Index       string          `json:"index,omitempty"`
+	DocID       string          `json:"docId,omitempty"`
+	Document    json.RawMessage `json:"document,omitempty"`
+}
+
+// deadLetterQueue: Kafka dead-letter 토픽 발행, 실패 시(또는 토픽 미설정 시) 로컬 파일에 NDJSON 추가
+type deadLetterQueue struct {
+	producer sarama.SyncProducer
+	topic    string
+	file     string
+	fileMu   sync.Mutex
+}
+
+func newDeadLetterQueue(producer sarama.SyncProducer, topic, file string) *deadLetterQueue {
+	if file != "" {
+		os.MkdirAll(filepath.Dir(file), 0o755)
+	}
+	return &deadLetterQueue{producer: producer, topic: topic, file: file}
+}
+
+// Send: 기록 실패는 로그로만 남긴다 (dead-letter 경로가 파이프라인을 멈추지 않도록)
+func (q *deadLetterQueue) Send(src *Source, stage string, cause error) {
+	q.send(src, newDeadLetter(src, stage, cause))
+}
+
+// SendIndex: 색인 거부 항목 — 재처리 시 변환 토픽 재발행 없이 같은 _id로 재색인할 수 있도록 문서 포함
+func (q *deadLetterQueue) SendIndex(src *Source, item *common.BulkItem) {
+	rec := newDeadLetter(src, StageIndex, errors.New(item.LastErr))
+	rec.Index = item.Index
+	rec.DocID = item.ID
+	rec.Document = json.RawMessage(item.Doc)
+	q.send(src, rec)
+}
+
+func newDeadLetter(src *Source, stage string, cause error) DeadLetter {
+	return DeadLetter{
+		SourceTopic: src.Topic,
+		Partition:   src.Partition,
+		Offset:      src.Offset,
+		Stage:       stage,
+		Error:       cause.Error(),
+		FailedAt:    common.Now().Format(time.RFC3339),
+		Original:    src.Raw,
+	}
+}
+
+func (q *deadLetterQueue) send(src *Source, rec DeadLetter) {
+	data, _ := json.Marshal(rec)
+
+	if q.topic != "" {
+		_, _, err := q.producer.SendMessage(&sarama.ProducerMessage{
+			Topic: q.topic,
+			Key:   sarama.StringEncoder(src.Topic),
+			Value: sarama.ByteEncoder(data),
+		})
+		if err == nil {
+			return
+		}
+		log.Printf("[LogSink] dead-letter 토픽 발행 실패: %v", err)
+	}
+	if q.file != "" {
+		err := q.appendFile(data)
+		if err == nil {
+			return
+		}
+		log.Printf("[LogSink] dead-letter 파일 기록 실패: %v", err)
+	}
+	log.Printf("[LogSink] dead-letter 유실 (%s %s/%d@%d): %s", rec.Stage, src.Topic, src.Partition, src.Offset, rec.Error)
+}
+
+func (q *deadLetterQueue) appendFile(data []byte) error {
+	q.fileMu.Lock()
+	defer q.fileMu.Unlock()
+	f, err := os.OpenFile(q.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ReplayOptions: dead-letter 재처리 옵션
+type ReplayOptions struct {
+	File        string // 지정 시 Kafka 대신 로컬 fallback 파일에서 읽음
+	Stage       string // 특정 실패 단계만 (빈값이면 전체)
+	SourceTopic string // 특정 원본 토픽만 (빈값이면 전체)
+	TargetTopic string // 원본 토픽 대신 발행할 토픽 (syslog 출처 레코드는 필수, 색인 단계 레코드도 지정 시에만 재발행)
+	DryRun      bool   // 발행 없이 집계만
+}
+
+// ReplayResult: 재처리 결과 (단계/원본 토픽별 건수)
+type ReplayResult struct {
+	Scanned   int
+	Replayed  int // 재발행
+	Reindexed int // 색인 단계 레코드 재색인 (이미 같은 _id 문서가 있던 건 포함)
+	Failed    int // 재색인 거부
+	Skipped   int
+	ByStage   map[string]int
+	ByTopic   map[string]int
+}
+
+// ReplayDeadLetters: dead-letter 레코드의 원본 바이트를 원본 토픽에 다시 발행
+// 색인 단계 레코드는 변환 토픽 발행(CEP/UEBA 반영)까지 끝난 이벤트이므로 재발행하지 않고
+// 기록된 문서를 같은 인덱스/_id로 create 재색인한다 (--target-topic 지정 시에만 원본 재발행, 문서가 없는 이전 형식 레코드는 제외).
+// Kafka 모드는 "<group>-dlq-replay" 그룹으로 offset을 커밋하므로 같은 레코드를 두 번 재처리하지 않는다.
+// 실행 시점의 high watermark까지만 읽어, 재처리 중 다시 실패한 레코드는 다음 실행 대상이 된다.
+func ReplayDeadLetters(cfg *config.Config, opts ReplayOptions) (*ReplayResult, error) {
+	var store common.Store
+	var producer sarama.SyncProducer
+	if !opts.DryRun {
+		osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+		if err != nil {
+			return nil, fmt.Errorf("OpenSearch 클라이언트 설정 오류: %v", err)
+		}
+		store = osClient
+		prodCfg := sarama.NewConfig()
+		prodCfg.Producer.Return.Successes = true
+		prodCfg.Producer.RequiredAcks = sarama.WaitForAll
+		p, err := sarama.NewSyncProducer([]string{cfg.Kafka.Bootstrap}, prodCfg)
+		if err != nil {
+			return nil, fmt.Errorf("producer 생성 실패: %v", err)
+		}
+		defer p.Close()
+		producer = p
+	}
+	return replayDeadLetters(cfg, opts, store, producer)
+}
+
+// replayDeadLetters: store/producer로 재처리 (DryRun이면 둘 다 nil 가능)
+func replayDeadLetters(cfg *config.Config, opts ReplayOptions, store common.Store, producer sarama.SyncProducer) (*ReplayResult, error) {
+	res := &ReplayResult{ByStage: map[string]int{}, ByTopic: map[string]int{}}
+
+	// handle: 레코드 1건 처리, 발행 실패 시 에러 (Kafka 모드에서는 offset 마킹 중단)
+	handle := func(data []byte) error {
+		var rec DeadLetter
+		if err := json.Unmarshal(data, &rec); err != nil {
+			res.Skipped++
+			return nil
+		}
+		res.Scanned++
+		if (opts.Stage != "" && rec.Stage != opts.Stage) || (opts.SourceTopic != "" && rec.SourceTopic != opts.SourceTopic) {
+			res.Skipped++
+			return nil
+		}
+		reindex := rec.Stage == StageIndex && opts.TargetTopic == ""
+		target := rec.SourceTopic
+		if opts.TargetTopic != "" {
+			target = opts.TargetTopic
+		} else if (reindex && len(rec.Document) == 0) || (!reindex && rec.Partition < 0) {
+			// 문서 없는 색인 단계 레코드 / syslog 등 Kafka 외 출처는 되돌릴 곳이 없음
+			res.Skipped++
+			return nil
+		}
+		res.ByStage[rec.Stage]++
+		res.ByTopic[rec.SourceTopic]++
+		if opts.DryRun {
+			return nil
+		}
+		if reindex {
+			return reindexDeadLetter(store, rec, res)
+		}
+		if _, _, err := producer.SendMessage(&sarama.ProducerMessage{Topic: target, Value: sarama.ByteEncoder(rec.Original)}); err != nil {
+			return err
+		}
+		res.Replayed++
+		return nil
+	}
+
+	if opts.File != "" {
+		return res, replayFromFile(opts.File, handle)
+	}
+	if cfg.LogSink.DeadLetterTopic == "" {
+		return nil, fmt.Errorf("dead-letter 토픽 미설정 (LOGSINK_DLQ_TOPIC) — --file 지정 필요")
+	}
+	return res, replayFromTopic(cfg, opts.DryRun, handle)
+}
+
+// reindexDeadLetter: 색인 단계 레코드 1건을 create로 재색인 (409는 이미 색인된 것으로 간주)
+// 요청 자체 실패는 error (재처리 중단), 항목 거부는 Failed로 집계하고 계속
+func reindexDeadLetter(store common.Store, rec DeadLetter, res *ReplayResult) error {
+	meta := map[string]interface{}{"_index": rec.Index}
+	op := "index"
+	if rec.DocID != "" {
+		meta["_id"] = rec.DocID
+		op = "create"
+	}
+	action, _ := json.Marshal(map[string]interface{}{op: meta})
+	body := append(action, '\n')
+	body = append(body, rec.Document...)
+	body = append(body, '\n')
+	result, err := store.Bulk(body)
+	if err != nil {
+		return err
+	}
+	var item map[string]interface{}
+	if items, _ := result["items"].([]interface{}); len(items) > 0 {
+		entry, _ := items[0].(map[string]interface{})
+		item, _ = entry[op].(map[string]interface{})
+	}
+	status, _ := item["status"].(float64)
+	if (status >= 200 && status < 300) || status == 409 {
+		res.Reindexed++
+		return nil
+	}
+	res.Failed++
+	log.Printf("[LogSink] dead-letter 재색인 거부 (%s/%s): %v", rec.Index, rec.DocID, item["error"])
+	return nil
+}
+
+func replayFromFile(path string, handle func([]byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := handle(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func replayFromTopic(cfg *config.Config, dryRun bool, handle func([]byte) error) error {
+	topic := cfg.LogSink.DeadLetterTopic
+	consCfg := sarama.NewConfig()
+	consCfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	client, err := sarama.NewClient([]string{cfg.Kafka.Bootstrap}, consCfg)
+	if err != nil {
+		return fmt.Errorf("Kafka 연결 실패: %v", err)
+	}
+	defer client.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+	offsets, err := sarama.NewOffsetManagerFromClient(cfg.Kafka.GroupID+"-dlq-replay", client)
+	if err != nil {
+		return err
+	}
+	defer offsets.Close() // 마킹된 offset 커밋
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("%s 파티션 조회 실패: %v", topic, err)
+	}
+	for _, p := range partitions {
+		hwm, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return err
+		}
+		pom, err := offsets.ManagePartition(topic, p)
+		if err != nil {
+			return err
+		}
+		next, _ := pom.NextOffset()
+		if next == sarama.OffsetOldest {
+			if next, err = client.GetOffset(topic, p, sarama.OffsetOldest); err != nil {
+				pom.Close()
+				return err
+			}
+		}
+		if next >= hwm {
+			pom.Close()
+			continue
+		}
+		pc, err := consumer.ConsumePartition(topic, p, next)
+		if err != nil {
+			pom.Close()
+			return err
+		}
+		for msg := range pc.Messages() {
+			if err := handle(msg.Value); err != nil {
+				pc.Close()
+				pom.Close()
+				return err
+			}
+			if !dryRun {
+				pom.MarkOffset(msg.Offset+1, "")
+			}
+			if msg.Offset+1 >= hwm {
+				break
+			}
+		}
+		pc.Close()
+		pom.Close()
+	}
+	return nil
+}
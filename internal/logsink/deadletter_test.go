@@ -0,0 +1,81 @@
+package logsink
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// TestReplayDeadLetters: 색인 단계 레코드는 같은 _id로 재색인, 그 외는 원본 토픽 재발행
+func TestReplayDeadLetters(t *testing.T) {
+	const index = "test-siem-event-logs-2024.01.01"
+	kafka := &Source{Topic: "MESSAGE_DEVICE", Partition: 0, Offset: 7, Raw: []byte(`{"msgId":"USB"}`)}
+	syslog := &Source{Topic: "syslog/udp", Partition: -1, Offset: -1, Raw: []byte("<13>hello")}
+	indexItem := func(id, doc string) *common.BulkItem {
+		return &common.BulkItem{Index: index, ID: id, Create: true, Doc: []byte(doc), LastErr: "status 429"}
+	}
+
+	// 레코드: 색인 2건(새 문서/이미 있는 문서) + 문서 없는 이전 형식 색인 1건 + Kafka decode 1건 + syslog decode 1건
+	writeDLQ := func(t *testing.T) string {
+		file := filepath.Join(t.TempDir(), "dlq.ndjson")
+		q := newDeadLetterQueue(nil, "", file)
+		q.SendIndex(kafka, indexItem("new", `{"seq":"new"}`))
+		q.SendIndex(kafka, indexItem("existing", `{"seq":"replayed"}`))
+		q.Send(kafka, StageIndex, errors.New("legacy"))
+		q.Send(kafka, StageDecode, errors.New("bad json"))
+		q.Send(syslog, StageDecode, errors.New("bad frame"))
+		return file
+	}
+
+	tests := []struct {
+		name          string
+		opts          ReplayOptions
+		wantReindexed int
+		wantReplayed  map[string]int // 토픽별 발행 건수
+		wantSkipped   int
+		wantDocs      int // 재처리 후 인덱스 문서 수 (existing 포함)
+	}{
+		{"default", ReplayOptions{}, 2, map[string]int{"MESSAGE_DEVICE": 1}, 2, 2},
+		{"index stage only", ReplayOptions{Stage: StageIndex}, 2, map[string]int{}, 3, 2},
+		{"target topic republishes everything", ReplayOptions{TargetTopic: "retry"}, 0, map[string]int{"retry": 5}, 0, 1},
+		{"dry run", ReplayOptions{DryRun: true}, 0, map[string]int{}, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := common.NewMemoryStore()
+			if err := store.Put(index, "existing", map[string]interface{}{"seq": "original"}); err != nil {
+				t.Fatal(err)
+			}
+			producer := &fakeProducer{sent: map[string]int{}}
+			tt.opts.File = writeDLQ(t)
+
+			res, err := replayDeadLetters(&config.Config{}, tt.opts, store, producer)
+			if err != nil {
+				t.Fatalf("replayDeadLetters: %v", err)
+			}
+			if res.Scanned != 5 || res.Reindexed != tt.wantReindexed || res.Skipped != tt.wantSkipped || res.Failed != 0 {
+				t.Errorf("result = %+v, want reindexed %d skipped %d", res, tt.wantReindexed, tt.wantSkipped)
+			}
+			replayed := 0
+			for topic, n := range tt.wantReplayed {
+				replayed += n
+				if producer.sent[topic] != n {
+					t.Errorf("published to %s = %d, want %d", topic, producer.sent[topic], n)
+				}
+			}
+			if res.Replayed != replayed || len(producer.sent) != len(tt.wantReplayed) {
+				t.Errorf("replayed %d %v, want %v", res.Replayed, producer.sent, tt.wantReplayed)
+			}
+			if n, _ := store.Count(index, nil); n != tt.wantDocs {
+				t.Errorf("docs in %s = %d, want %d", index, n, tt.wantDocs)
+			}
+			// 이미 색인된 _id는 덮어쓰지 않는다
+			if doc, _ := store.Get(index, "existing"); doc["seq"] != "original" {
+				t.Errorf("existing doc overwritten: %v", doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// 입력 포맷 (LOGSINK_TOPIC_FORMATS로 토픽별 지정)
+const (
+	FormatJSON = "json"
+	FormatCEF  = "cef"
+	FormatLEEF = "leef"
+)
+
+// decodeEvent: 입력 포맷에 따라 원본 바이트 → 이벤트 map
+// json: cefExtensions를 포함한 기존 에이전트 JSON / cef, leef: raw CEF 또는 LEEF 문자열 (헤더로 자동 판별)
+func decodeEvent(format string, data []byte, aliases common.LEEFAliases) (map[string]interface{}, error) {
+	switch format {
+	case FormatCEF, FormatLEEF:
+		return common.ParseRawEvent(string(bytes.TrimSpace(data)), aliases)
+	case FormatJSON, "":
+		var event map[string]interface{}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		return event, nil
+	}
+	return nil, fmt.Errorf("지원하지 않는 입력 포맷: %s", format)
+}
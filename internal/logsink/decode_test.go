@@ -0,0 +1,40 @@
+package logsink
+
+import "testing"
+
+// TestDecodeEvent: 토픽 입력 포맷별 디코딩 (json 기본, cef/leef는 헤더로 판별)
+func TestDecodeEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		data      string
+		wantMsgID string
+		wantErr   bool
+	}{
+		{"json default", "", `{"msgId":"USB","cefExtensions":{}}`, "USB", false},
+		{"json", FormatJSON, `{"msgId":"PRINT"}`, "PRINT", false},
+		{"json invalid", FormatJSON, `CEF:0|V|P|1|ID|N|5|k=v`, "", true},
+		{"cef", FormatCEF, "  CEF:0|V|P|1|MESSAGE_DEVICE|N|5|suid=u1\n", "MESSAGE_DEVICE", false},
+		{"cef topic accepts leef", FormatCEF, "LEEF:1.0|V|P|1|LOGIN|usrName=u1", "LOGIN", false},
+		{"leef", FormatLEEF, "LEEF:2.0|V|P|1|LOGIN|^|usrName=u1", "LOGIN", false},
+		{"raw without header", FormatCEF, "hello", "", true},
+		{"unknown format", "xml", `<e/>`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := decodeEvent(tt.format, []byte(tt.data), nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeEvent = %v, want error", event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeEvent: %v", err)
+			}
+			if event["msgId"] != tt.wantMsgID {
+				t.Errorf("msgId = %v, want %s", event["msgId"], tt.wantMsgID)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package logsink
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// 중복 이벤트 처리
+const (
+	DedupDrop = "drop" // 변환 토픽 발행/색인 없이 ack
+	DedupTag  = "tag"  // duplicate=true로 발행/색인 (원본과 다른 _id로 별도 문서)
+)
+
+const dedupStatsInterval = time.Minute
+
+// deduplicator: 내용 지문(fingerprint) 기준 구간 내 중복 억제
+//
+// 지문은 변환/보강 전 원본 이벤트의 설정 필드 값으로 계산하므로 파이프라인·참조 데이터가 바뀌어도 같다.
+// 지문은 OpenSearch 문서 _id로도 쓰여 장애 후 재전달(구간 기억 소실)도 create 409로 걸러진다.
+// 구간 기억은 색인 완료(ack) 후에만 기록 — 발행/색인 전에 실패해 재전달되는 이벤트가 중복으로 버려지지 않도록.
+type deduplicator struct {
+	fields []string
+	window time.Duration // 0이면 구간 억제 없이 지문(_id)만 사용
+	action string
+
+	mu         sync.Mutex
+	seen       map[string]time.Time
+	order      []dedupEntry // 추가 순서 (만료 제거용, head 이전은 만료됨)
+	head       int
+	suppressed int64
+}
+
+type dedupEntry struct {
+	fingerprint string
+	at          time.Time
+}
+
+// newDeduplicator: 필드가 없으면 nil (지문/중복 억제 비활성)
+func newDeduplicator(fields []string, window time.Duration, action string) *deduplicator {
+	if len(fields) == 0 {
+		return nil
+	}
+	if action != DedupTag {
+		action = DedupDrop
+	}
+	return &deduplicator{fields: fields, window: window, action: action, seen: make(map[string]time.Time)}
+}
+
+// Fingerprint: 설정 필드 (필드명, 값) 목록의 SHA-256 (객체 값은 키 정렬된 JSON)
+// 설정 필드가 하나도 없는 이벤트는 원본 바이트(없으면 입력 위치)로 계산 — 서로 다른 이벤트가 같은 지문으로 묶이지 않도록
+func (d *deduplicator) Fingerprint(event map[string]interface{}, src *Source) string {
+	h := sha256.New()
+	found := false
+	for _, field := range d.fields {
+		v, ok := getPath(event, field)
+		if !ok {
+			continue
+		}
+		found = true
+		data, _ := json.Marshal(v)
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	if !found && src != nil {
+		h.Write([]byte("raw"))
+		h.Write([]byte{0})
+		if len(src.Raw) > 0 {
+			h.Write(src.Raw)
+		} else {
+			fmt.Fprintf(h, "%s/%d@%d", src.Topic, src.Partition, src.Offset)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// eventID: 지문이 없을 때 이벤트 _id
+// Kafka 입력은 입력 위치(토픽/파티션/offset) 해시라 재전달돼도 같고, 재전달이 없는 syslog/HTTP 입력은 임의 ID
+func eventID(src *Source) string {
+	if src == nil || src.Partition < 0 {
+		return common.NewDocID()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d@%d", src.Topic, src.Partition, src.Offset)))
+	return hex.EncodeToString(sum[:])
+}
+
+// duplicateID: tag 중복 사본의 문서 _id
+// Kafka 입력은 지문+입력 위치로 고정해 같은 메시지 재전달은 create 409로 걸러지고, 그 외 입력은 임의 ID
+func duplicateID(fingerprint string, src *Source) string {
+	if src == nil || src.Partition < 0 {
+		return common.NewDocID()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s/%d@%d", fingerprint, src.Topic, src.Partition, src.Offset)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Seen: 구간 내 같은 지문이 색인된 적 있으면 true (기록은 Record)
+func (d *deduplicator) Seen(fingerprint string) bool {
+	if d.window <= 0 {
+		return false
+	}
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(now)
+	if at, ok := d.seen[fingerprint]; ok && now.Sub(at) < d.window {
+		d.suppressed++
+		return true
+	}
+	return false
+}
+
+// Record: 색인 완료된 지문을 구간 기억에 기록
+func (d *deduplicator) Record(fingerprint string) {
+	if d.window <= 0 {
+		return
+	}
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(now)
+	if _, ok := d.seen[fingerprint]; ok {
+		// 구간 안에서 이미 기록된 지문 (동시에 처리 중이던 사본) — 처음 기록 시각 유지
+		return
+	}
+	d.seen[fingerprint] = now
+	d.order = append(d.order, dedupEntry{fingerprint: fingerprint, at: now})
+}
+
+// expire: d.mu 보유 상태에서 호출
+// 만료 항목은 head만 전진시키고, 절반 이상이 만료됐을 때만 앞부분을 잘라 복사 (호출당 분할 상환 O(1))
+func (d *deduplicator) expire(now time.Time) {
+	for ; d.head < len(d.order) && now.Sub(d.order[d.head].at) >= d.window; d.head++ {
+		e := d.order[d.head]
+		if d.seen[e.fingerprint].Equal(e.at) {
+			delete(d.seen, e.fingerprint)
+		}
+		d.order[d.head] = dedupEntry{}
+	}
+	if d.head > 0 && d.head >= len(d.order)/2 {
+		d.order = append([]dedupEntry(nil), d.order[d.head:]...)
+		d.head = 0
+	}
+}
+
+// Run: 주기적으로 억제 건수 로그 + 만료 정리 (유입이 멈춘 동안에도 메모리 반환)
+func (d *deduplicator) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(dedupStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.expire(time.Now())
+			n, tracked := d.suppressed, len(d.seen)
+			d.suppressed = 0
+			d.mu.Unlock()
+			if n > 0 {
+				log.Printf("[LogSink] 중복 이벤트 %d건 %s (추적 중 지문 %d개)", n, d.action, tracked)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package logsink
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDeduplicatorFingerprint: 설정 필드 값만 반영 (키 순서 무관), 필드가 없으면 원본 바이트/입력 위치
+func TestDeduplicatorFingerprint(t *testing.T) {
+	d := newDeduplicator([]string{"msgId", "cefExtensions.suid", "cefExtensions.meta"}, time.Minute, DedupDrop)
+	base := d.Fingerprint(parseEvent(t, `{"msgId":"USB","cefExtensions":{"suid":"u1","meta":{"a":1,"b":2}}}`), nil)
+	src := &Source{Topic: "T", Partition: 0, Offset: 5}
+	tests := []struct {
+		name     string
+		event    string
+		src      *Source
+		wantSame bool
+	}{
+		{"other fields ignored", `{"msgId":"USB","seq":9,"cefExtensions":{"suid":"u1","fname":"x","meta":{"a":1,"b":2}}}`, nil, true},
+		{"object key order ignored", `{"msgId":"USB","cefExtensions":{"meta":{"b":2,"a":1},"suid":"u1"}}`, nil, true},
+		{"input position ignored when fields exist", `{"msgId":"USB","cefExtensions":{"suid":"u1","meta":{"a":1,"b":2}}}`, src, true},
+		{"different value", `{"msgId":"USB","cefExtensions":{"suid":"u2","meta":{"a":1,"b":2}}}`, nil, false},
+		{"missing field differs", `{"msgId":"USB","cefExtensions":{"suid":"u1"}}`, nil, false},
+		{"value moved to other field", `{"msgId":"u1","cefExtensions":{"suid":"USB","meta":{"a":1,"b":2}}}`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := d.Fingerprint(parseEvent(t, tt.event), tt.src)
+			if (got == base) != tt.wantSame {
+				t.Errorf("Fingerprint(%s) same = %v, want %v", tt.event, got == base, tt.wantSame)
+			}
+		})
+	}
+
+	// 설정 필드가 하나도 없는 이벤트끼리는 원본 바이트(없으면 입력 위치)로 구분
+	none := parseEvent(t, `{"other":1}`)
+	rawA := d.Fingerprint(none, &Source{Raw: []byte(`{"other":1,"x":"a"}`)})
+	rawB := d.Fingerprint(none, &Source{Raw: []byte(`{"other":1,"x":"b"}`)})
+	posA := d.Fingerprint(none, &Source{Topic: "T", Offset: 1})
+	posB := d.Fingerprint(none, &Source{Topic: "T", Offset: 2})
+	if rawA == rawB || posA == posB {
+		t.Errorf("fingerprints without configured fields collide: %s %s %s %s", rawA, rawB, posA, posB)
+	}
+}
+
+// TestEventIDs: Kafka 입력은 입력 위치로 고정, 그 외 입력은 매번 새 ID
+func TestEventIDs(t *testing.T) {
+	kafka := &Source{Topic: "MESSAGE_DEVICE", Partition: 2, Offset: 100}
+	tests := []struct {
+		name     string
+		a, b     *Source
+		wantSame bool
+	}{
+		{"same kafka position", kafka, &Source{Topic: "MESSAGE_DEVICE", Partition: 2, Offset: 100}, true},
+		{"other offset", kafka, &Source{Topic: "MESSAGE_DEVICE", Partition: 2, Offset: 101}, false},
+		{"other partition", kafka, &Source{Topic: "MESSAGE_DEVICE", Partition: 3, Offset: 100}, false},
+		{"syslog/http input", &Source{Topic: "syslog", Partition: -1, Offset: 1}, &Source{Topic: "syslog", Partition: -1, Offset: 1}, false},
+		{"no source", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := eventID(tt.a) == eventID(tt.b); same != tt.wantSame {
+				t.Errorf("eventID same = %v, want %v", same, tt.wantSame)
+			}
+			if same := duplicateID("fp", tt.a) == duplicateID("fp", tt.b); same != tt.wantSame {
+				t.Errorf("duplicateID same = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+	if eventID(kafka) == duplicateID("fp", kafka) || duplicateID("fp", kafka) == duplicateID("fp2", kafka) {
+		t.Errorf("duplicateID must differ from eventID and per fingerprint")
+	}
+}
+
+// TestDeduplicatorSeenRecord: 색인 완료(Record) 후에만 억제, 구간 0이면 억제 없음, 재기록은 처음 시각 유지
+func TestDeduplicatorSeenRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		window     time.Duration
+		record     []string
+		seen       string
+		want       bool
+		suppressed int64
+	}{
+		{"not recorded yet", time.Minute, nil, "a", false, 0},
+		{"recorded", time.Minute, []string{"a"}, "a", true, 1},
+		{"other fingerprint", time.Minute, []string{"a"}, "b", false, 0},
+		{"recorded twice", time.Minute, []string{"a", "a"}, "a", true, 1},
+		{"window disabled", 0, []string{"a"}, "a", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDeduplicator([]string{"msgId"}, tt.window, DedupTag)
+			for _, fp := range tt.record {
+				d.Record(fp)
+			}
+			if got := d.Seen(tt.seen); got != tt.want {
+				t.Errorf("Seen(%s) = %v, want %v", tt.seen, got, tt.want)
+			}
+			if d.suppressed != tt.suppressed {
+				t.Errorf("suppressed = %d, want %d", d.suppressed, tt.suppressed)
+			}
+			if len(d.order) != len(d.seen) {
+				t.Errorf("order = %d entries, seen = %d", len(d.order), len(d.seen))
+			}
+		})
+	}
+	if d := newDeduplicator(nil, time.Minute, DedupDrop); d != nil {
+		t.Errorf("newDeduplicator without fields = %v, want nil", d)
+	}
+	if d := newDeduplicator([]string{"msgId"}, time.Minute, "bogus"); d.action != DedupDrop {
+		t.Errorf("unknown action = %s, want %s", d.action, DedupDrop)
+	}
+}
+
+// TestDeduplicatorExpire: 구간이 지난 지문만 제거, 절반 이상 만료되면 order 앞부분 정리
+func TestDeduplicatorExpire(t *testing.T) {
+	base := time.Now()
+	tests := []struct {
+		name      string
+		ages      []time.Duration // 지문별 기록 시각 (base 기준 과거)
+		wantSeen  int
+		wantOrder int
+	}{
+		{"none expired", []time.Duration{30 * time.Second, 10 * time.Second}, 2, 2},
+		{"boundary expires", []time.Duration{time.Minute, 10 * time.Second}, 1, 1},
+		{"minority expired keeps head", []time.Duration{90 * time.Second, 30 * time.Second, 20 * time.Second, 10 * time.Second}, 3, 4},
+		{"all expired", []time.Duration{3 * time.Minute, 2 * time.Minute}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDeduplicator([]string{"msgId"}, time.Minute, DedupDrop)
+			for i, age := range tt.ages {
+				fp := string(rune('a' + i))
+				at := base.Add(-age)
+				d.seen[fp] = at
+				d.order = append(d.order, dedupEntry{fingerprint: fp, at: at})
+			}
+			d.expire(base)
+			if len(d.seen) != tt.wantSeen || len(d.order) != tt.wantOrder {
+				t.Errorf("seen/order = %d/%d, want %d/%d", len(d.seen), len(d.order), tt.wantSeen, tt.wantOrder)
+			}
+			if live := len(d.order) - d.head; live != tt.wantSeen {
+				t.Errorf("live order entries = %d, want %d", live, tt.wantSeen)
+			}
+		})
+	}
+}
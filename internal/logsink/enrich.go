@@ -0,0 +1,310 @@
+package logsink
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// enrichIPFields: IP 보강 대상 cefExtensions 키 (키 이름이 추가 필드의 접두어가 된다)
+var enrichIPFields = []string{"src", "dst"}
+
+// enricher: 로컬 참조 파일 기반 이벤트 보강
+//
+//	CIDR CSV   (헤더: cidr,site,department,...)  → src/dst + 컬럼명 (예: srcSite, dstDepartment), 최장 prefix 일치
+//	자산 CSV   (헤더: hostname,owner,...)         → asset + 컬럼명 (예: assetOwner), hostname 대소문자 무시
+//	MMDB       (MaxMind GeoIP2/GeoLite2 City/ASN) → srcCountry, srcCity, srcAsn, srcAsOrg
+//
+// 결과는 모두 문자열로 cefExtensions에 추가되므로 CEP(MAP<STRING,STRING>)와 UEBA 규칙에서 그대로 조건으로 쓸 수 있다.
+// 이벤트에 이미 있는 키는 덮어쓰지 않는다. 파일은 주기적으로 변경 여부(mtime/크기)를 확인해 다시 읽는다.
+type enricher struct {
+	cidrFiles []string
+	assetFile string
+	mmdbFile  string
+
+	ref     atomic.Pointer[refData]
+	changed map[string]string // 파일 → 마지막 로드 시점 "mtime/size"
+}
+
+// refData: 한 번에 교체되는 참조 데이터 스냅샷
+type refData struct {
+	subnets  []subnetEntry                // prefix 길이 내림차순
+	assets   map[string]map[string]string // 소문자 hostname → 추가 필드
+	geo      *maxminddb.Reader
+	loadedAt time.Time
+}
+
+type subnetEntry struct {
+	prefix netip.Prefix
+	attrs  map[string]string // 컬럼명 → 값
+}
+
+// geoRecord: City/ASN DB 공용 (없는 항목은 빈값)
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+func newEnricher(cidrFiles []string, assetFile, mmdbFile string) *enricher {
+	e := &enricher{cidrFiles: cidrFiles, assetFile: assetFile, mmdbFile: mmdbFile, changed: map[string]string{}}
+	e.reload()
+	return e
+}
+
+func (e *enricher) files() []string {
+	files := append([]string{}, e.cidrFiles...)
+	if e.assetFile != "" {
+		files = append(files, e.assetFile)
+	}
+	if e.mmdbFile != "" {
+		files = append(files, e.mmdbFile)
+	}
+	return files
+}
+
+// reload: 파일이 하나라도 바뀌었으면 전체를 다시 읽어 교체 (실패 시 이전 스냅샷 유지)
+func (e *enricher) reload() {
+	dirty := false
+	for _, f := range e.files() {
+		stamp := ""
+		if st, err := os.Stat(f); err == nil {
+			stamp = fmt.Sprintf("%d/%d", st.ModTime().UnixNano(), st.Size())
+		}
+		if e.changed[f] != stamp {
+			e.changed[f] = stamp
+			dirty = true
+		}
+	}
+	if !dirty {
+		return
+	}
+
+	ref := &refData{assets: map[string]map[string]string{}, loadedAt: time.Now()}
+	for _, f := range e.cidrFiles {
+		entries, err := loadSubnetCSV(f)
+		if err != nil {
+			log.Printf("[LogSink] CIDR 참조 로드 실패 (%s): %v", f, err)
+			return
+		}
+		ref.subnets = append(ref.subnets, entries...)
+	}
+	sort.SliceStable(ref.subnets, func(i, j int) bool {
+		return ref.subnets[i].prefix.Bits() > ref.subnets[j].prefix.Bits()
+	})
+	if e.assetFile != "" {
+		assets, err := loadAssetCSV(e.assetFile)
+		if err != nil {
+			log.Printf("[LogSink] 자산 참조 로드 실패 (%s): %v", e.assetFile, err)
+			return
+		}
+		ref.assets = assets
+	}
+	if e.mmdbFile != "" {
+		// 메모리로 읽어 두면 교체 시 이전 Reader를 닫지 않아도 된다 (조회 중인 고루틴 보호)
+		data, err := os.ReadFile(e.mmdbFile)
+		if err == nil {
+			ref.geo, err = maxminddb.FromBytes(data)
+		}
+		if err != nil {
+			log.Printf("[LogSink] MMDB 로드 실패 (%s): %v", e.mmdbFile, err)
+			return
+		}
+	}
+	e.ref.Store(ref)
+	log.Printf("[LogSink] 보강 참조 로드: 서브넷 %d개, 자산 %d개, MMDB %v", len(ref.subnets), len(ref.assets), ref.geo != nil)
+}
+
+// Run: interval마다 참조 파일 변경 확인 (0 이하면 재로드 없음)
+func (e *enricher) Run(done <-chan struct{}, interval time.Duration) {
+	if len(e.files()) == 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			e.reload()
+		}
+	}
+}
+
+// Enrich: cefExtensions에 서브넷/지역/자산 정보 추가
+func (e *enricher) Enrich(event map[string]interface{}) {
+	ref := e.ref.Load()
+	if ref == nil {
+		return
+	}
+	ext, _ := event["cefExtensions"].(map[string]interface{})
+	put := func(key, val string) {
+		if val == "" {
+			return
+		}
+		if ext == nil {
+			ext = make(map[string]interface{})
+			event["cefExtensions"] = ext
+		}
+		if _, exists := ext[key]; !exists {
+			ext[key] = val
+		}
+	}
+
+	for _, field := range enrichIPFields {
+		s, _ := ext[field].(string)
+		addr, err := netip.ParseAddr(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		for _, sn := range ref.subnets {
+			if sn.prefix.Contains(addr) {
+				put(field+"Subnet", sn.prefix.String())
+				for col, val := range sn.attrs {
+					put(field+col, val)
+				}
+				break
+			}
+		}
+		if ref.geo != nil && !addr.IsPrivate() && !addr.IsLoopback() {
+			var rec geoRecord
+			if err := ref.geo.Lookup(addr.AsSlice(), &rec); err == nil {
+				put(field+"Country", rec.Country.ISOCode)
+				put(field+"City", rec.City.Names["en"])
+				if rec.ASN > 0 {
+					put(field+"Asn", strconv.FormatUint(uint64(rec.ASN), 10))
+				}
+				put(field+"AsOrg", rec.ASOrg)
+			}
+		}
+	}
+
+	if host, _ := event["hostname"].(string); host != "" {
+		for col, val := range ref.assets[strings.ToLower(host)] {
+			put("asset"+col, val)
+		}
+	}
+}
+
+// Status: 현재 로드된 참조 데이터 요약
+func (e *enricher) Status() map[string]interface{} {
+	ref := e.ref.Load()
+	if ref == nil {
+		return map[string]interface{}{"loaded": false, "files": e.files()}
+	}
+	return map[string]interface{}{
+		"loaded":   true,
+		"files":    e.files(),
+		"subnets":  len(ref.subnets),
+		"assets":   len(ref.assets),
+		"mmdb":     ref.geo != nil,
+		"loadedAt": ref.loadedAt.Format(time.RFC3339),
+	}
+}
+
+// loadSubnetCSV: 첫 컬럼 CIDR(또는 단일 IP), 나머지 컬럼은 추가 필드
+func loadSubnetCSV(path string) ([]subnetEntry, error) {
+	header, rows, err := readRefCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []subnetEntry
+	for i, row := range rows {
+		prefix, err := netip.ParsePrefix(row[0])
+		if err != nil {
+			addr, aerr := netip.ParseAddr(row[0])
+			if aerr != nil {
+				log.Printf("[LogSink] %s:%d 잘못된 CIDR '%s' 건너뜀", path, i+2, row[0])
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		entries = append(entries, subnetEntry{prefix: prefix.Masked(), attrs: rowAttrs(header, row)})
+	}
+	return entries, nil
+}
+
+// loadAssetCSV: 첫 컬럼 hostname, 나머지 컬럼은 추가 필드
+func loadAssetCSV(path string) (map[string]map[string]string, error) {
+	header, rows, err := readRefCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	assets := make(map[string]map[string]string, len(rows))
+	for _, row := range rows {
+		assets[strings.ToLower(row[0])] = rowAttrs(header, row)
+	}
+	return assets, nil
+}
+
+// readRefCSV: 헤더 포함 CSV 읽기 ('#' 주석 행, 빈 키 행 무시)
+func readRefCSV(path string) ([]string, [][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("헤더 읽기 실패: %v", err)
+	}
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		row[0] = strings.TrimSpace(row[0])
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// rowAttrs: 헤더 컬럼명을 필드 접미어로 변환 (site → Site, owner_name → OwnerName)
+func rowAttrs(header, row []string) map[string]string {
+	attrs := make(map[string]string)
+	for i := 1; i < len(header) && i < len(row); i++ {
+		if v := strings.TrimSpace(row[i]); v != "" {
+			attrs[fieldSuffix(header[i])] = v
+		}
+	}
+	return attrs
+}
+
+func fieldSuffix(col string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(strings.TrimSpace(col), func(r rune) bool {
+		return r == '_' || r == '-' || r == ' '
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package logsink
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeRefFile: 테스트용 참조 CSV 생성
+func writeRefFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestEnricherEnrich: 최장 prefix 서브넷, 자산(hostname 대소문자 무시), 기존 키 유지
+func TestEnricherEnrich(t *testing.T) {
+	dir := t.TempDir()
+	cidr := writeRefFile(t, dir, "cidr.csv", "cidr,site,department_name\n"+
+		"# 주석\n"+
+		"10.0.0.0/8,HQ,\n"+
+		"10.1.0.0/16,HQ,Dev Team\n"+
+		"192.168.1.7,Lab,QA\n"+
+		"not-a-cidr,X,Y\n")
+	asset := writeRefFile(t, dir, "asset.csv", "hostname,owner,os\nPC-1,u1,win11\n")
+	e := newEnricher([]string{cidr}, asset, "")
+
+	tests := []struct {
+		name  string
+		event map[string]interface{}
+		want  map[string]interface{} // 보강 후 cefExtensions
+	}{
+		{
+			name:  "longest prefix wins",
+			event: map[string]interface{}{"cefExtensions": map[string]interface{}{"src": "10.1.2.3"}},
+			want:  map[string]interface{}{"src": "10.1.2.3", "srcSubnet": "10.1.0.0/16", "srcSite": "HQ", "srcDepartmentName": "Dev Team"},
+		},
+		{
+			name:  "empty column not added",
+			event: map[string]interface{}{"cefExtensions": map[string]interface{}{"dst": "10.200.0.1"}},
+			want:  map[string]interface{}{"dst": "10.200.0.1", "dstSubnet": "10.0.0.0/8", "dstSite": "HQ"},
+		},
+		{
+			name:  "single ip and ipv4-mapped ipv6",
+			event: map[string]interface{}{"cefExtensions": map[string]interface{}{"src": "::ffff:192.168.1.7"}},
+			want:  map[string]interface{}{"src": "::ffff:192.168.1.7", "srcSubnet": "192.168.1.7/32", "srcSite": "Lab", "srcDepartmentName": "QA"},
+		},
+		{
+			name:  "existing keys kept",
+			event: map[string]interface{}{"cefExtensions": map[string]interface{}{"src": "10.1.2.3", "srcSite": "agent"}},
+			want:  map[string]interface{}{"src": "10.1.2.3", "srcSite": "agent", "srcSubnet": "10.1.0.0/16", "srcDepartmentName": "Dev Team"},
+		},
+		{
+			name:  "asset by hostname without cefExtensions",
+			event: map[string]interface{}{"hostname": "pc-1"},
+			want:  map[string]interface{}{"assetOwner": "u1", "assetOs": "win11"},
+		},
+		{
+			name:  "unknown ip and invalid ip",
+			event: map[string]interface{}{"cefExtensions": map[string]interface{}{"src": "8.8.8.8", "dst": "bogus"}},
+			want:  map[string]interface{}{"src": "8.8.8.8", "dst": "bogus"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e.Enrich(tt.event)
+			if got := tt.event["cefExtensions"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cefExtensions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEnricherReload: 파일이 바뀐 경우만 다시 읽고, 읽기 실패 시 이전 스냅샷 유지
+func TestEnricherReload(t *testing.T) {
+	dir := t.TempDir()
+	asset := writeRefFile(t, dir, "asset.csv", "hostname,owner\npc-1,u1\n")
+	e := newEnricher(nil, asset, "")
+	owner := func() interface{} {
+		event := map[string]interface{}{"hostname": "pc-1"}
+		e.Enrich(event)
+		ext, _ := event["cefExtensions"].(map[string]interface{})
+		return ext["assetOwner"]
+	}
+	if got := owner(); got != "u1" {
+		t.Fatalf("owner = %v, want u1", got)
+	}
+
+	writeRefFile(t, dir, "asset.csv", "hostname,owner\npc-1,u2-changed\n")
+	e.reload()
+	if got := owner(); got != "u2-changed" {
+		t.Errorf("owner after change = %v, want u2-changed", got)
+	}
+
+	os.Remove(asset)
+	e.reload()
+	if got := owner(); got != "u2-changed" {
+		t.Errorf("owner after failed reload = %v, want previous snapshot", got)
+	}
+	if status := e.Status(); status["assets"] != 1 || status["loaded"] != true {
+		t.Errorf("status = %v", status)
+	}
+}
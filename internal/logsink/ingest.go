@@ -0,0 +1,196 @@
+package logsink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IngestResult: 이벤트(줄/배열 원소) 1건 처리 결과
+type IngestResult struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"` // accepted / rejected
+	Error  string `json:"error,omitempty"`
+}
+
+// IngestController: Kafka에 직접 접근할 수 없는 원격 에이전트용 HTTP 수집 API
+//
+// 인증은 출처별 API 키 (Authorization: Bearer <key> 또는 X-API-Key).
+// 본문은 NDJSON 또는 JSON 배열, Content-Encoding: gzip 지원.
+// 각 이벤트는 Kafka 입력과 같은 processEvent를 거치며, 변환 토픽 발행까지 끝난 건을 accepted로 응답한다.
+type IngestController struct {
+	sink     *Sink
+	keys     map[string]string // 출처 → API 키
+	maxBytes int64             // 압축 해제 후 본문 최대 크기
+}
+
+func NewIngestController(sink *Sink, keys map[string]string, maxBytes int64) *IngestController {
+	return &IngestController{sink: sink, keys: keys, maxBytes: maxBytes}
+}
+
+// requestKey: 요청의 API 키 (X-API-Key 우선, 없으면 Authorization: Bearer)
+func requestKey(ctx echo.Context) string {
+	key := ctx.Request().Header.Get("X-API-Key")
+	if auth := ctx.Request().Header.Get("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return key
+}
+
+// matchKey: key에 해당하는 keys의 이름 (없으면 빈값)
+func matchKey(key string, keys map[string]string) string {
+	if key == "" {
+		return ""
+	}
+	for name, want := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1 {
+			return name
+		}
+	}
+	return ""
+}
+
+// authenticate: API 키에 해당하는 출처 이름 (없으면 빈값)
+func (c *IngestController) authenticate(ctx echo.Context) string {
+	return matchKey(requestKey(ctx), c.keys)
+}
+
+// Ingest - POST /api/ingest[?topic=MESSAGE_DEVICE]
+// topic 지정 시 해당 원본 토픽의 변환 파이프라인을 적용 (미지정 시 "http/<출처>")
+func (c *IngestController) Ingest(ctx echo.Context) error {
+	source := c.authenticate(ctx)
+	if source == "" {
+		return ctx.JSON(401, map[string]string{"error": "유효하지 않은 API 키"})
+	}
+	topic := ctx.QueryParam("topic")
+	if topic == "" {
+		topic = "http/" + source
+	}
+
+	var body io.Reader = ctx.Request().Body
+	if strings.EqualFold(ctx.Request().Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return ctx.JSON(400, map[string]string{"error": "gzip 본문 해제 실패"})
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := io.ReadAll(io.LimitReader(body, c.maxBytes+1))
+	if err != nil {
+		return ctx.JSON(400, map[string]string{"error": "본문 읽기 실패: " + err.Error()})
+	}
+	if int64(len(data)) > c.maxBytes {
+		return ctx.JSON(413, map[string]string{"error": fmt.Sprintf("본문 크기 초과 (최대 %d bytes)", c.maxBytes)})
+	}
+
+	lines, err := splitIngestBody(data)
+	if err != nil {
+		return ctx.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	results := make([]IngestResult, 0, len(lines))
+	accepted := 0
+	for i, line := range lines {
+		res := IngestResult{Line: line.Line, Status: "accepted"}
+		src := &Source{Topic: topic, Partition: -1, Offset: int64(line.Line), Raw: line.Raw}
+		event, err := validateIngestEvent(line.Raw)
+		if err != nil {
+			c.sink.dlq.Send(src, StageDecode, err)
+			res.Status, res.Error = "rejected", err.Error()
+			results = append(results, res)
+			continue
+		}
+		if err := c.sink.processEvent(ctx.Request().Context(), src, event, func() {}); err != nil {
+			res.Status = "rejected"
+			if errors.Is(err, errDeadLettered) {
+				res.Error = "발행 불가 (dead-letter 기록)"
+			} else {
+				// 요청 취소/종료 — 이후 이벤트는 처리하지 않음
+				res.Error = err.Error()
+				results = append(results, res)
+				for j := i + 1; j < len(lines); j++ {
+					results = append(results, IngestResult{Line: lines[j].Line, Status: "rejected", Error: "요청 중단"})
+				}
+				break
+			}
+			results = append(results, res)
+			continue
+		}
+		accepted++
+		results = append(results, res)
+	}
+	if rejected := len(lines) - accepted; rejected > 0 {
+		log.Printf("[LogSink] HTTP 수집 %s: %d건 수락, %d건 거부", source, accepted, rejected)
+	}
+	return ctx.JSON(200, map[string]interface{}{
+		"source":   source,
+		"accepted": accepted,
+		"rejected": len(lines) - accepted,
+		"results":  results,
+	})
+}
+
+// ingestLine: 본문에서 분리한 이벤트 1건과 원래 위치 (NDJSON 줄 번호 또는 배열 원소 번호, 1부터)
+type ingestLine struct {
+	Line int
+	Raw  []byte
+}
+
+// splitIngestBody: JSON 배열이면 원소별, 아니면 NDJSON 줄별 (빈 줄 제외, 줄 번호는 원본 기준)
+func splitIngestBody(data []byte) ([]ingestLine, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("빈 본문")
+	}
+	if trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("JSON 배열 파싱 실패: %v", err)
+		}
+		lines := make([]ingestLine, len(items))
+		for i, item := range items {
+			lines[i] = ingestLine{Line: i + 1, Raw: item}
+		}
+		return lines, nil
+	}
+	var lines []ingestLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lines = append(lines, ingestLine{Line: n, Raw: append([]byte(nil), line...)})
+	}
+	return lines, scanner.Err()
+}
+
+// validateIngestEvent: 에이전트 JSON 이벤트 형식 검증 (msgId 필수, cefExtensions는 객체)
+func validateIngestEvent(raw []byte) (map[string]interface{}, error) {
+	var event map[string]interface{}
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, fmt.Errorf("JSON 객체 아님: %v", err)
+	}
+	if msgID, _ := event["msgId"].(string); msgID == "" {
+		return nil, fmt.Errorf("msgId 필수")
+	}
+	switch ext := event["cefExtensions"].(type) {
+	case nil:
+		event["cefExtensions"] = map[string]interface{}{}
+	case map[string]interface{}:
+	default:
+		return nil, fmt.Errorf("cefExtensions는 객체여야 함 (%T)", ext)
+	}
+	return event, nil
+}
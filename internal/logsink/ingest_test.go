@@ -0,0 +1,217 @@
+package logsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// TestSplitIngestBody: NDJSON 줄 번호는 빈 줄을 건너뛰어도 원본 기준, 배열은 원소 번호
+func TestSplitIngestBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantLines []int
+		wantRaw   []string
+		wantErr   bool
+	}{
+		{"ndjson", `{"a":1}` + "\n" + `{"a":2}`, []int{1, 2}, []string{`{"a":1}`, `{"a":2}`}, false},
+		{"ndjson blank lines keep numbering", "\n" + `{"a":1}` + "\n\n  \r\n" + ` {"a":2} ` + "\n", []int{2, 5}, []string{`{"a":1}`, `{"a":2}`}, false},
+		{"ndjson crlf", `{"a":1}` + "\r\n" + `{"a":2}` + "\r\n", []int{1, 2}, []string{`{"a":1}`, `{"a":2}`}, false},
+		{"array", ` [{"a":1}, {"a":2}] `, []int{1, 2}, []string{`{"a":1}`, `{"a":2}`}, false},
+		{"empty array", `[]`, []int{}, []string{}, false},
+		{"broken array", `[{"a":1}`, nil, nil, true},
+		{"empty body", " \n\t", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := splitIngestBody([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitIngestBody err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			gotLines, gotRaw := []int{}, []string{}
+			for _, l := range lines {
+				gotLines = append(gotLines, l.Line)
+				gotRaw = append(gotRaw, string(l.Raw))
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) || !reflect.DeepEqual(gotRaw, tt.wantRaw) {
+				t.Errorf("lines = %v %q, want %v %q", gotLines, gotRaw, tt.wantLines, tt.wantRaw)
+			}
+		})
+	}
+}
+
+// TestValidateIngestEvent: msgId 필수, cefExtensions는 객체 (없으면 빈 객체)
+func TestValidateIngestEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"valid", `{"msgId":"USB","cefExtensions":{"suid":"u1"}}`, false},
+		{"missing cefExtensions", `{"msgId":"USB"}`, false},
+		{"missing msgId", `{"cefExtensions":{}}`, true},
+		{"non string msgId", `{"msgId":1}`, true},
+		{"cefExtensions not object", `{"msgId":"USB","cefExtensions":"suid=u1"}`, true},
+		{"not an object", `["USB"]`, true},
+		{"not json", `msgId=USB`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := validateIngestEvent([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateIngestEvent(%s) err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if err == nil {
+				if _, ok := event["cefExtensions"].(map[string]interface{}); !ok {
+					t.Errorf("cefExtensions = %#v, want object", event["cefExtensions"])
+				}
+			}
+		})
+	}
+}
+
+// TestAdminAuth: 관리 키 우선, 없으면 수집 키, 둘 다 없으면 인증 없음
+func TestAdminAuth(t *testing.T) {
+	ingestKeys := map[string]string{"branch-a": "ingest-key"}
+	tests := []struct {
+		name     string
+		adminKey string
+		ingest   map[string]string
+		header   map[string]string
+		want     int
+	}{
+		{"admin key via X-API-Key", "admin-key", ingestKeys, map[string]string{"X-API-Key": "admin-key"}, 200},
+		{"admin key via bearer", "admin-key", ingestKeys, map[string]string{"Authorization": "Bearer admin-key"}, 200},
+		{"ingest key rejected when admin key set", "admin-key", ingestKeys, map[string]string{"X-API-Key": "ingest-key"}, 401},
+		{"missing key", "admin-key", ingestKeys, nil, 401},
+		{"ingest key without admin key", "", ingestKeys, map[string]string{"Authorization": "Bearer ingest-key"}, 200},
+		{"wrong key without admin key", "", ingestKeys, map[string]string{"X-API-Key": "nope"}, 401},
+		{"no keys configured", "", nil, nil, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/pipeline", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler := adminAuth(tt.adminKey, tt.ingest)(func(c echo.Context) error {
+				return c.JSON(200, map[string]string{"ok": "true"})
+			})
+			if err := handler(e.NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestIngest: 인증, 크기 제한, gzip 본문과 이벤트별 결과 (줄 번호는 원본 기준)
+func TestIngest(t *testing.T) {
+	cfg := config.LoadFromEnv("logsink")
+	cfg.IndexPrefix = "test"
+	cfg.Timezone = "Asia/Seoul"
+	cfg.LogSink.DeadLetterFile = filepath.Join(t.TempDir(), "dlq.ndjson")
+	cfg.LogSink.ArchiveDir = ""
+	cfg.LogSink.PipelineFile = ""
+	common.InitTimezone(cfg.Timezone)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	producer := &fakeProducer{sent: map[string]int{}}
+	sink, wait, err := newSink(ctx, cfg, common.NewMemoryStore(), producer)
+	if err != nil {
+		t.Fatalf("newSink: %v", err)
+	}
+	defer func() {
+		cancel()
+		wait()
+	}()
+	ctrl := NewIngestController(sink, map[string]string{"branch-a": "key-a"}, 256)
+
+	gzipBody := func(s string) string {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return buf.String()
+	}
+	tests := []struct {
+		name        string
+		key         string
+		gzip        bool
+		body        string
+		wantCode    int
+		wantResults []IngestResult
+	}{
+		{name: "bad key", key: "nope", body: `{"msgId":"USB"}`, wantCode: 401},
+		{
+			name: "ndjson with blank and invalid lines", key: "key-a",
+			body:     `{"msgId":"USB"}` + "\n\n" + `{"cefExtensions":{}}` + "\n" + `{"msgId":"PRINT"}`,
+			wantCode: 200,
+			wantResults: []IngestResult{
+				{Line: 1, Status: "accepted"},
+				{Line: 3, Status: "rejected", Error: "msgId 필수"},
+				{Line: 4, Status: "accepted"},
+			},
+		},
+		{
+			name: "gzip array", key: "key-a", gzip: true,
+			body:        `[{"msgId":"USB"},{"msgId":"USB","cefExtensions":[]}]`,
+			wantCode:    200,
+			wantResults: []IngestResult{{Line: 1, Status: "accepted"}, {Line: 2, Status: "rejected", Error: "cefExtensions는 객체여야 함 ([]interface {})"}},
+		},
+		{name: "too large", key: "key-a", body: `{"msgId":"` + strings.Repeat("x", 300) + `"}`, wantCode: 413},
+		{name: "empty", key: "key-a", body: "\n", wantCode: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if tt.gzip {
+				body = gzipBody(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/ingest", strings.NewReader(body))
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			req.Header.Set("X-API-Key", tt.key)
+			rec := httptest.NewRecorder()
+			if err := ctrl.Ingest(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantResults == nil {
+				return
+			}
+			var resp struct {
+				Source  string         `json:"source"`
+				Results []IngestResult `json:"results"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Source != "branch-a" || !reflect.DeepEqual(resp.Results, tt.wantResults) {
+				t.Errorf("response = %+v, want results %+v", resp, tt.wantResults)
+			}
+		})
+	}
+}
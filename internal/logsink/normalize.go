@@ -0,0 +1,421 @@
+package logsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// 정규화 출력 방식
+const (
+	NormalizeEmbed = "embed" // 변환 이벤트에 "normalized" 하위 객체로 포함
+	NormalizeTopic = "topic" // 정규화 문서만 별도 토픽으로 발행 (변환 토픽은 기존 형식 그대로)
+)
+
+// NormalizeMapping: 매핑 테이블 1개
+//
+//	static  대상 경로 → 고정값 (class_uid, event.category 등)
+//	fields  대상 경로 → 원본 경로. 쉼표로 후보 나열 시 처음 존재하는 값 사용,
+//	        "|epoch_ms" "|int" "|lower" 접미사로 값 변환 (예: "@timestamp|epoch_ms")
+//	lookups 대상 경로 → 원본 값 치환표 (예: outcome "blocked" → disposition_id 2)
+//
+// 대상 경로는 점 표기이며 결과는 중첩 객체로 만들어진다.
+type NormalizeMapping struct {
+	Static  map[string]interface{}     `json:"static,omitempty"`
+	Fields  map[string]string          `json:"fields,omitempty"`
+	Lookups map[string]NormalizeLookup `json:"lookups,omitempty"`
+}
+
+// NormalizeLookup: 원본 값(문자열 비교, 대소문자 무시) → 대상 값
+// 원본 값이 있으나 치환표에 없으면 Default (nil이면 생략)
+type NormalizeLookup struct {
+	From    string                 `json:"from"`
+	Values  map[string]interface{} `json:"values"`
+	Default interface{}            `json:"default,omitempty"`
+}
+
+// NormalizeProfile: 스키마별 매핑 테이블
+//
+// 적용 순서: common → topics[원본 토픽] (없으면 msgId가 토픽 이름으로 시작하는 항목) → msgIds[msgId]
+// 뒤 테이블이 같은 대상 경로를 덮어쓴다.
+type NormalizeProfile struct {
+	Schema  string                      `json:"schema"`
+	Version string                      `json:"version,omitempty"`
+	Common  NormalizeMapping            `json:"common"`
+	Topics  map[string]NormalizeMapping `json:"topics"`
+	MsgIDs  map[string]NormalizeMapping `json:"msgIds,omitempty"`
+}
+
+// normalizer: 변환 이벤트 → OCSF/ECS 문서
+type normalizer struct {
+	profile *NormalizeProfile
+	output  string
+	topic   string
+}
+
+// newNormalizer: schema가 빈값이면 nil (비활성). file 지정 시 내장 테이블 대신 사용
+func newNormalizer(schema, file, output, topic string) (*normalizer, error) {
+	if schema == "" {
+		return nil, nil
+	}
+	if output != NormalizeEmbed && output != NormalizeTopic {
+		return nil, fmt.Errorf("잘못된 정규화 출력 '%s' (embed/topic)", output)
+	}
+	if output == NormalizeTopic && topic == "" {
+		return nil, fmt.Errorf("정규화 토픽 미설정")
+	}
+	var profile *NormalizeProfile
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		profile = &NormalizeProfile{}
+		if err := json.Unmarshal(data, profile); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if profile.Schema != schema {
+			return nil, fmt.Errorf("%s: schema '%s'가 설정값 '%s'와 다름", file, profile.Schema, schema)
+		}
+	} else {
+		switch schema {
+		case "ocsf":
+			profile = ocsfProfile()
+		case "ecs":
+			profile = ecsProfile()
+		default:
+			return nil, fmt.Errorf("잘못된 정규화 스키마 '%s' (ocsf/ecs)", schema)
+		}
+	}
+	return &normalizer{profile: profile, output: output, topic: topic}, nil
+}
+
+// Normalize: 이벤트(읽기 전용)로 정규화 문서 생성
+func (n *normalizer) Normalize(topic string, event map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, m := range n.mappings(topic, event) {
+		m.apply(event, out)
+	}
+	return out
+}
+
+func (n *normalizer) mappings(topic string, event map[string]interface{}) []NormalizeMapping {
+	p := n.profile
+	list := []NormalizeMapping{p.Common}
+	msgID, _ := event["msgId"].(string)
+	if m, ok := p.Topics[topic]; ok {
+		list = append(list, m)
+	} else if key := topicPrefix(p.Topics, msgID); key != "" {
+		// syslog/CEF 입력 등 토픽 이름이 없는 경우 msgId 접두사로 분류
+		list = append(list, p.Topics[key])
+	}
+	if m, ok := p.MsgIDs[msgID]; ok {
+		list = append(list, m)
+	}
+	return list
+}
+
+// topicPrefix: msgId가 시작하는 가장 긴 토픽 이름 (MESSAGE_DEVICE_USAGE → MESSAGE_DEVICE)
+func topicPrefix(topics map[string]NormalizeMapping, msgID string) string {
+	best := ""
+	for key := range topics {
+		if strings.HasPrefix(msgID, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	return best
+}
+
+func (m NormalizeMapping) apply(event, out map[string]interface{}) {
+	for _, target := range sortedMapKeys(m.Static) {
+		setPath(out, target, m.Static[target])
+	}
+	for target, spec := range m.Fields {
+		if v, ok := resolveNormalizeField(event, spec); ok {
+			setPath(out, target, v)
+		}
+	}
+	for target, lk := range m.Lookups {
+		if v, ok := lk.lookup(event); ok {
+			setPath(out, target, v)
+		}
+	}
+}
+
+// lookup: 원본 값이 없으면 적용하지 않음 (static 값 유지), 치환표에 없으면 Default
+// From은 fields와 같은 후보 표기 지원
+func (lk NormalizeLookup) lookup(event map[string]interface{}) (interface{}, bool) {
+	v, ok := resolveNormalizeField(event, lk.From)
+	if !ok {
+		return nil, false
+	}
+	key := fmt.Sprint(v)
+	for k, mapped := range lk.Values {
+		if strings.EqualFold(k, key) {
+			return mapped, true
+		}
+	}
+	return lk.Default, lk.Default != nil
+}
+
+// resolveNormalizeField: "a.b,c.d|int" → 첫 번째로 존재하는 값 + 변환
+func resolveNormalizeField(event map[string]interface{}, spec string) (interface{}, bool) {
+	paths, modifier, _ := strings.Cut(spec, "|")
+	for _, path := range strings.Split(paths, ",") {
+		v, ok := getPath(event, strings.TrimSpace(path))
+		if !ok || v == nil || v == "" {
+			continue
+		}
+		switch modifier {
+		case "epoch_ms":
+			t, ok := parseEventTime(v, common.Now())
+			if !ok {
+				continue
+			}
+			return t.UnixMilli(), true
+		case "int":
+			// typer가 숫자로 변환한 CEF 값은 int64/float64, JSON에서 읽은 값은 float64/json.Number
+			switch n := v.(type) {
+			case int64:
+				return n, true
+			case int:
+				return int64(n), true
+			case float64:
+				return int64(n), true
+			case json.Number:
+				if i, err := n.Int64(); err == nil {
+					return i, true
+				}
+				if f, err := n.Float64(); err == nil {
+					return int64(f), true
+				}
+			case string:
+				i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+				if err != nil {
+					continue
+				}
+				return i, true
+			}
+			continue
+		case "lower":
+			return strings.ToLower(fmt.Sprint(v)), true
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ocsfProfile: OCSF 1.1 내장 매핑
+//
+// type_uid = class_uid * 100 + activity_id. 대응 클래스가 없는 인쇄/클립보드/캡처/화면보호는 Base Event(0)로 두고
+// metadata.event_code(msgId)로 구분한다.
+func ocsfProfile() *NormalizeProfile {
+	class := func(categoryUID, classUID int, className string, activity map[string]interface{}) NormalizeMapping {
+		m := NormalizeMapping{
+			Static: map[string]interface{}{
+				"category_uid": categoryUID,
+				"class_uid":    classUID,
+				"class_name":   className,
+				"activity_id":  0,
+				"type_uid":     classUID * 100,
+			},
+		}
+		if activity != nil {
+			m.Lookups = map[string]NormalizeLookup{
+				"activity_id": {From: "cefExtensions.act,cefExtensions.action", Values: activity, Default: 99},
+			}
+			// activity_id를 알 수 있는 클래스는 type_uid를 activity별로 고정
+			typeUIDs := make(map[string]interface{}, len(activity))
+			for k, v := range activity {
+				typeUIDs[k] = classUID*100 + v.(int)
+			}
+			m.Lookups["type_uid"] = NormalizeLookup{From: "cefExtensions.act,cefExtensions.action", Values: typeUIDs, Default: classUID*100 + 99}
+		}
+		return m
+	}
+	fileActivity := map[string]interface{}{
+		"create": 1, "read": 2, "write": 3, "update": 3, "copy": 3, "rename": 5, "delete": 4,
+		"encrypt": 11, "decrypt": 12,
+	}
+	return &NormalizeProfile{
+		Schema:  "ocsf",
+		Version: "1.1.0",
+		Common: NormalizeMapping{
+			Static: map[string]interface{}{
+				"metadata.version":             "1.1.0",
+				"metadata.product.vendor_name": "MarkAny",
+			},
+			Fields: map[string]string{
+				"time":                  "@timestamp|epoch_ms",
+				"metadata.logged_time":  "ingestTime|epoch_ms",
+				"metadata.product.name": "appName",
+				"metadata.event_code":   "msgId",
+				"message":               "message,name",
+				"actor.user.uid":        "cefExtensions.suid",
+				"actor.user.name":       "cefExtensions.suser",
+				"device.hostname":       "hostname",
+				"device.ip":             "cefExtensions.src",
+				"src_endpoint.ip":       "cefExtensions.src",
+				"dst_endpoint.ip":       "cefExtensions.dst",
+			},
+			Lookups: map[string]NormalizeLookup{
+				// CEF severity 0~10 → OCSF severity_id (1 Informational ~ 5 Critical)
+				"severity_id": {From: "severity", Values: map[string]interface{}{
+					"0": 1, "1": 1, "2": 1, "3": 2, "4": 3, "5": 3, "6": 3, "7": 4, "8": 4, "9": 5, "10": 5,
+					"low": 2, "medium": 3, "high": 4, "very-high": 5, "critical": 5,
+				}, Default: 0},
+				"status_id": {From: "cefExtensions.outcome", Values: map[string]interface{}{
+					"success": 1, "allowed": 1, "fail": 2, "failure": 2, "blocked": 2,
+				}},
+				"disposition_id": {From: "cefExtensions.outcome", Values: map[string]interface{}{
+					"allowed": 1, "success": 1, "blocked": 2,
+				}},
+			},
+		},
+		Topics: map[string]NormalizeMapping{
+			"MESSAGE_AGENT":         class(6, 6002, "Application Lifecycle", map[string]interface{}{"install": 1, "uninstall": 2, "start": 3, "stop": 4, "update": 6}),
+			"MESSAGE_DEVICE":        withFileFields(class(1, 1001, "File System Activity", fileActivity)),
+			"MESSAGE_DRM":           withFileFields(class(1, 1001, "File System Activity", fileActivity)),
+			"MESSAGE_NETWORK":       withNetworkFields(class(4, 4001, "Network Activity", map[string]interface{}{"open": 1, "close": 2, "reset": 3, "fail": 4, "refuse": 5, "traffic": 6})),
+			"MESSAGE_PROCESS":       withProcessFields(class(1, 1007, "Process Activity", map[string]interface{}{"launch": 1, "start": 1, "terminate": 2, "kill": 2, "block": 99})),
+			"MESSAGE_PRINT":         withFileFields(class(0, 0, "Base Event", nil)),
+			"MESSAGE_CLIPBOARD":     class(0, 0, "Base Event", nil),
+			"MESSAGE_CAPTURE":       class(0, 0, "Base Event", nil),
+			"MESSAGE_SCREENBLOCKER": class(0, 0, "Base Event", nil),
+			"MESSAGE_PC":            class(5, 5002, "Device Config State", nil),
+			"MESSAGE_ASSETS":        class(5, 5001, "Device Inventory Info", map[string]interface{}{"log": 1, "collect": 2}),
+		},
+		MsgIDs: map[string]NormalizeMapping{
+			"MESSAGE_AGENT_AUTHENTICATION": {
+				Static: map[string]interface{}{
+					"category_uid": 3, "class_uid": 3002, "class_name": "Authentication",
+					"activity_id": 1, "type_uid": 300201,
+				},
+				Fields: map[string]string{"user.uid": "cefExtensions.suid", "user.name": "cefExtensions.suser"},
+			},
+		},
+	}
+}
+
+func withFileFields(m NormalizeMapping) NormalizeMapping {
+	if m.Fields == nil {
+		m.Fields = map[string]string{}
+	}
+	m.Fields["file.name"] = "cefExtensions.fname,cefExtensions.fileName"
+	m.Fields["file.path"] = "cefExtensions.filePath"
+	m.Fields["file.size"] = "cefExtensions.fsize,cefExtensions.fileSize|int"
+	return m
+}
+
+func withNetworkFields(m NormalizeMapping) NormalizeMapping {
+	if m.Fields == nil {
+		m.Fields = map[string]string{}
+	}
+	m.Fields["src_endpoint.port"] = "cefExtensions.spt|int"
+	m.Fields["dst_endpoint.port"] = "cefExtensions.dpt|int"
+	m.Fields["dst_endpoint.hostname"] = "cefExtensions.dhost"
+	m.Fields["connection_info.protocol_name"] = "cefExtensions.proto|lower"
+	m.Fields["traffic.bytes_in"] = "cefExtensions.in|int"
+	m.Fields["traffic.bytes_out"] = "cefExtensions.out|int"
+	return m
+}
+
+func withProcessFields(m NormalizeMapping) NormalizeMapping {
+	if m.Fields == nil {
+		m.Fields = map[string]string{}
+	}
+	m.Fields["process.name"] = "cefExtensions.sproc,cefExtensions.processName"
+	m.Fields["process.file.path"] = "cefExtensions.filePath"
+	m.Fields["process.pid"] = "cefExtensions.spid|int"
+	return m
+}
+
+// ecsProfile: Elastic Common Schema 8.x 내장 매핑
+func ecsProfile() *NormalizeProfile {
+	category := func(kind string, categories ...string) NormalizeMapping {
+		return NormalizeMapping{Static: map[string]interface{}{"event.kind": kind, "event.category": categories}}
+	}
+	file := func(m NormalizeMapping) NormalizeMapping {
+		m.Fields = map[string]string{
+			"file.name": "cefExtensions.fname,cefExtensions.fileName",
+			"file.path": "cefExtensions.filePath",
+			"file.size": "cefExtensions.fsize,cefExtensions.fileSize|int",
+		}
+		return m
+	}
+	network := category("event", "network")
+	network.Fields = map[string]string{
+		"source.port":        "cefExtensions.spt|int",
+		"destination.port":   "cefExtensions.dpt|int",
+		"destination.domain": "cefExtensions.dhost",
+		"network.transport":  "cefExtensions.proto|lower",
+		"source.bytes":       "cefExtensions.out|int",
+		"destination.bytes":  "cefExtensions.in|int",
+	}
+	process := category("event", "process")
+	process.Fields = map[string]string{
+		"process.name":       "cefExtensions.sproc,cefExtensions.processName",
+		"process.executable": "cefExtensions.filePath",
+		"process.pid":        "cefExtensions.spid|int",
+	}
+	return &NormalizeProfile{
+		Schema:  "ecs",
+		Version: "8.11",
+		Common: NormalizeMapping{
+			Static: map[string]interface{}{
+				"ecs.version":     "8.11.0",
+				"observer.vendor": "MarkAny",
+			},
+			Fields: map[string]string{
+				"@timestamp":       "@timestamp",
+				"event.created":    "ingestTime",
+				"event.code":       "msgId",
+				"event.action":     "cefExtensions.act,cefExtensions.action|lower",
+				"event.severity":   "severity|int",
+				"message":          "message,name",
+				"observer.product": "appName",
+				"user.id":          "cefExtensions.suid",
+				"user.name":        "cefExtensions.suser",
+				"host.hostname":    "hostname",
+				"host.ip":          "cefExtensions.src",
+				"source.ip":        "cefExtensions.src",
+				"destination.ip":   "cefExtensions.dst",
+			},
+			Lookups: map[string]NormalizeLookup{
+				"event.outcome": {From: "cefExtensions.outcome", Values: map[string]interface{}{
+					"success": "success", "allowed": "success", "fail": "failure", "failure": "failure", "blocked": "failure",
+				}, Default: "unknown"},
+			},
+		},
+		Topics: map[string]NormalizeMapping{
+			"MESSAGE_AGENT":         category("event", "configuration", "package"),
+			"MESSAGE_DEVICE":        file(category("event", "file", "host")),
+			"MESSAGE_DRM":           file(category("event", "file")),
+			"MESSAGE_NETWORK":       network,
+			"MESSAGE_PROCESS":       process,
+			"MESSAGE_PRINT":         file(category("event", "file")),
+			"MESSAGE_CLIPBOARD":     category("event", "host"),
+			"MESSAGE_CAPTURE":       category("event", "host"),
+			"MESSAGE_SCREENBLOCKER": category("event", "host"),
+			"MESSAGE_PC":            category("state", "host", "configuration"),
+			"MESSAGE_ASSETS":        category("state", "host"),
+		},
+		MsgIDs: map[string]NormalizeMapping{
+			"MESSAGE_AGENT_AUTHENTICATION": category("event", "authentication"),
+		},
+	}
+}
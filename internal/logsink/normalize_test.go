@@ -0,0 +1,175 @@
+package logsink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// TestResolveNormalizeField: 후보 경로 순서, 빈값 건너뛰기, 변환 접미사
+func TestResolveNormalizeField(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	event := map[string]interface{}{
+		"@timestamp": "2024-01-02T09:00:00+09:00",
+		"name":       "",
+		"message":    "usb blocked",
+		"cefExtensions": map[string]interface{}{
+			"fsize": int64(1024), "spt": 443.0, "dpt": json.Number("8080"), "spid": " 77 ",
+			"in": "n/a", "proto": "TCP", "cnt": 3,
+		},
+	}
+	tests := []struct {
+		spec   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"message", "usb blocked", true},
+		{"name,message", "usb blocked", true}, // 빈 문자열은 없는 값
+		{"missing, message", "usb blocked", true},
+		{"missing", nil, false},
+		{"@timestamp|epoch_ms", int64(1704153600000), true},
+		{"message|epoch_ms", nil, false},
+		{"cefExtensions.fsize|int", int64(1024), true},
+		{"cefExtensions.spt|int", int64(443), true},
+		{"cefExtensions.dpt|int", int64(8080), true},
+		{"cefExtensions.spid|int", int64(77), true},
+		{"cefExtensions.cnt|int", int64(3), true},
+		{"cefExtensions.in|int", nil, false},
+		{"cefExtensions.in,cefExtensions.fsize|int", int64(1024), true},
+		{"cefExtensions.proto|lower", "tcp", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, ok := resolveNormalizeField(event, tt.spec)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("resolveNormalizeField(%s) = %#v, %v; want %#v, %v", tt.spec, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestNormalizerNormalize: 내장 OCSF/ECS 프로파일의 토픽/msgId 분류와 치환표
+func TestNormalizerNormalize(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	device := `{"msgId":"MESSAGE_DEVICE_USAGE","severity":"7","hostname":"pc-1",
+		"cefExtensions":{"act":"copy","outcome":"blocked","suid":"u1","fname":"a.txt","fsize":2048}}`
+	tests := []struct {
+		name   string
+		schema string
+		topic  string
+		event  string
+		want   map[string]interface{} // 대상 경로 → 기대값 (JSON 왕복 후)
+	}{
+		{
+			name: "ocsf file activity by topic", schema: "ocsf", topic: "MESSAGE_DEVICE", event: device,
+			want: map[string]interface{}{
+				"class_uid": 1001.0, "activity_id": 3.0, "type_uid": 100103.0, "severity_id": 4.0,
+				"status_id": 2.0, "disposition_id": 2.0, "actor.user.uid": "u1", "device.hostname": "pc-1",
+				"file.name": "a.txt", "file.size": 2048.0, "metadata.event_code": "MESSAGE_DEVICE_USAGE",
+			},
+		},
+		{
+			name: "ocsf msgId prefix without topic", schema: "ocsf", topic: "syslog", event: device,
+			want: map[string]interface{}{"class_uid": 1001.0, "type_uid": 100103.0},
+		},
+		{
+			name: "ocsf unknown activity uses default", schema: "ocsf", topic: "MESSAGE_NETWORK",
+			event: `{"msgId":"MESSAGE_NETWORK","cefExtensions":{"act":"weird","dpt":"443","proto":"UDP"}}`,
+			want: map[string]interface{}{
+				"class_uid": 4001.0, "activity_id": 99.0, "type_uid": 400199.0,
+				"dst_endpoint.port": 443.0, "connection_info.protocol_name": "udp",
+			},
+		},
+		{
+			name: "ocsf no action keeps static activity", schema: "ocsf", topic: "MESSAGE_PROCESS",
+			event: `{"msgId":"MESSAGE_PROCESS"}`,
+			want:  map[string]interface{}{"class_uid": 1007.0, "activity_id": 0.0, "type_uid": 100700.0, "status_id": nil},
+		},
+		{
+			name: "ocsf msgId overrides topic", schema: "ocsf", topic: "MESSAGE_AGENT",
+			event: `{"msgId":"MESSAGE_AGENT_AUTHENTICATION","cefExtensions":{"suid":"u1"}}`,
+			want:  map[string]interface{}{"class_uid": 3002.0, "type_uid": 300201.0, "user.uid": "u1"},
+		},
+		{
+			name: "ecs file event", schema: "ecs", topic: "MESSAGE_DEVICE", event: device,
+			want: map[string]interface{}{
+				"event.kind": "event", "event.category": []interface{}{"file", "host"}, "event.action": "copy",
+				"event.outcome": "failure", "event.severity": 7.0, "user.id": "u1", "file.size": 2048.0,
+			},
+		},
+		{
+			name: "ecs unknown outcome", schema: "ecs", topic: "MESSAGE_CLIPBOARD",
+			event: `{"msgId":"MESSAGE_CLIPBOARD","cefExtensions":{"outcome":"maybe"}}`,
+			want:  map[string]interface{}{"event.category": []interface{}{"host"}, "event.outcome": "unknown", "file.name": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := newNormalizer(tt.schema, "", NormalizeEmbed, "")
+			if err != nil {
+				t.Fatalf("newNormalizer: %v", err)
+			}
+			event := parseEvent(t, tt.event)
+			before := copyEvent(event)
+			doc := copyEvent(n.Normalize(tt.topic, event))
+			for path, want := range tt.want {
+				got, _ := getPath(doc, path)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", path, got, want)
+				}
+			}
+			if !reflect.DeepEqual(copyEvent(event), before) {
+				t.Errorf("Normalize modified the event: %v", event)
+			}
+		})
+	}
+}
+
+// TestNewNormalizer: 비활성, 출력 방식/스키마 검증, 매핑 파일 사용
+func TestNewNormalizer(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "custom.json")
+	if err := os.WriteFile(custom, []byte(`{"schema":"ocsf","common":{"static":{"class_uid":42}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name                        string
+		schema, file, output, topic string
+		wantNil, wantErr            bool
+	}{
+		{name: "disabled", output: "bogus", wantNil: true},
+		{name: "embed", schema: "ocsf", output: NormalizeEmbed},
+		{name: "topic", schema: "ecs", output: NormalizeTopic, topic: "normalized"},
+		{name: "topic without name", schema: "ecs", output: NormalizeTopic, wantErr: true},
+		{name: "bad output", schema: "ocsf", output: "inline", wantErr: true},
+		{name: "unknown schema", schema: "cim", output: NormalizeEmbed, wantErr: true},
+		{name: "custom file", schema: "ocsf", file: custom, output: NormalizeEmbed},
+		{name: "file schema mismatch", schema: "ecs", file: custom, output: NormalizeEmbed, wantErr: true},
+		{name: "broken file", schema: "ocsf", file: broken, output: NormalizeEmbed, wantErr: true},
+		{name: "missing file", schema: "ocsf", file: filepath.Join(dir, "none.json"), output: NormalizeEmbed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := newNormalizer(tt.schema, tt.file, tt.output, tt.topic)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newNormalizer err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (n == nil) != tt.wantNil {
+				t.Errorf("normalizer = %v, wantNil %v", n, tt.wantNil)
+			}
+			if tt.file == custom && n != nil {
+				if doc := n.Normalize("T", map[string]interface{}{}); doc["class_uid"] != 42.0 {
+					t.Errorf("custom class_uid = %#v, want 42", doc["class_uid"])
+				}
+			}
+		})
+	}
+}
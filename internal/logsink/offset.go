@@ -0,0 +1,37 @@
+package logsink
+
+import "sync"
+
+// offsetTracker: 파티션 내 메시지의 비동기 ack를 모아 연속 구간만 offset 마킹
+// 앞선 메시지가 아직 색인 중이면 뒤 메시지가 먼저 성공해도 마킹하지 않는다
+type offsetTracker struct {
+	mu      sync.Mutex
+	pending []int64
+	done    map[int64]bool
+	mark    func(offset int64)
+}
+
+func newOffsetTracker(mark func(offset int64)) *offsetTracker {
+	return &offsetTracker{done: make(map[int64]bool), mark: mark}
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	t.pending = append(t.pending, offset)
+	t.mu.Unlock()
+}
+
+func (t *offsetTracker) ack(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done[offset] = true
+	last := int64(-1)
+	for len(t.pending) > 0 && t.done[t.pending[0]] {
+		last = t.pending[0]
+		delete(t.done, last)
+		t.pending = t.pending[1:]
+	}
+	if last >= 0 {
+		t.mark(last + 1)
+	}
+}
@@ -0,0 +1,38 @@
+package logsink
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestOffsetTrackerContiguousMarks: 앞선 offset이 ack되기 전에는 뒤 offset이 성공해도 마킹하지 않음
+func TestOffsetTrackerContiguousMarks(t *testing.T) {
+	tests := []struct {
+		name    string
+		offsets []int64
+		acks    []int64
+		want    []int64 // mark 호출 순서 (다음에 읽을 offset)
+	}{
+		{"in order", []int64{10, 11, 12}, []int64{10, 11, 12}, []int64{11, 12, 13}},
+		{"out of order waits for head", []int64{10, 11, 12}, []int64{12, 11, 10}, []int64{13}},
+		{"head acked last of two", []int64{10, 11, 12}, []int64{11, 10}, []int64{12}},
+		{"gap in offsets", []int64{5, 9, 20}, []int64{9, 5, 20}, []int64{10, 21}},
+		{"unacked head blocks all", []int64{1, 2, 3}, []int64{2, 3}, nil},
+		{"offset zero", []int64{0, 1}, []int64{0}, []int64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var marks []int64
+			tracker := newOffsetTracker(func(offset int64) { marks = append(marks, offset) })
+			for _, o := range tt.offsets {
+				tracker.add(o)
+			}
+			for _, o := range tt.acks {
+				tracker.ack(o)
+			}
+			if !reflect.DeepEqual(marks, tt.want) {
+				t.Errorf("marks = %v, want %v", marks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package logsink
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	partitionStatsInterval = 5 * time.Minute
+	partitionTopKeys       = 10
+)
+
+// partitionKeyer: 이벤트 → 변환 토픽 메시지 키 (설정 필드 중 처음으로 값이 있는 것)
+// 같은 키는 해시 파티셔너로 항상 같은 파티션에 들어가 사용자별 순서가 유지된다.
+type partitionKeyer struct {
+	fields []string
+}
+
+func newPartitionKeyer(fields []string) *partitionKeyer {
+	return &partitionKeyer{fields: fields}
+}
+
+// Key: 값이 없으면 빈 문자열 (키 없이 발행 → 임의 파티션)
+func (k *partitionKeyer) Key(event map[string]interface{}) string {
+	for _, field := range k.fields {
+		v, ok := getPath(event, field)
+		if !ok || v == nil {
+			continue
+		}
+		if s := strings.TrimSpace(fmt.Sprint(v)); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// encoder: 빈 키는 nil (sarama 해시 파티셔너가 임의 파티션 선택)
+func (k *partitionKeyer) encoder(key string) sarama.Encoder {
+	if key == "" {
+		return nil
+	}
+	return sarama.StringEncoder(key)
+}
+
+// partitionStats: 변환 토픽 파티션별 발행 건수 + 구간(5분)별 고유 키 수/상위 키
+type partitionStats struct {
+	mu       sync.Mutex
+	total    map[int32]int64
+	keyless  int64
+	window   map[int32]map[string]int64 // 구간 내 파티션 → 키 → 건수
+	since    time.Time
+	previous map[string]interface{} // 직전 구간 요약 (API 조회용)
+}
+
+func newPartitionStats() *partitionStats {
+	return &partitionStats{
+		total:  make(map[int32]int64),
+		window: make(map[int32]map[string]int64),
+		since:  time.Now(),
+	}
+}
+
+func (p *partitionStats) Record(partition int32, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total[partition]++
+	if key == "" {
+		p.keyless++
+		return
+	}
+	keys := p.window[partition]
+	if keys == nil {
+		keys = make(map[string]int64)
+		p.window[partition] = keys
+	}
+	keys[key]++
+}
+
+// Run: 구간마다 분포 로그 + 요약 보관 후 구간 초기화
+func (p *partitionStats) Run(done <-chan struct{}) {
+	ticker := time.NewTicker(partitionStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			summary := p.summarize()
+			p.previous = summary
+			p.window = make(map[int32]map[string]int64)
+			p.since = time.Now()
+			p.mu.Unlock()
+			if parts, _ := summary["partitions"].([]map[string]interface{}); len(parts) > 0 {
+				log.Printf("[LogSink] 파티션 키 분포: %d개 파티션, 최대/평균 %.2f, 키 없음 %d건",
+					len(parts), summary["skew"], summary["keyless"])
+			}
+		}
+	}
+}
+
+// Snapshot: 현재 구간 + 직전 구간 요약
+func (p *partitionStats) Snapshot() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]interface{}{
+		"current":  p.summarize(),
+		"previous": p.previous,
+	}
+}
+
+// summarize: p.mu 보유 상태에서 호출
+// skew = 구간 내 최다 파티션 건수 / 파티션 평균 건수 (1에 가까울수록 균등)
+func (p *partitionStats) summarize() map[string]interface{} {
+	type keyCount struct {
+		Key       string `json:"key"`
+		Partition int32  `json:"partition"`
+		Count     int64  `json:"count"`
+	}
+	var partitions []map[string]interface{}
+	var top []keyCount
+	var sum, max int64
+	ids := make([]int32, 0, len(p.total))
+	for id := range p.total {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		var count int64
+		for key, n := range p.window[id] {
+			count += n
+			top = append(top, keyCount{Key: key, Partition: id, Count: n})
+		}
+		sum += count
+		if count > max {
+			max = count
+		}
+		partitions = append(partitions, map[string]interface{}{
+			"partition":    id,
+			"total":        p.total[id],
+			"windowEvents": count,
+			"windowKeys":   len(p.window[id]),
+		})
+	}
+	sort.Slice(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if len(top) > partitionTopKeys {
+		top = top[:partitionTopKeys]
+	}
+	skew := 0.0
+	if sum > 0 && len(ids) > 0 {
+		skew = float64(max) / (float64(sum) / float64(len(ids)))
+	}
+	return map[string]interface{}{
+		"since":      p.since.Format(time.RFC3339),
+		"partitions": partitions,
+		"topKeys":    top,
+		"keyless":    p.keyless,
+		"skew":       skew,
+	}
+}
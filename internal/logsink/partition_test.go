@@ -0,0 +1,121 @@
+package logsink
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+// TestPartitionKeyerKey: 설정 필드 중 처음으로 값이 있는 것 (공백/빈값/nil은 건너뜀)
+func TestPartitionKeyerKey(t *testing.T) {
+	keyer := newPartitionKeyer([]string{"cefExtensions.suid", "userId", "hostname"})
+	tests := []struct {
+		name  string
+		event string
+		want  string
+	}{
+		{"first field", `{"cefExtensions":{"suid":"u1"},"userId":"x","hostname":"pc-1"}`, "u1"},
+		{"empty falls through", `{"cefExtensions":{"suid":"  "},"userId":"u2"}`, "u2"},
+		{"null falls through", `{"cefExtensions":{"suid":null},"hostname":" pc-1 "}`, "pc-1"},
+		{"number value", `{"userId":1001}`, "1001"},
+		{"no field", `{"msgId":"USB"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyer.Key(parseEvent(t, tt.event)); got != tt.want {
+				t.Errorf("Key(%s) = %q, want %q", tt.event, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPartitionKeyerEncoder: 같은 키는 해시 파티셔너로 항상 같은 파티션, 빈 키는 nil
+func TestPartitionKeyerEncoder(t *testing.T) {
+	keyer := newPartitionKeyer(nil)
+	if enc := keyer.encoder(""); enc != nil {
+		t.Errorf("encoder(\"\") = %v, want nil", enc)
+	}
+	partitioner := sarama.NewHashPartitioner("MESSAGE_DEVICE_TRANSFORMED")
+	partitionOf := func(key string) int32 {
+		p, err := partitioner.Partition(&sarama.ProducerMessage{Key: keyer.encoder(key)}, 12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	for _, key := range []string{"u1", "u2", "pc-1", "1001"} {
+		t.Run(key, func(t *testing.T) {
+			first := partitionOf(key)
+			for i := 0; i < 5; i++ {
+				if p := partitionOf(key); p != first {
+					t.Errorf("partition(%s) = %d, want %d", key, p, first)
+				}
+			}
+		})
+	}
+}
+
+// TestPartitionStatsSummarize: 파티션별 건수/고유 키, 상위 키 순서, 키 없음 건수와 skew
+func TestPartitionStatsSummarize(t *testing.T) {
+	type record struct {
+		partition int32
+		key       string
+		n         int
+	}
+	tests := []struct {
+		name        string
+		records     []record
+		wantParts   int
+		wantKeyless int64
+		wantSkew    float64
+		wantTop     string
+	}{
+		{name: "empty", wantSkew: 0},
+		{
+			name:      "balanced",
+			records:   []record{{0, "u1", 2}, {1, "u2", 2}},
+			wantParts: 2, wantSkew: 1,
+		},
+		{
+			name:      "hot key",
+			records:   []record{{0, "u1", 1}, {1, "u2", 1}, {2, "hot", 7}},
+			wantParts: 3, wantSkew: 7.0 / 3.0, wantTop: "hot",
+		},
+		{
+			name:      "keyless counted separately",
+			records:   []record{{0, "", 3}, {0, "u1", 1}},
+			wantParts: 1, wantKeyless: 3, wantSkew: 1, wantTop: "u1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPartitionStats()
+			for _, r := range tt.records {
+				for i := 0; i < r.n; i++ {
+					p.Record(r.partition, r.key)
+				}
+			}
+			current, _ := p.Snapshot()["current"].(map[string]interface{})
+			parts, _ := current["partitions"].([]map[string]interface{})
+			if len(parts) != tt.wantParts {
+				t.Errorf("partitions = %v, want %d", parts, tt.wantParts)
+			}
+			if current["keyless"] != tt.wantKeyless {
+				t.Errorf("keyless = %v, want %d", current["keyless"], tt.wantKeyless)
+			}
+			if skew, _ := current["skew"].(float64); skew < tt.wantSkew-1e-9 || skew > tt.wantSkew+1e-9 {
+				t.Errorf("skew = %v, want %v", skew, tt.wantSkew)
+			}
+			if tt.wantTop != "" {
+				// topKeys는 비공개 구조체 목록 → JSON 왕복으로 비교
+				top, _ := copyEvent(current)["topKeys"].([]interface{})
+				if len(top) == 0 {
+					t.Fatalf("topKeys empty, want %q first", tt.wantTop)
+				}
+				if first, _ := top[0].(map[string]interface{}); first["key"] != tt.wantTop {
+					t.Errorf("topKeys = %v, want %q first", top, tt.wantTop)
+				}
+			}
+		})
+	}
+}
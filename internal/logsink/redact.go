@@ -0,0 +1,182 @@
+package logsink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 비식별 동작
+const (
+	RedactHash = "hash" // HMAC-SHA256(키, 값) 앞 32자 — 같은 값은 항상 같은 해시 (상관분석 유지)
+	RedactMask = "mask" // 앞 keepStart, 뒤 keepEnd 글자만 남기고 '*'
+	RedactDrop = "drop" // 필드 삭제 (regex 규칙은 일치 부분을 "[REDACTED]"로 치환)
+)
+
+// RedactRule: 비식별 규칙 1개
+//
+//	fields 지정 시 해당 필드(점 표기) 값 전체에 적용, regex도 지정하면 값 중 일치 부분에만 적용
+//	fields 없이 regex만 지정하면 이벤트의 모든 문자열 값에서 일치 부분에 적용 (주민번호/전화번호 등)
+type RedactRule struct {
+	Fields    []string `json:"fields,omitempty"`
+	Regex     string   `json:"regex,omitempty"`
+	Action    string   `json:"action"`
+	KeepStart int      `json:"keepStart,omitempty"`
+	KeepEnd   int      `json:"keepEnd,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// RedactConfig: 저장 대상별 정책 (OpenSearch/아카이브 사본과 Kafka 변환 토픽 사본을 따로 적용)
+type RedactConfig struct {
+	OpenSearch []RedactRule `json:"opensearch"`
+	Kafka      []RedactRule `json:"kafka"`
+}
+
+// redactor: 비식별 정책 + HMAC 키
+type redactor struct {
+	cfg RedactConfig
+	key []byte
+}
+
+// newRedactor: 파일이 빈값이면 규칙 없는 redactor (Apply가 원본을 그대로 반환)
+func newRedactor(file, key string) (*redactor, error) {
+	r := &redactor{key: []byte(key)}
+	if file == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	for name, rules := range map[string][]RedactRule{"opensearch": r.cfg.OpenSearch, "kafka": r.cfg.Kafka} {
+		for i := range rules {
+			if err := rules[i].compile(); err != nil {
+				return nil, fmt.Errorf("%s[%d]: %v", name, i, err)
+			}
+			if rules[i].Action == RedactHash && key == "" {
+				return nil, fmt.Errorf("%s[%d]: hash 규칙에는 HMAC 키(LOGSINK_REDACT_KEY) 필요", name, i)
+			}
+		}
+	}
+	return r, nil
+}
+
+func (rule *RedactRule) compile() error {
+	switch rule.Action {
+	case RedactHash, RedactDrop:
+	case RedactMask:
+		if rule.KeepStart == 0 && rule.KeepEnd == 0 {
+			rule.KeepStart, rule.KeepEnd = 1, 1
+		}
+	default:
+		return fmt.Errorf("잘못된 action '%s' (hash/mask/drop)", rule.Action)
+	}
+	if len(rule.Fields) == 0 && rule.Regex == "" {
+		return fmt.Errorf("fields 또는 regex 필수")
+	}
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("regex 오류: %v", err)
+		}
+		rule.re = re
+	}
+	return nil
+}
+
+// Enabled: 규칙이 하나라도 있으면 Kafka/OpenSearch 사본이 서로 다른 객체
+func (r *redactor) Enabled() bool {
+	return len(r.cfg.OpenSearch) > 0 || len(r.cfg.Kafka) > 0
+}
+
+// Apply: 규칙이 없으면 event 그대로, 있으면 깊은 복사본에 적용해 반환 (원본은 변경하지 않음)
+func (r *redactor) Apply(event map[string]interface{}, rules []RedactRule) map[string]interface{} {
+	if len(rules) == 0 {
+		return event
+	}
+	out := deepCopyEvent(event)
+	for i := range rules {
+		r.applyRule(out, &rules[i])
+	}
+	return out
+}
+
+func (r *redactor) applyRule(event map[string]interface{}, rule *RedactRule) {
+	if len(rule.Fields) == 0 {
+		r.redactStrings(event, rule)
+		return
+	}
+	for _, field := range rule.Fields {
+		v, ok := getPath(event, field)
+		if !ok || v == nil {
+			continue
+		}
+		if rule.re == nil && rule.Action == RedactDrop {
+			deletePath(event, field)
+			continue
+		}
+		setPath(event, field, r.redactValue(fmt.Sprint(v), rule))
+	}
+}
+
+// redactStrings: 모든 문자열 값에 regex 규칙 적용 (중첩 객체/배열 포함)
+func (r *redactor) redactStrings(v interface{}, rule *RedactRule) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = r.redactStrings(item, rule)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = r.redactStrings(item, rule)
+		}
+	case string:
+		return r.redactValue(t, rule)
+	}
+	return v
+}
+
+// redactValue: regex가 있으면 일치 부분만, 없으면 값 전체
+func (r *redactor) redactValue(s string, rule *RedactRule) string {
+	if rule.re != nil {
+		return rule.re.ReplaceAllStringFunc(s, func(m string) string { return r.transform(m, rule) })
+	}
+	return r.transform(s, rule)
+}
+
+func (r *redactor) transform(s string, rule *RedactRule) string {
+	switch rule.Action {
+	case RedactHash:
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))[:32]
+	case RedactMask:
+		runes := []rune(s)
+		if len(runes) <= rule.KeepStart+rule.KeepEnd {
+			return strings.Repeat("*", len(runes))
+		}
+		return string(runes[:rule.KeepStart]) + strings.Repeat("*", len(runes)-rule.KeepStart-rule.KeepEnd) +
+			string(runes[len(runes)-rule.KeepEnd:])
+	}
+	return "[REDACTED]"
+}
+
+// deepCopyEvent: JSON 왕복 복사 (숫자는 json.Number로 보존해 큰 정수 정밀도 유지)
+func deepCopyEvent(event map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(event)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out map[string]interface{}
+	dec.Decode(&out)
+	return out
+}
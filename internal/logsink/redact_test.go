@@ -0,0 +1,160 @@
+package logsink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestRedactorApply: 필드/regex 규칙별 hash·mask·drop 결과, 원본 이벤트는 변경하지 않음
+func TestRedactorApply(t *testing.T) {
+	r := &redactor{key: []byte("secret")}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("u1"))
+	hashU1 := hex.EncodeToString(mac.Sum(nil))[:32]
+
+	tests := []struct {
+		name  string
+		rules []RedactRule
+		event string
+		want  string
+	}{
+		{
+			name:  "hash fields keeps correlation",
+			rules: []RedactRule{{Fields: []string{"cefExtensions.suid", "userId"}, Action: RedactHash}},
+			event: `{"userId":"u1","cefExtensions":{"suid":"u1","fname":"a.txt"}}`,
+			want:  `{"userId":"` + hashU1 + `","cefExtensions":{"suid":"` + hashU1 + `","fname":"a.txt"}}`,
+		},
+		{
+			name:  "mask default keeps first and last",
+			rules: []RedactRule{{Fields: []string{"cefExtensions.suser"}, Action: RedactMask}},
+			event: `{"cefExtensions":{"suser":"홍길동님"}}`,
+			want:  `{"cefExtensions":{"suser":"홍**님"}}`,
+		},
+		{
+			name:  "mask short value fully",
+			rules: []RedactRule{{Fields: []string{"ip"}, Action: RedactMask, KeepStart: 3, KeepEnd: 2}},
+			event: `{"ip":"10.0"}`,
+			want:  `{"ip":"****"}`,
+		},
+		{
+			name:  "mask keepEnd only",
+			rules: []RedactRule{{Fields: []string{"phone"}, Action: RedactMask, KeepEnd: 4}},
+			event: `{"phone":"01012345678"}`,
+			want:  `{"phone":"*******5678"}`,
+		},
+		{
+			name:  "drop fields",
+			rules: []RedactRule{{Fields: []string{"cefExtensions.fname", "missing.x"}, Action: RedactDrop}},
+			event: `{"cefExtensions":{"fname":"a.txt","fsize":1}}`,
+			want:  `{"cefExtensions":{"fsize":1}}`,
+		},
+		{
+			name:  "regex within field",
+			rules: []RedactRule{{Fields: []string{"message"}, Regex: `\d{6}-\d{7}`, Action: RedactDrop}},
+			event: `{"message":"rrn 900101-1234567 found","other":"900101-1234567"}`,
+			want:  `{"message":"rrn [REDACTED] found","other":"900101-1234567"}`,
+		},
+		{
+			name:  "regex over all strings including arrays",
+			rules: []RedactRule{{Regex: `010-\d{4}-\d{4}`, Action: RedactMask, KeepStart: 4}},
+			event: `{"a":"call 010-1234-5678","n":{"list":["010-9999-0000",7]}}`,
+			want:  `{"a":"call 010-*********","n":{"list":["010-*********",7]}}`,
+		},
+		{
+			name: "rules applied in order",
+			rules: []RedactRule{
+				{Fields: []string{"userId"}, Action: RedactHash},
+				{Fields: []string{"userId"}, Action: RedactMask, KeepStart: 2, KeepEnd: 0},
+			},
+			event: `{"userId":"u1"}`,
+			want:  `{"userId":"` + hashU1[:2] + "******************************" + `"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.rules {
+				if err := tt.rules[i].compile(); err != nil {
+					t.Fatalf("compile: %v", err)
+				}
+			}
+			event := parseEvent(t, tt.event)
+			got := r.Apply(event, tt.rules)
+			if want := parseEvent(t, tt.want); !reflect.DeepEqual(copyEvent(got), want) {
+				t.Errorf("Apply = %v, want %v", got, want)
+			}
+			if !reflect.DeepEqual(event, parseEvent(t, tt.event)) {
+				t.Errorf("original event modified: %v", event)
+			}
+		})
+	}
+}
+
+// TestRedactorNoRules: 규칙이 없으면 같은 객체를 그대로 반환
+func TestRedactorNoRules(t *testing.T) {
+	r, err := newRedactor("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := map[string]interface{}{"a": "b"}
+	if got := r.Apply(event, nil); reflect.ValueOf(got).Pointer() != reflect.ValueOf(event).Pointer() {
+		t.Errorf("Apply without rules returned a copy")
+	}
+	if r.Enabled() {
+		t.Errorf("Enabled() = true, want false")
+	}
+}
+
+// TestNewRedactor: 정책 파일 검증 (action, fields/regex, regex 오류, hash 규칙의 키)
+func TestNewRedactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		key     string
+		wantErr bool
+	}{
+		{"valid", `{"opensearch":[{"fields":["userId"],"action":"hash"}],"kafka":[{"regex":"\\d+","action":"drop"}]}`, "k", false},
+		{"hash without key", `{"kafka":[{"fields":["userId"],"action":"hash"}]}`, "", true},
+		{"mask without key", `{"kafka":[{"fields":["userId"],"action":"mask"}]}`, "", false},
+		{"unknown action", `{"opensearch":[{"fields":["a"],"action":"encrypt"}]}`, "k", true},
+		{"no fields or regex", `{"opensearch":[{"action":"drop"}]}`, "k", true},
+		{"bad regex", `{"opensearch":[{"regex":"(","action":"drop"}]}`, "k", true},
+		{"broken json", `{"opensearch":`, "k", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "redact.json")
+			if err := os.WriteFile(path, []byte(tt.policy), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			r, err := newRedactor(path, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRedactor err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !r.Enabled() {
+				t.Errorf("Enabled() = false, want true")
+			}
+		})
+	}
+	if _, err := newRedactor(filepath.Join(t.TempDir(), "none.json"), "k"); err == nil {
+		t.Errorf("missing policy file: want error")
+	}
+}
+
+// TestDeepCopyEvent: 큰 정수는 json.Number로 정밀도 유지, 중첩 객체는 별도 사본
+func TestDeepCopyEvent(t *testing.T) {
+	event := map[string]interface{}{"id": int64(9007199254740993), "n": map[string]interface{}{"a": "b"}}
+	out := deepCopyEvent(event)
+	if out["id"] != json.Number("9007199254740993") {
+		t.Errorf("id = %#v, want json.Number 9007199254740993", out["id"])
+	}
+	out["n"].(map[string]interface{})["a"] = "changed"
+	if event["n"].(map[string]interface{})["a"] != "b" {
+		t.Errorf("nested object shared with original")
+	}
+}
@@ -0,0 +1,381 @@
+package logsink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// 재주입 시각 재작성 방식
+const (
+	RewriteNone  = ""
+	RewriteNow   = "now"   // 모든 이벤트를 발행 시각으로
+	RewriteShift = "shift" // 첫 이벤트를 재주입 시작 시각으로 옮기고 이벤트 간 간격은 유지
+)
+
+const replayScrollKeepAlive = "5m"
+
+// EventReplayOptions: 과거 이벤트 재주입 옵션 (파일 또는 OpenSearch 기간 중 하나)
+type EventReplayOptions struct {
+	File        string   // NDJSON 파일 (.gz면 gzip 해제 — 아카이브 세그먼트도 가능)
+	From        string   // OpenSearch 기간 시작 (RFC3339 또는 yyyy-mm-dd, 포함)
+	To          string   // OpenSearch 기간 끝 (RFC3339 또는 yyyy-mm-dd, 날짜만이면 그날 끝까지)
+	MsgIDs      []string // msgId 필터 (빈값이면 전체)
+	Topic       string   // 대상 토픽 (빈값이면 변환 토픽)
+	Rate        float64  // 초당 최대 발행 건수 (0이면 제한 없음)
+	RewriteTime string   // none/now/shift
+	DryRun      bool     // 발행 없이 msgId별 집계만
+	BatchSize   int      // OpenSearch scroll 페이지 크기
+}
+
+// EventReplayResult: 재주입 결과
+type EventReplayResult struct {
+	Read      int
+	Published int
+	Skipped   int // JSON 아님 / msgId 필터 제외
+	ByMsgID   map[string]int
+	First     time.Time // 원본 이벤트 시각 범위
+	Last      time.Time
+}
+
+// ReplayEvents: 저장된 이벤트를 변환 토픽(또는 지정 토픽)에 속도 제한을 두고 다시 발행
+//
+// 새 탐지 규칙을 과거 데이터로 검증할 때 사용한다. 변환 토픽 메시지와 같은 키(LOGSINK_PARTITION_KEY)로
+// 발행해 사용자별 순서를 유지하고, 재주입된 이벤트에는 replayed=true를 붙인다.
+// UEBA는 replayed 이벤트를 집계하지 않고, CEP alert에는 replayed 값이 그대로 실린다.
+func ReplayEvents(ctx context.Context, cfg *config.Config, opts EventReplayOptions) (*EventReplayResult, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var producer sarama.SyncProducer
+	if !opts.DryRun {
+		prodCfg := sarama.NewConfig()
+		prodCfg.Producer.Return.Successes = true
+		prodCfg.Producer.RequiredAcks = sarama.WaitForAll
+		prodCfg.Producer.Partitioner = sarama.NewHashPartitioner
+		p, err := sarama.NewSyncProducer([]string{cfg.Kafka.Bootstrap}, prodCfg)
+		if err != nil {
+			return nil, fmt.Errorf("producer 생성 실패: %v", err)
+		}
+		defer p.Close()
+		producer = p
+	}
+	return replayEvents(ctx, cfg, opts, producer)
+}
+
+func (opts EventReplayOptions) validate() error {
+	switch opts.RewriteTime {
+	case RewriteNone, RewriteNow, RewriteShift:
+	default:
+		return fmt.Errorf("잘못된 시각 재작성 방식 '%s' (now/shift)", opts.RewriteTime)
+	}
+	if (opts.File == "") == (opts.From == "") {
+		return fmt.Errorf("--file 또는 --from/--to 중 하나만 지정")
+	}
+	return nil
+}
+
+// replayEvents: 검증된 옵션으로 producer에 재주입 (DryRun이면 producer는 nil 가능)
+func replayEvents(ctx context.Context, cfg *config.Config, opts EventReplayOptions, producer sarama.SyncProducer) (*EventReplayResult, error) {
+	res := &EventReplayResult{ByMsgID: map[string]int{}}
+	topic := opts.Topic
+	if topic == "" {
+		topic = cfg.LogSink.TransformedTopic
+	}
+	msgIDs := make(map[string]bool, len(opts.MsgIDs))
+	for _, id := range opts.MsgIDs {
+		msgIDs[id] = true
+	}
+
+	keyer := newPartitionKeyer(cfg.LogSink.PartitionKeys)
+	pacer := newReplayPacer(opts.Rate)
+
+	var base, start time.Time // shift 기준: 첫 이벤트 원본 시각 → 재주입 시작 시각
+	handle := func(data []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res.Read++
+		event, err := decodeReplayEvent(data)
+		if err != nil {
+			res.Skipped++
+			return nil
+		}
+		msgID, _ := event["msgId"].(string)
+		if len(msgIDs) > 0 && !msgIDs[msgID] {
+			res.Skipped++
+			return nil
+		}
+		res.ByMsgID[msgID]++
+		t, hasTime := replayEventTime(event)
+		if hasTime {
+			if res.First.IsZero() || t.Before(res.First) {
+				res.First = t
+			}
+			if t.After(res.Last) {
+				res.Last = t
+			}
+		}
+		if opts.DryRun {
+			return nil
+		}
+
+		pacer.Wait(ctx)
+		if opts.RewriteTime != RewriteNone {
+			now := common.Now()
+			newTime := now
+			if opts.RewriteTime == RewriteShift && hasTime {
+				if base.IsZero() {
+					base, start = t, now
+				}
+				newTime = start.Add(t.Sub(base))
+			}
+			rewriteEventTime(event, newTime)
+		}
+		event["replayed"] = true
+
+		out, _ := json.Marshal(event)
+		if _, _, err := producer.SendMessage(&sarama.ProducerMessage{
+			Topic: topic,
+			Key:   keyer.encoder(keyer.Key(event)),
+			Value: sarama.ByteEncoder(out),
+		}); err != nil {
+			return fmt.Errorf("발행 실패 (%d번째 이벤트): %v", res.Read, err)
+		}
+		res.Published++
+		if res.Published%10000 == 0 {
+			log.Printf("  발행 %d건...", res.Published)
+		}
+		return nil
+	}
+
+	if opts.File != "" {
+		return res, replayEventsFromFile(opts.File, handle)
+	}
+	osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+	if err != nil {
+		return nil, err
+	}
+	query, err := replayRangeQuery(opts.From, opts.To, opts.MsgIDs)
+	if err != nil {
+		return nil, err
+	}
+	batch := opts.BatchSize
+	if batch <= 0 {
+		batch = 1000
+	}
+	return res, replayEventsFromOpenSearch(osClient, common.LogsIndexPattern(cfg.IndexPrefix), query, batch, handle)
+}
+
+// decodeReplayEvent: 숫자는 json.Number로 보존 (epoch/큰 정수 정밀도)
+func decodeReplayEvent(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var event map[string]interface{}
+	if err := dec.Decode(&event); err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, fmt.Errorf("빈 이벤트")
+	}
+	return event, nil
+}
+
+// replayEventTime: @timestamp, 없으면 cefExtensions.rt
+func replayEventTime(event map[string]interface{}) (time.Time, bool) {
+	now := common.Now()
+	for _, field := range []string{"@timestamp", "cefExtensions.rt"} {
+		v, ok := getPath(event, field)
+		if !ok {
+			continue
+		}
+		if n, isNum := v.(json.Number); isNum {
+			v = n.String()
+		}
+		if t, ok := parseEventTime(v, now); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rewriteEventTime: @timestamp와 cefExtensions.rt를 새 시각으로 (원래 @timestamp는 originalTime에 보관)
+func rewriteEventTime(event map[string]interface{}, t time.Time) {
+	if old, ok := event["@timestamp"].(string); ok && old != "" {
+		event["originalTime"] = old
+	}
+	event["@timestamp"] = t.Format(time.RFC3339Nano)
+	ext, _ := event["cefExtensions"].(map[string]interface{})
+	if ext == nil {
+		return
+	}
+	ms := t.UnixMilli()
+	switch ext["rt"].(type) {
+	case nil:
+	case string:
+		ext["rt"] = strconv.FormatInt(ms, 10)
+	default:
+		ext["rt"] = ms
+	}
+}
+
+// replayPacer: 초당 rate건을 넘지 않도록 발행 간격 조절 (누적 기준이라 짧은 지연은 이후에 만회)
+type replayPacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newReplayPacer(rate float64) *replayPacer {
+	if rate <= 0 {
+		return &replayPacer{}
+	}
+	return &replayPacer{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+func (p *replayPacer) Wait(ctx context.Context) {
+	if p.interval == 0 {
+		return
+	}
+	now := time.Now()
+	if p.next.IsZero() || p.next.Before(now.Add(-time.Second)) {
+		// 첫 호출 또는 1초 이상 밀린 경우 기준 재설정 (발행 지연 후 폭주 방지)
+		p.next = now
+	}
+	if wait := p.next.Sub(now); wait > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+	}
+	p.next = p.next.Add(p.interval)
+}
+
+func replayEventsFromFile(path string, handle func([]byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: gzip 해제 실패: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := handle(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// replayRangeQuery: @timestamp 기간 + msgId 필터
+func replayRangeQuery(from, to string, msgIDs []string) (map[string]interface{}, error) {
+	gte, err := replayBound(from, false)
+	if err != nil {
+		return nil, err
+	}
+	lte := ""
+	if to != "" {
+		if lte, err = replayBound(to, true); err != nil {
+			return nil, err
+		}
+	}
+	rangeQuery := map[string]interface{}{"gte": gte}
+	if lte != "" {
+		rangeQuery["lte"] = lte
+	}
+	filters := []interface{}{
+		map[string]interface{}{"range": map[string]interface{}{"@timestamp": rangeQuery}},
+	}
+	if len(msgIDs) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"msgId": msgIDs}})
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"filter": filters}}, nil
+}
+
+// replayBound: yyyy-mm-dd(설정 타임존, 끝 경계면 그날 마지막 시각) 또는 RFC3339
+func replayBound(s string, end bool) (string, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, common.Now().Location()); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1).Add(-time.Millisecond)
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+		return "", fmt.Errorf("잘못된 시각 '%s' (yyyy-mm-dd 또는 RFC3339)", s)
+	}
+	return s, nil
+}
+
+// replayEventsFromOpenSearch: scroll로 @timestamp 오름차순 조회
+func replayEventsFromOpenSearch(osClient *common.OSClient, index string, query map[string]interface{}, batch int, handle func([]byte) error) error {
+	status, result, err := osClient.Request("POST", "/"+index+"/_search?scroll="+replayScrollKeepAlive, map[string]interface{}{
+		"size":  batch,
+		"query": query,
+		"sort":  []interface{}{map[string]interface{}{"@timestamp": "asc"}, "_doc"},
+	})
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return fmt.Errorf("OpenSearch 조회 실패: %d %v", status, result["error"])
+	}
+	scrollID, _ := result["_scroll_id"].(string)
+	defer func() {
+		if scrollID != "" {
+			osClient.Request("DELETE", "/_search/scroll", map[string]interface{}{"scroll_id": scrollID})
+		}
+	}()
+
+	for {
+		hitsObj, _ := result["hits"].(map[string]interface{})
+		hits, _ := hitsObj["hits"].([]interface{})
+		if len(hits) == 0 {
+			return nil
+		}
+		for _, h := range hits {
+			hit, _ := h.(map[string]interface{})
+			data, _ := json.Marshal(hit["_source"])
+			if err := handle(data); err != nil {
+				return err
+			}
+		}
+		status, result, err = osClient.Request("POST", "/_search/scroll", map[string]interface{}{
+			"scroll":    replayScrollKeepAlive,
+			"scroll_id": scrollID,
+		})
+		if err != nil {
+			return err
+		}
+		if status >= 400 {
+			return fmt.Errorf("OpenSearch scroll 실패: %d %v", status, result["error"])
+		}
+		if id, _ := result["_scroll_id"].(string); id != "" {
+			scrollID = id
+		}
+	}
+}
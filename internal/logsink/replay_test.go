@@ -0,0 +1,272 @@
+package logsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+const replayTestEvents = `{"msgId":"USB","@timestamp":"2024-01-01T00:00:00Z","cefExtensions":{"suid":"u1","rt":"1704067200000"}}
+{"msgId":"PRINT","@timestamp":"2024-01-01T00:00:10Z","cefExtensions":{"suid":"u2","rt":1704067210000}}
+not json
+
+{"msgId":"USB","@timestamp":"2024-01-01T00:01:00Z","cefExtensions":{"suid":"u1"}}
+`
+
+// decodeReplayed: 발행 메시지 → 키, 이벤트
+func decodeReplayed(t *testing.T, msg *sarama.ProducerMessage) (string, map[string]interface{}) {
+	t.Helper()
+	key := ""
+	if msg.Key != nil {
+		b, _ := msg.Key.Encode()
+		key = string(b)
+	}
+	b, _ := msg.Value.Encode()
+	event, err := decodeReplayEvent(b)
+	if err != nil {
+		t.Fatalf("발행 메시지 파싱 실패: %v", err)
+	}
+	return key, event
+}
+
+// TestReplayEvents: 파일 재주입의 집계/필터/대상 토픽/키/replayed 표시와 시각 재작성
+func TestReplayEvents(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "events.ndjson")
+	if err := os.WriteFile(plain, []byte(replayTestEvents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(replayTestEvents))
+	w.Close()
+	gzipped := filepath.Join(dir, "events.ndjson.gz")
+	if err := os.WriteFile(gzipped, gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.LogSink.TransformedTopic = "MESSAGE_TRANSFORMED"
+	cfg.LogSink.PartitionKeys = []string{"cefExtensions.suid"}
+
+	eventTime := func(t *testing.T, event map[string]interface{}) time.Time {
+		ts, err := time.Parse(time.RFC3339Nano, event["@timestamp"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	tests := []struct {
+		name          string
+		opts          EventReplayOptions
+		wantPublished int
+		wantSkipped   int
+		wantByMsgID   map[string]int
+		check         func(t *testing.T, msgs []*sarama.ProducerMessage)
+	}{
+		{
+			name:          "dry run summary",
+			opts:          EventReplayOptions{File: plain, DryRun: true},
+			wantPublished: 0, wantSkipped: 1,
+			wantByMsgID: map[string]int{"USB": 2, "PRINT": 1},
+		},
+		{
+			name:          "publish to transformed topic",
+			opts:          EventReplayOptions{File: gzipped},
+			wantPublished: 3, wantSkipped: 1,
+			wantByMsgID: map[string]int{"USB": 2, "PRINT": 1},
+			check: func(t *testing.T, msgs []*sarama.ProducerMessage) {
+				wantKeys := []string{"u1", "u2", "u1"}
+				for i, msg := range msgs {
+					key, event := decodeReplayed(t, msg)
+					if msg.Topic != "MESSAGE_TRANSFORMED" || key != wantKeys[i] || event["replayed"] != true {
+						t.Errorf("message %d: topic=%s key=%s replayed=%v", i, msg.Topic, key, event["replayed"])
+					}
+					if _, ok := event["originalTime"]; ok {
+						t.Errorf("message %d: originalTime set without rewrite", i)
+					}
+				}
+			},
+		},
+		{
+			name:          "msgId filter and topic override",
+			opts:          EventReplayOptions{File: plain, MsgIDs: []string{"PRINT"}, Topic: "replay-test"},
+			wantPublished: 1, wantSkipped: 3,
+			wantByMsgID: map[string]int{"PRINT": 1},
+			check: func(t *testing.T, msgs []*sarama.ProducerMessage) {
+				_, event := decodeReplayed(t, msgs[0])
+				ext := event["cefExtensions"].(map[string]interface{})
+				if msgs[0].Topic != "replay-test" || ext["rt"] != json.Number("1704067210000") {
+					t.Errorf("topic=%s rt=%#v, want replay-test and original number", msgs[0].Topic, ext["rt"])
+				}
+			},
+		},
+		{
+			name:          "rewrite now",
+			opts:          EventReplayOptions{File: plain, RewriteTime: RewriteNow},
+			wantPublished: 3, wantSkipped: 1,
+			wantByMsgID: map[string]int{"USB": 2, "PRINT": 1},
+			check: func(t *testing.T, msgs []*sarama.ProducerMessage) {
+				_, first := decodeReplayed(t, msgs[0])
+				_, second := decodeReplayed(t, msgs[1])
+				if since := time.Since(eventTime(t, first)); since < 0 || since > time.Minute {
+					t.Errorf("@timestamp = %v, want now", first["@timestamp"])
+				}
+				if first["originalTime"] != "2024-01-01T00:00:00Z" {
+					t.Errorf("originalTime = %v", first["originalTime"])
+				}
+				// rt는 원래 타입 유지 (문자열 → 문자열, 숫자 → 숫자)
+				if _, ok := first["cefExtensions"].(map[string]interface{})["rt"].(string); !ok {
+					t.Errorf("string rt = %#v, want string", first["cefExtensions"])
+				}
+				if _, ok := second["cefExtensions"].(map[string]interface{})["rt"].(json.Number); !ok {
+					t.Errorf("number rt = %#v, want number", second["cefExtensions"])
+				}
+			},
+		},
+		{
+			name:          "rewrite shift keeps spacing",
+			opts:          EventReplayOptions{File: plain, RewriteTime: RewriteShift},
+			wantPublished: 3, wantSkipped: 1,
+			wantByMsgID: map[string]int{"USB": 2, "PRINT": 1},
+			check: func(t *testing.T, msgs []*sarama.ProducerMessage) {
+				var times []time.Time
+				for _, msg := range msgs {
+					_, event := decodeReplayed(t, msg)
+					times = append(times, eventTime(t, event))
+				}
+				if d := times[1].Sub(times[0]); d != 10*time.Second {
+					t.Errorf("gap 1 = %v, want 10s", d)
+				}
+				if d := times[2].Sub(times[0]); d != time.Minute {
+					t.Errorf("gap 2 = %v, want 1m", d)
+				}
+				if since := time.Since(times[0]); since < 0 || since > time.Minute {
+					t.Errorf("first event = %v, want now", times[0])
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var producer sarama.SyncProducer
+			fake := &fakeProducer{sent: map[string]int{}}
+			if !tt.opts.DryRun {
+				producer = fake
+			}
+			res, err := replayEvents(context.Background(), cfg, tt.opts, producer)
+			if err != nil {
+				t.Fatalf("replayEvents: %v", err)
+			}
+			if res.Read != 4 || res.Published != tt.wantPublished || res.Skipped != tt.wantSkipped {
+				t.Errorf("read/published/skipped = %d/%d/%d, want 4/%d/%d",
+					res.Read, res.Published, res.Skipped, tt.wantPublished, tt.wantSkipped)
+			}
+			if !reflect.DeepEqual(res.ByMsgID, tt.wantByMsgID) {
+				t.Errorf("ByMsgID = %v, want %v", res.ByMsgID, tt.wantByMsgID)
+			}
+			if len(fake.msgs) != tt.wantPublished {
+				t.Fatalf("messages = %d, want %d", len(fake.msgs), tt.wantPublished)
+			}
+			if tt.check != nil {
+				tt.check(t, fake.msgs)
+			}
+		})
+	}
+}
+
+// TestEventReplayOptionsValidate: 시각 재작성 방식과 입력 소스(파일/기간 중 하나)
+func TestEventReplayOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    EventReplayOptions
+		wantErr bool
+	}{
+		{"file", EventReplayOptions{File: "a.ndjson"}, false},
+		{"range with shift", EventReplayOptions{From: "2024-01-01", RewriteTime: RewriteShift}, false},
+		{"both sources", EventReplayOptions{File: "a.ndjson", From: "2024-01-01"}, true},
+		{"no source", EventReplayOptions{}, true},
+		{"bad rewrite", EventReplayOptions{File: "a.ndjson", RewriteTime: "later"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReplayRangeQuery: 날짜만이면 설정 타임존 하루 경계, RFC3339는 그대로, msgId terms 필터
+func TestReplayRangeQuery(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	tests := []struct {
+		name     string
+		from, to string
+		msgIDs   []string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "dates", from: "2024-01-01", to: "2024-01-02",
+			want: `{"bool":{"filter":[{"range":{"@timestamp":{"gte":"2024-01-01T00:00:00+09:00","lte":"2024-01-02T23:59:59.999+09:00"}}}]}}`,
+		},
+		{
+			name: "rfc3339 open end with msgIds", from: "2024-01-01T10:00:00Z", msgIDs: []string{"USB", "PRINT"},
+			want: `{"bool":{"filter":[{"range":{"@timestamp":{"gte":"2024-01-01T10:00:00Z"}}},{"terms":{"msgId":["USB","PRINT"]}}]}}`,
+		},
+		{name: "bad from", from: "yesterday", wantErr: true},
+		{name: "bad to", from: "2024-01-01", to: "2024/01/02", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := replayRangeQuery(tt.from, tt.to, tt.msgIDs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replayRangeQuery err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, _ := json.Marshal(query); string(got) != tt.want {
+				t.Errorf("query = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReplayPacer: 초당 rate건 간격 유지, rate 0이면 대기 없음
+func TestReplayPacer(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		calls   int
+		minTime time.Duration
+		maxTime time.Duration
+	}{
+		{"unlimited", 0, 100, 0, 50 * time.Millisecond},
+		{"100 per second", 100, 6, 45 * time.Millisecond, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newReplayPacer(tt.rate)
+			start := time.Now()
+			for i := 0; i < tt.calls; i++ {
+				p.Wait(context.Background())
+			}
+			if elapsed := time.Since(start); elapsed < tt.minTime || elapsed > tt.maxTime {
+				t.Errorf("%d calls took %v, want %v..%v", tt.calls, elapsed, tt.minTime, tt.maxTime)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package logsink
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/IBM/sarama"
@@ -11,9 +16,27 @@ import (
 	"github.com/markany/safepc-siem/internal/common"
 )
 
-func Start(cfg *config.Config) {
+const (
+	retryInitialBackoff = 500 * time.Millisecond
+	retryMaxBackoff     = 30 * time.Second
+	bulkStatsInterval   = 30 * time.Second
+)
+
+// errDeadLettered: 이벤트가 dead-letter로 처리되고 ack됨 (Kafka 입력은 정상 진행, HTTP 수집은 거부로 응답)
+var errDeadLettered = errors.New("dead-letter 처리됨")
+
+// Start: 설정의 OpenSearch로 LogSink 실행 (종료 신호까지 반환하지 않음)
+func Start(cfg *config.Config) error {
+	osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+	if err != nil {
+		return fmt.Errorf("OpenSearch 클라이언트 설정 오류: %v", err)
+	}
+	return StartWithStore(cfg, osClient)
+}
+
+// StartWithStore: store를 색인/설정 저장소로 LogSink 실행 (Kafka 입출력은 cfg 기준)
+func StartWithStore(cfg *config.Config, store common.Store) error {
 	common.InitTimezone(cfg.Timezone)
-	osClient := common.NewOSClient(cfg.OpenSearch.URL)
 
 	topics := strings.Split(cfg.Kafka.EventTopics, ",")
 	for i := range topics {
@@ -23,72 +46,459 @@ func Start(cfg *config.Config) {
 	// Kafka producer (변환 토픽 발행용)
 	prodCfg := sarama.NewConfig()
 	prodCfg.Producer.Return.Successes = true
+	prodCfg.Producer.RequiredAcks = sarama.WaitForAll
+	// 키 해시 파티셔닝 + 브로커당 요청 1개 (재시도 시에도 같은 키의 순서 유지)
+	prodCfg.Producer.Partitioner = sarama.NewHashPartitioner
+	prodCfg.Net.MaxOpenRequests = 1
 	producer, err := sarama.NewSyncProducer([]string{cfg.Kafka.Bootstrap}, prodCfg)
 	if err != nil {
-		log.Fatalf("[LogSink] Producer 생성 실패: %v", err)
+		return fmt.Errorf("Producer 생성 실패: %v", err)
 	}
 	defer producer.Close()
 
 	outTopic := cfg.LogSink.TransformedTopic
-	log.Printf("[LogSink] 시작: %d개 원본 토픽 → %s", len(topics), outTopic)
+	log.Printf("[LogSink] 시작: %d개 원본 토픽 → %s (group: %s)", len(topics), outTopic, cfg.Kafka.GroupID)
 
-	// Kafka consumer
+	// Kafka consumer group (커밋된 offset부터 재개, 최초 기동 시에만 최신 offset)
 	consCfg := sarama.NewConfig()
+	consCfg.Consumer.Return.Errors = true
 	consCfg.Consumer.Offsets.Initial = sarama.OffsetNewest
-	consumer, err := sarama.NewConsumer([]string{cfg.Kafka.Bootstrap}, consCfg)
+	consCfg.Consumer.Offsets.AutoCommit.Enable = true
+	consCfg.Consumer.Offsets.AutoCommit.Interval = time.Second
+	consCfg.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	group, err := sarama.NewConsumerGroup([]string{cfg.Kafka.Bootstrap}, cfg.Kafka.GroupID, consCfg)
 	if err != nil {
-		log.Fatalf("[LogSink] Consumer 생성 실패: %v", err)
+		return fmt.Errorf("Consumer group 생성 실패: %v", err)
 	}
-	defer consumer.Close()
+	defer group.Close()
 
-	for _, topic := range topics {
-		partitions, err := consumer.Partitions(topic)
-		if err != nil {
-			log.Printf("[LogSink] %s 파티션 조회 실패: %v", topic, err)
-			continue
+	go func() {
+		for err := range group.Errors() {
+			log.Printf("[LogSink] Consumer group 에러: %v", err)
 		}
-		for _, p := range partitions {
-			pc, err := consumer.ConsumePartition(topic, p, sarama.OffsetNewest)
-			if err != nil {
-				continue
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sink, wait, err := newSink(ctx, cfg, store, producer)
+	if err != nil {
+		return err
+	}
+	defer wait()
+	startAPI(ctx, cfg, sink)
+	startSyslog(ctx, sink, cfg.LogSink.SyslogUDP, cfg.LogSink.SyslogTCP)
+
+	handler := &groupHandler{sink: sink}
+	for {
+		// Consume은 리밸런스마다 반환되므로 루프에서 재참여
+		if err := group.Consume(ctx, topics, handler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
 			}
-			go func(pc sarama.PartitionConsumer) {
-				defer pc.Close()
-				for msg := range pc.Messages() {
-					processMessage(msg.Value, producer, outTopic, osClient, cfg.IndexPrefix)
-				}
-			}(pc)
+			log.Printf("[LogSink] Consume 실패: %v", err)
+			time.Sleep(retryInitialBackoff)
+		}
+		if ctx.Err() != nil {
+			log.Println("[LogSink] 종료 신호 수신, 커밋 후 종료")
+			return nil
+		}
+	}
+}
+
+// newSink: store/producer로 Sink를 구성하고 백그라운드 작업(bulk flush, 리로드, 통계)을 ctx 수명으로 시작
+// 설정 파일(LEEF 별칭/정규화/비식별) 오류는 백그라운드 작업을 시작하기 전에 반환한다.
+// 반환된 wait는 ctx 종료 후 아카이브와 bulk의 마지막 flush가 끝날 때까지 대기한다.
+func newSink(ctx context.Context, cfg *config.Config, store common.Store, producer sarama.SyncProducer) (*Sink, func(), error) {
+	// LEEF 벤더별 속성 키 별칭
+	leefAliases, err := common.LoadLEEFAliases(cfg.LogSink.LEEFAliasFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("LEEF 별칭 파일 오류: %v", err)
+	}
+
+	// OCSF/ECS 정규화 (비활성 시 nil)
+	normalizer, err := newNormalizer(cfg.LogSink.NormalizeSchema, cfg.LogSink.NormalizeFile,
+		cfg.LogSink.NormalizeOutput, cfg.LogSink.NormalizeTopic)
+	if err != nil {
+		return nil, nil, fmt.Errorf("정규화 설정 오류: %v", err)
+	}
+	if normalizer != nil {
+		log.Printf("[LogSink] 정규화: %s (%s, 토픽 %s)", cfg.LogSink.NormalizeSchema, cfg.LogSink.NormalizeOutput, cfg.LogSink.NormalizeTopic)
+	}
+
+	// 비식별 정책 (OpenSearch/Kafka 사본별)
+	redactor, err := newRedactor(cfg.LogSink.RedactFile, cfg.LogSink.RedactKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("비식별 정책 오류: %v", err)
+	}
+	if n := len(redactor.cfg.OpenSearch) + len(redactor.cfg.Kafka); n > 0 {
+		log.Printf("[LogSink] 비식별 규칙: OpenSearch %d개, Kafka %d개", len(redactor.cfg.OpenSearch), len(redactor.cfg.Kafka))
+	}
+
+	// OpenSearch bulk 색인기 (건수/크기/주기 flush)
+	bulk := common.NewBulkWriter(store, common.BulkOptions{
+		Name:          "LogSink",
+		MaxDocs:       cfg.LogSink.BulkMaxDocs,
+		MaxBytes:      cfg.LogSink.BulkMaxBytes,
+		FlushInterval: cfg.LogSink.BulkFlushInterval,
+		MaxRetries:    cfg.LogSink.BulkMaxRetries,
+		Backoff:       retryInitialBackoff,
+		MaxBackoff:    retryMaxBackoff,
+		StatsInterval: bulkStatsInterval,
+	})
+	bulkDone := make(chan struct{})
+	go func() {
+		bulk.Run(ctx)
+		close(bulkDone)
+	}()
+
+	// dead-letter (파싱 실패/발행 불가/색인 거부)
+	dlq := newDeadLetterQueue(producer, cfg.LogSink.DeadLetterTopic, cfg.LogSink.DeadLetterFile)
+	// 색인 거부(매핑 오류 등 재시도 불가) → dead-letter 후 ack (동일 이벤트가 파티션 진행을 막지 않도록)
+	// 연결 오류/429/5xx는 BulkWriter가 버퍼에 보류하므로 ack되지 않는다 (OpenSearch 복구 후 색인)
+	bulk.OnFailure = func(item *common.BulkItem) {
+		meta, _ := item.Meta.(*indexMeta)
+		dlq.SendIndex(meta.src, item)
+		meta.ack()
+	}
+
+	// 변환 파이프라인 (파일 + settings 인덱스, 주기적 리로드)
+	transformer := newTransformer(store, cfg.IndexPrefix, cfg.LogSink.PipelineFile)
+	go transformer.Run(ctx.Done(), cfg.LogSink.PipelineReload)
+
+	// IP/자산 보강 (참조 파일 변경 시 자동 리로드)
+	enricher := newEnricher(cfg.LogSink.EnrichCIDRFiles, cfg.LogSink.EnrichAssetFile, cfg.LogSink.EnrichMMDBFile)
+	go enricher.Run(ctx.Done(), cfg.LogSink.EnrichReload)
+
+	// CEF 값 타입 사전 (field-meta 숫자 필드는 파이프라인과 같은 주기로 갱신)
+	typer := newTyper(store, cfg.IndexPrefix)
+	go typer.Run(ctx.Done(), cfg.LogSink.PipelineReload)
+
+	// 원본 보존용 로컬 아카이브 (gzip NDJSON, 일자/크기 단위 세그먼트)
+	var archive *archiver
+	archiveDone := make(chan struct{})
+	if cfg.LogSink.ArchiveDir != "" {
+		archive = newArchiver(cfg.LogSink.ArchiveDir, cfg.LogSink.ArchiveMaxBytes)
+		bulk.BeforeFlush = archive.Flush
+		go func() {
+			archive.Run(ctx.Done())
+			close(archiveDone)
+		}()
+		log.Printf("[LogSink] 아카이브: %s (세그먼트 %dMB)", cfg.LogSink.ArchiveDir, cfg.LogSink.ArchiveMaxBytes/1024/1024)
+	}
+
+	// 내용 지문 기반 중복 억제 (지문은 OpenSearch _id로도 사용)
+	dedup := newDeduplicator(cfg.LogSink.DedupFields, cfg.LogSink.DedupWindow, cfg.LogSink.DedupAction)
+	if dedup != nil {
+		go dedup.Run(ctx.Done())
+		log.Printf("[LogSink] 중복 억제: 지문 필드 %s, 구간 %s, 처리 %s", strings.Join(dedup.fields, ","), dedup.window, dedup.action)
+	}
+
+	// 변환 토픽 메시지 키 (사용자별 파티션 고정) + 파티션 분포 통계
+	partitions := newPartitionStats()
+	go partitions.Run(ctx.Done())
+	log.Printf("[LogSink] 메시지 키 필드: %s", strings.Join(cfg.LogSink.PartitionKeys, " → "))
+
+	// 출처(토픽/msgId/호스트)별 수집량 이상 감시 → health 토픽
+	volume := newVolumeMonitor(producer, cfg.LogSink.HealthTopic, VolumeOptions{
+		Interval:    cfg.LogSink.VolumeInterval,
+		Window:      cfg.LogSink.VolumeWindow,
+		SpikeFactor: cfg.LogSink.VolumeSpikeFactor,
+		SilentAfter: cfg.LogSink.VolumeSilentAfter,
+		MinRate:     cfg.LogSink.VolumeMinRate,
+	})
+	go volume.Run(ctx.Done())
+
+	sink := &Sink{
+		producer:    producer,
+		outTopic:    cfg.LogSink.TransformedTopic,
+		bulk:        bulk,
+		dlq:         dlq,
+		transformer: transformer,
+		enricher:    enricher,
+		times:       newTimeNormalizer(cfg.LogSink.TimeFields, cfg.LogSink.ClockSkew),
+		typer:       typer,
+		archive:     archive,
+		normalizer:  normalizer,
+		redactor:    redactor,
+		keyer:       newPartitionKeyer(cfg.LogSink.PartitionKeys),
+		partitions:  partitions,
+		volume:      volume,
+		dedup:       dedup,
+		prefix:      cfg.IndexPrefix,
+		formats:     cfg.LogSink.TopicFormats,
+		leefAliases: leefAliases,
+	}
+	wait := func() {
+		if archive != nil {
+			<-archiveDone
 		}
+		<-bulkDone
 	}
-	select {}
+	return sink, wait, nil
+}
+
+// Sink: 원본 이벤트 변환 → 변환 토픽 발행 → OpenSearch bulk 색인
+type Sink struct {
+	producer    sarama.SyncProducer
+	outTopic    string
+	bulk        *common.BulkWriter
+	dlq         *deadLetterQueue
+	transformer *transformer
+	enricher    *enricher
+	times       *timeNormalizer
+	typer       *typer
+	archive     *archiver   // nil이면 비활성
+	normalizer  *normalizer // nil이면 비활성
+	redactor    *redactor
+	keyer       *partitionKeyer
+	partitions  *partitionStats
+	volume      *volumeMonitor
+	dedup       *deduplicator // nil이면 비활성
+	prefix      string
+	formats     map[string]string // 토픽별 입력 포맷
+	leefAliases common.LEEFAliases
+}
+
+// groupHandler: sarama.ConsumerGroupHandler 구현
+// 변환 토픽 발행 + OpenSearch 색인이 모두 성공한 메시지만 offset을 마킹한다 (at-least-once)
+type groupHandler struct {
+	sink *Sink
+}
+
+func (h *groupHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	log.Printf("[LogSink] 파티션 할당: %v (generation %d)", sess.Claims(), sess.GenerationID())
+	return nil
+}
+
+// Cleanup: 세션 종료 직전 버퍼를 비워 ack된 offset이 이번 세션에서 커밋되도록 한다
+func (h *groupHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	h.sink.bulk.Flush(sess.Context())
+	log.Printf("[LogSink] 파티션 해제 (generation %d)", sess.GenerationID())
+	return nil
 }
 
-func processMessage(data []byte, producer sarama.SyncProducer, outTopic string, os *common.OSClient, prefix string) {
-	var event map[string]interface{}
-	if err := json.Unmarshal(data, &event); err != nil {
-		return
+func (h *groupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tracker := newOffsetTracker(func(offset int64) {
+		sess.MarkOffset(claim.Topic(), claim.Partition(), offset, "")
+	})
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			tracker.add(msg.Offset)
+			offset := msg.Offset
+			src := &Source{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, Raw: msg.Value}
+			if err := h.sink.processMessage(sess.Context(), src, func() { tracker.ack(offset) }); err != nil {
+				// 세션 종료(리밸런스/셧다운)로 중단된 경우 마킹하지 않음 → 다음 소유자가 재처리
+				log.Printf("[LogSink] %s/%d@%d 처리 중단: %v", msg.Topic, msg.Partition, msg.Offset, err)
+				return nil
+			}
+		case <-sess.Context().Done():
+			return nil
+		}
 	}
+}
 
-	now := common.Now()
-	if event["@timestamp"] == nil {
-		event["@timestamp"] = now.Format(time.RFC3339)
+// processMessage: 원본 이벤트 변환 → 변환 토픽 발행 → bulk 버퍼 적재
+// 발행 실패는 ctx가 끝날 때까지 재시도하고, ack는 색인 완료(또는 dead-letter) 후 호출된다
+// 파싱 불가 이벤트는 dead-letter 기록 후 즉시 ack
+func (s *Sink) processMessage(ctx context.Context, src *Source, ack func()) error {
+	event, err := decodeEvent(s.formats[src.Topic], src.Raw, s.leefAliases)
+	if err != nil {
+		s.dlq.Send(src, StageDecode, err)
+		ack()
+		return nil
 	}
+	if err := s.processEvent(ctx, src, event, ack); err != nil && !errors.Is(err, errDeadLettered) {
+		return err
+	}
+	return nil
+}
+
+// processEvent: 디코딩된 이벤트 공통 처리 (Kafka/syslog/HTTP 입력 공용)
+// 재시도 불가 발행 오류는 dead-letter 기록 + ack 후 errDeadLettered 반환
+func (s *Sink) processEvent(ctx context.Context, src *Source, event map[string]interface{}, ack func()) error {
+	// 수집량 집계 (변환 전 원본 msgId/hostname 기준)
+	s.volume.Record(src.Topic, event)
 
 	// CEF label 변환
 	if ext, ok := event["cefExtensions"].(map[string]interface{}); ok {
 		common.ExpandCEFLabels(ext)
 	}
 
-	out, _ := json.Marshal(event)
+	// 문서 _id (eventId로도 기록) — 실시간 색인, 재전달, 아카이브 복원이 같은 _id로 create하므로 중복 문서가 생기지 않는다
+	// 내용 지문 (변환/보강 전 원본 기준) — 구간 내 중복은 drop 또는 tag
+	// 구간 기억은 색인 완료 시 기록 (dead-letter/재전달 대상 이벤트는 기록하지 않음)
+	fingerprint, docID := "", eventID(src)
+	indexed := ack
+	if s.dedup != nil {
+		fingerprint = s.dedup.Fingerprint(event, src)
+		docID = fingerprint
+		if s.dedup.Seen(fingerprint) {
+			if s.dedup.action == DedupDrop {
+				ack()
+				return nil
+			}
+			// tag 사본은 원본과 다른 _id — 같은 _id면 create 409로 색인되지 않는다
+			event["duplicate"] = true
+			docID = duplicateID(fingerprint, src)
+		} else {
+			indexed = func() {
+				s.dedup.Record(fingerprint)
+				ack()
+			}
+		}
+		event["fingerprint"] = fingerprint
+	}
+	event["eventId"] = docID
+
+	// 토픽별 변환 파이프라인 (단계 오류는 로그만 남기고 계속 진행)
+	if errs := s.transformer.Get().Apply(src.Topic, event); len(errs) > 0 {
+		log.Printf("[LogSink] 변환 단계 오류 (%s): %s", src.Topic, strings.Join(errs, "; "))
+	}
+
+	// 서브넷/지역/자산 보강
+	s.enricher.Enrich(event)
+
+	// 이벤트 시각(@timestamp) 정규화 — 일별 인덱스도 이벤트 시각 기준
+	eventTime := s.times.Normalize(event)
+
+	// cefExtensions 숫자 타입 변환 (표준 키/label/field-meta)
+	s.typer.Apply(event)
+
+	// 비식별 — Kafka 사본과 OpenSearch/아카이브 사본에 정책을 따로 적용 (규칙이 없으면 같은 이벤트)
+	kafkaEvent := s.redactor.Apply(event, s.redactor.cfg.Kafka)
+	storedEvent := s.redactor.Apply(event, s.redactor.cfg.OpenSearch)
+	separate := s.redactor.Enabled()
+
+	// OCSF/ECS 정규화 — embed는 하위 객체로 포함, topic은 변환 토픽 발행 후 별도 발행 (Kafka 사본 기준)
+	var normalized []byte
+	if s.normalizer != nil {
+		n := s.normalizer.Normalize(src.Topic, kafkaEvent)
+		if s.normalizer.output == NormalizeEmbed {
+			kafkaEvent["normalized"] = n
+			if separate {
+				storedEvent["normalized"] = s.normalizer.Normalize(src.Topic, storedEvent)
+			}
+		} else {
+			normalized, _ = json.Marshal(n)
+		}
+	}
+
+	out, _ := json.Marshal(kafkaEvent)
+	stored := out
+	if separate {
+		stored, _ = json.Marshal(storedEvent)
+	}
+	// 메시지 키도 Kafka 사본 값 (해시 정책이면 해시가 키 → 사용자별 파티션 고정 유지)
+	key := s.keyer.Key(kafkaEvent)
 
-	// 변환 토픽 발행
-	producer.SendMessage(&sarama.ProducerMessage{
-		Topic: outTopic,
-		Value: sarama.ByteEncoder(out),
+	// 변환 토픽 발행 (메시지 크기 초과 등 재시도 불가 오류는 dead-letter)
+	if err := retryUntil(ctx, "발행", func() error {
+		partition, _, err := s.producer.SendMessage(&sarama.ProducerMessage{
+			Topic: s.outTopic,
+			Key:   s.keyer.encoder(key),
+			Value: sarama.ByteEncoder(out),
+		})
+		if err == nil {
+			s.partitions.Record(partition, key)
+		}
+		if isPermanentProduceError(err) {
+			return backoffStop{err}
+		}
+		return err
+	}); err != nil {
+		var stop backoffStop
+		if errors.As(err, &stop) {
+			s.dlq.Send(src, StagePublish, stop.err)
+			ack()
+			return errDeadLettered
+		}
+		return err
+	}
+
+	// 정규화 토픽 발행 (재시도 불가 오류는 로그만 — 변환 토픽/색인 경로는 계속)
+	if normalized != nil {
+		if err := retryUntil(ctx, "정규화 발행", func() error {
+			_, _, err := s.producer.SendMessage(&sarama.ProducerMessage{
+				Topic: s.normalizer.topic,
+				Key:   s.keyer.encoder(key),
+				Value: sarama.ByteEncoder(normalized),
+			})
+			if isPermanentProduceError(err) {
+				return backoffStop{err}
+			}
+			return err
+		}); err != nil {
+			var stop backoffStop
+			if !errors.As(err, &stop) {
+				return err
+			}
+			log.Printf("[LogSink] 정규화 발행 불가 (%s/%d@%d): %v", src.Topic, src.Partition, src.Offset, stop.err)
+		}
+	}
+
+	// 로컬 아카이브 (디스크 가득 참 등 실패는 복구될 때까지 재시도 — 아카이브에 없는 이벤트는 ack하지 않음)
+	if s.archive != nil {
+		day := eventTime.Format("2006-01-02")
+		if err := retryUntil(ctx, "아카이브 기록", func() error { return s.archive.Write(day, stored) }); err != nil {
+			return err
+		}
+	}
+
+	// OpenSearch event-logs 색인 (bulk) — eventId가 _id라 재전달/재시도는 create 409로 원본 1건만 남는다
+	s.bulk.AddItem(&common.BulkItem{
+		Index:     common.DailyLogsIndex(s.prefix, eventTime.Format("2006.01.02")),
+		ID:        docID,
+		Create:    true,
+		Doc:       stored,
+		OnSuccess: indexed,
+		Meta:      &indexMeta{src: src, ack: ack},
 	})
+	return nil
+}
+
+// indexMeta: bulk 항목 메타 — 색인 거부 시 dead-letter 원본과 (지문 기록 없는) ack
+type indexMeta struct {
+	src *Source
+	ack func()
+}
+
+// isPermanentProduceError: 재시도해도 성공할 수 없는 producer 오류
+func isPermanentProduceError(err error) bool {
+	return errors.Is(err, sarama.ErrMessageSizeTooLarge) ||
+		errors.Is(err, sarama.ErrInvalidMessage) ||
+		errors.Is(err, sarama.ErrInvalidMessageSize)
+}
 
-	// OpenSearch event-logs 저장
-	if err := os.Index(common.DailyLogsIndex(prefix, now.Format("2006.01.02")), event); err != nil {
-		log.Printf("[LogSink] 저장 실패: %v", err)
+// backoffStop: retryUntil 재시도를 즉시 중단시키는 오류 래퍼
+type backoffStop struct{ err error }
+
+func (b backoffStop) Error() string { return b.err.Error() }
+
+// retryUntil: fn이 성공하거나 ctx가 끝날 때까지 지수 백오프로 재시도
+func retryUntil(ctx context.Context, stage string, fn func() error) error {
+	backoff := retryInitialBackoff
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if _, stop := err.(backoffStop); stop {
+			return err
+		}
+		log.Printf("[LogSink] %s 실패, %s 후 재시도: %v", stage, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > retryMaxBackoff {
+			backoff = retryMaxBackoff
+		}
 	}
 }
@@ -0,0 +1,110 @@
+package logsink
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/ostest"
+)
+
+// fakeProducer: 발행 메시지를 토픽별로 기록하는 SyncProducer (나머지 메서드는 호출되지 않음)
+type fakeProducer struct {
+	sarama.SyncProducer
+	mu   sync.Mutex
+	sent map[string]int
+	msgs []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent[msg.Topic]++
+	p.msgs = append(p.msgs, msg)
+	return 0, int64(p.sent[msg.Topic]), nil
+}
+
+// TestSinkDailyIndex: 이벤트 시각(설정 타임존) 일자별 event-logs 인덱스로 bulk 색인되고 색인 후 ack
+func TestSinkDailyIndex(t *testing.T) {
+	srv := ostest.NewServer()
+	defer srv.Close()
+
+	cfg := config.LoadFromEnv("logsink")
+	cfg.IndexPrefix = "test"
+	cfg.Timezone = "Asia/Seoul"
+	cfg.LogSink.DeadLetterFile = filepath.Join(t.TempDir(), "dlq.ndjson")
+	cfg.LogSink.ArchiveDir = ""
+	cfg.LogSink.PipelineFile = ""
+	common.InitTimezone(cfg.Timezone)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	producer := &fakeProducer{sent: map[string]int{}}
+	sink, wait, err := newSink(ctx, cfg, srv.OSClient(), producer)
+	if err != nil {
+		t.Fatalf("newSink: %v", err)
+	}
+	defer func() {
+		cancel()
+		wait()
+	}()
+
+	events := []string{
+		`{"seq":"kst-late","msgId":"USB","@timestamp":"2024-01-01T23:30:00+09:00"}`,
+		`{"seq":"utc-after-kst-midnight","msgId":"USB","@timestamp":"2024-01-01T15:30:00Z"}`,
+		`{"seq":"cef-rt","msgId":"PRINT","cefExtensions":{"rt":"1704153600000","suid":"u1"},"@timestamp":"2023-12-31T00:00:00Z"}`,
+		`{"seq":"utc-before-kst-midnight","msgId":"USB","@timestamp":"2024-01-01T14:59:59Z"}`,
+	}
+	var mu sync.Mutex
+	acked := 0
+	for i, raw := range events {
+		src := &Source{Topic: "MESSAGE_DEVICE", Partition: 0, Offset: int64(i), Raw: []byte(raw)}
+		err := sink.processMessage(ctx, src, func() {
+			mu.Lock()
+			acked++
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("processMessage %d: %v", i, err)
+		}
+	}
+	mu.Lock()
+	if acked != 0 {
+		t.Errorf("acked %d before bulk flush, want 0", acked)
+	}
+	mu.Unlock()
+	sink.bulk.Flush(ctx)
+
+	want := map[string][]string{
+		common.DailyLogsIndex("test", "2024.01.01"): {"kst-late", "utc-before-kst-midnight"},
+		common.DailyLogsIndex("test", "2024.01.02"): {"cef-rt", "utc-after-kst-midnight"},
+	}
+	if got := srv.Indices(common.LogsIndexPattern("test")); len(got) != len(want) {
+		t.Errorf("indices = %v, want %d daily indices", got, len(want))
+	}
+	for index, seqs := range want {
+		var got []string
+		for _, doc := range srv.Docs(index) {
+			got = append(got, doc["seq"].(string))
+		}
+		sort.Strings(got)
+		if len(got) != len(seqs) || got[0] != seqs[0] || got[1] != seqs[1] {
+			t.Errorf("%s = %v, want %v", index, got, seqs)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if acked != len(events) {
+		t.Errorf("acked %d after flush, want %d", acked, len(events))
+	}
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+	if n := producer.sent[cfg.LogSink.TransformedTopic]; n != len(events) {
+		t.Errorf("published %d to %s, want %d", n, cfg.LogSink.TransformedTopic, len(events))
+	}
+}
@@ -0,0 +1,430 @@
+package logsink
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+const (
+	syslogMaxFrame      = 64 * 1024
+	syslogMaxCountLen   = 10 // octet-counting 길이 접두어 최대 자릿수
+	syslogStatsInterval = 60 * time.Second
+	syslogTCPIdle       = 5 * time.Minute
+	syslogQueueSize     = 10000 // 수신 → 처리 대기열 (UDP는 가득 차면 버림, TCP는 읽기 대기)
+	syslogWorkers       = 4
+)
+
+// SyslogMessage: RFC 3164 / RFC 5424 파싱 결과
+type SyslogMessage struct {
+	Facility  int
+	Severity  int
+	Timestamp time.Time // 헤더에 시각이 없거나 파싱 불가면 zero
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Message   string
+}
+
+// syslogStats: 리스너별 수신 통계
+type syslogStats struct {
+	received  atomic.Int64
+	accepted  atomic.Int64
+	malformed atomic.Int64
+	dropped   atomic.Int64 // 대기열 초과로 버린 UDP 프레임
+	bytes     atomic.Int64
+}
+
+// syslogFrame: 처리 대기 중인 수신 프레임
+type syslogFrame struct {
+	proto string
+	peer  string
+	data  []byte
+}
+
+// syslogReceiver: UDP/TCP syslog 수신 → 대기열 → 워커가 이벤트 변환 후 Sink.processEvent
+// 수신 루프는 Kafka/OpenSearch 지연에 막히지 않는다 (발행 재시도는 워커에서)
+type syslogReceiver struct {
+	sink  *Sink
+	stats map[string]*syslogStats // "udp"/"tcp"
+	queue chan syslogFrame
+}
+
+// startSyslog: 주소가 지정된 리스너만 기동 (빈 문자열이면 비활성)
+func startSyslog(ctx context.Context, sink *Sink, udpAddr, tcpAddr string) {
+	if udpAddr == "" && tcpAddr == "" {
+		return
+	}
+	r := &syslogReceiver{
+		sink:  sink,
+		stats: map[string]*syslogStats{"udp": {}, "tcp": {}},
+		queue: make(chan syslogFrame, syslogQueueSize),
+	}
+	for i := 0; i < syslogWorkers; i++ {
+		go r.work(ctx)
+	}
+	if udpAddr != "" {
+		go r.serveUDP(ctx, udpAddr)
+	}
+	if tcpAddr != "" {
+		go r.serveTCP(ctx, tcpAddr)
+	}
+	go r.logStats(ctx)
+}
+
+func (r *syslogReceiver) serveUDP(ctx context.Context, addr string) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		log.Printf("[LogSink] syslog UDP 리스너 시작 실패 (%s): %v", addr, err)
+		return
+	}
+	log.Printf("[LogSink] syslog UDP 수신 대기: %s", addr)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, syslogMaxFrame)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[LogSink] syslog UDP 수신 실패: %v", err)
+			continue
+		}
+		stats := r.stats["udp"]
+		stats.received.Add(1)
+		stats.bytes.Add(int64(n))
+		// 버퍼 재사용: dead-letter/bulk 항목이 원본을 참조하므로 복사
+		// 대기열이 가득 차면 읽기를 멈추지 않고 버린다 (멈추면 커널 버퍼에서 어차피 유실)
+		select {
+		case r.queue <- syslogFrame{proto: "udp", peer: peer.String(), data: append([]byte(nil), buf[:n]...)}:
+		default:
+			if stats.dropped.Add(1)%1000 == 1 {
+				log.Printf("[LogSink] syslog UDP 처리 대기열 초과, 프레임 버림 (누적 %d건)", stats.dropped.Load())
+			}
+		}
+	}
+}
+
+// work: 대기열 프레임 처리 (ctx 종료 시 남은 프레임은 버림 — syslog는 재전송 수단이 없음)
+func (r *syslogReceiver) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-r.queue:
+			r.handleFrame(ctx, f.proto, f.peer, f.data)
+		}
+	}
+}
+
+func (r *syslogReceiver) serveTCP(ctx context.Context, addr string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("[LogSink] syslog TCP 리스너 시작 실패 (%s): %v", addr, err)
+		return
+	}
+	log.Printf("[LogSink] syslog TCP 수신 대기: %s", addr)
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[LogSink] syslog TCP accept 실패: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go r.serveTCPConn(ctx, conn)
+	}
+}
+
+// serveTCPConn: 프레임마다 octet-counting("LEN SP MSG")과 LF 구분(non-transparent)을 자동 판별
+// 종료 신호를 받으면 유휴 대기 중인 연결도 즉시 닫는다
+func (r *syslogReceiver) serveTCPConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	peer := conn.RemoteAddr().String()
+	stats := r.stats["tcp"]
+	reader := bufio.NewReaderSize(conn, syslogMaxFrame)
+	for ctx.Err() == nil {
+		conn.SetReadDeadline(time.Now().Add(syslogTCPIdle))
+		frame, err := readSyslogFrame(reader)
+		if len(frame) > 0 {
+			stats.received.Add(1)
+			stats.bytes.Add(int64(len(frame)))
+			// TCP는 대기열이 가득 차면 읽기를 멈춰 송신측에 백프레셔
+			select {
+			case r.queue <- syslogFrame{proto: "tcp", peer: peer, data: frame}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Printf("[LogSink] syslog TCP 연결 종료 (%s): %v", peer, err)
+			}
+			return
+		}
+	}
+}
+
+// readSyslogFrame: TCP 스트림에서 syslog 프레임 1개 읽기 (RFC 6587)
+func readSyslogFrame(reader *bufio.Reader) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		// octet-counting (길이 접두어는 최대 syslogMaxCountLen자리 — 공백이 오지 않는 스트림을 무한히 버퍼링하지 않도록)
+		var lenStr []byte
+		for {
+			c, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if c == ' ' {
+				break
+			}
+			if c < '0' || c > '9' || len(lenStr) >= syslogMaxCountLen {
+				return nil, fmt.Errorf("잘못된 octet count: %q", append(lenStr, c))
+			}
+			lenStr = append(lenStr, c)
+		}
+		n, err := strconv.Atoi(string(lenStr))
+		if err != nil || n <= 0 || n > syslogMaxFrame {
+			return nil, fmt.Errorf("잘못된 octet count: %q", lenStr)
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+	// non-transparent framing (LF 구분)
+	line, err := reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		// 최대 길이 초과: 나머지는 버리고 다음 프레임부터 재동기화
+		for errors.Is(err, bufio.ErrBufferFull) {
+			_, err = reader.ReadSlice('\n')
+		}
+		return nil, err
+	}
+	frame := make([]byte, len(line))
+	copy(frame, line)
+	return frame, err
+}
+
+// handleFrame: 파싱 실패 프레임은 통계만 남기고 버린다 (리스너는 계속 동작)
+func (r *syslogReceiver) handleFrame(ctx context.Context, proto, peer string, frame []byte) {
+	stats := r.stats[proto]
+	src := &Source{Topic: "syslog/" + proto, Partition: -1, Offset: -1, Raw: frame}
+	msg, err := ParseSyslog(strings.TrimRight(string(frame), "\r\n\x00"))
+	if err != nil {
+		if stats.malformed.Add(1)%1000 == 1 {
+			log.Printf("[LogSink] syslog 프레임 파싱 실패 (%s %s): %v", proto, peer, err)
+		}
+		r.sink.dlq.Send(src, StageDecode, err)
+		return
+	}
+	event, err := syslogToEvent(msg, peer, r.sink.leefAliases)
+	if err != nil {
+		if stats.malformed.Add(1)%1000 == 1 {
+			log.Printf("[LogSink] syslog CEF/LEEF 파싱 실패 (%s %s): %v", proto, peer, err)
+		}
+		r.sink.dlq.Send(src, StageDecode, err)
+		return
+	}
+	if err := r.sink.processEvent(ctx, src, event, func() {}); err != nil {
+		return
+	}
+	stats.accepted.Add(1)
+}
+
+func (r *syslogReceiver) logStats(ctx context.Context) {
+	ticker := time.NewTicker(syslogStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for proto, s := range r.stats {
+				if s.received.Load() == 0 {
+					continue
+				}
+				log.Printf("[LogSink] syslog %s 통계: 수신 %d건 (%dKB), 처리 %d건, 파싱 실패 %d건, 대기열 초과 %d건, 대기 %d건",
+					proto, s.received.Load(), s.bytes.Load()/1024, s.accepted.Load(), s.malformed.Load(), s.dropped.Load(), len(r.queue))
+			}
+		}
+	}
+}
+
+// syslogToEvent: CEF/LEEF 페이로드면 ParseRawEvent, 아니면 메시지 원문을 담은 이벤트로 변환
+func syslogToEvent(msg *SyslogMessage, peer string, aliases common.LEEFAliases) (map[string]interface{}, error) {
+	var event map[string]interface{}
+	if strings.Contains(msg.Message, "CEF:") || strings.Contains(msg.Message, "LEEF:") {
+		e, err := common.ParseRawEvent(msg.Message, aliases)
+		if err != nil {
+			return nil, err
+		}
+		event = e
+	} else {
+		msgID := msg.MsgID
+		if msgID == "" {
+			msgID = "SYSLOG"
+		}
+		event = map[string]interface{}{
+			"msgId":         msgID,
+			"appName":       msg.AppName,
+			"message":       msg.Message,
+			"cefExtensions": map[string]interface{}{},
+		}
+	}
+	if event["hostname"] == nil && msg.Hostname != "" {
+		event["hostname"] = msg.Hostname
+	}
+	if event["appName"] == nil || event["appName"] == "" {
+		event["appName"] = msg.AppName
+	}
+	if !msg.Timestamp.IsZero() && event["@timestamp"] == nil {
+		event["@timestamp"] = msg.Timestamp.In(common.Now().Location()).Format(time.RFC3339)
+	}
+	event["syslog"] = map[string]interface{}{
+		"facility": msg.Facility,
+		"severity": msg.Severity,
+		"procId":   msg.ProcID,
+		"peer":     peer,
+	}
+	return event, nil
+}
+
+// ParseSyslog: "<PRI>" 뒤 "1 "이면 RFC 5424, 아니면 RFC 3164로 파싱
+func ParseSyslog(line string) (*SyslogMessage, error) {
+	if !strings.HasPrefix(line, "<") {
+		return nil, fmt.Errorf("PRI 없음")
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("잘못된 PRI")
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("잘못된 PRI: %s", line[1:end])
+	}
+	msg := &SyslogMessage{Facility: pri / 8, Severity: pri % 8}
+	rest := line[end+1:]
+	if strings.HasPrefix(rest, "1 ") {
+		return parseRFC5424(msg, rest[2:])
+	}
+	return parseRFC3164(msg, rest), nil
+}
+
+// parseRFC5424: TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(msg *SyslogMessage, rest string) (*SyslogMessage, error) {
+	fields := make([]string, 0, 5)
+	for len(fields) < 5 {
+		sp := strings.IndexByte(rest, ' ')
+		if sp < 0 {
+			return nil, fmt.Errorf("RFC 5424 헤더 필드 부족")
+		}
+		fields = append(fields, rest[:sp])
+		rest = rest[sp+1:]
+	}
+	nilOr := func(s string) string {
+		if s == "-" {
+			return ""
+		}
+		return s
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		msg.Timestamp = t
+	}
+	msg.Hostname = nilOr(fields[1])
+	msg.AppName = nilOr(fields[2])
+	msg.ProcID = nilOr(fields[3])
+	msg.MsgID = nilOr(fields[4])
+
+	// STRUCTURED-DATA: "-" 또는 [..][..] (값 내부 \] 이스케이프 고려)
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else if strings.HasPrefix(rest, "[") {
+		i, depth := 0, 0
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if c == '\\' {
+				i++
+				continue
+			}
+			if c == '[' {
+				depth++
+			} else if c == ']' {
+				depth--
+				if depth == 0 && (i+1 >= len(rest) || rest[i+1] != '[') {
+					i++
+					break
+				}
+			}
+		}
+		if depth != 0 {
+			return nil, fmt.Errorf("STRUCTURED-DATA 종료 안 됨")
+		}
+		rest = rest[i:]
+	} else {
+		return nil, fmt.Errorf("잘못된 STRUCTURED-DATA")
+	}
+	msg.Message = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+	return msg, nil
+}
+
+// parseRFC3164: "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG" (헤더가 불완전하면 전체를 MSG로)
+func parseRFC3164(msg *SyslogMessage, rest string) *SyslogMessage {
+	if len(rest) >= 16 && rest[15] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, rest[:15], common.Now().Location()); err == nil {
+			now := common.Now()
+			t = t.AddDate(now.Year(), 0, 0)
+			// 연말/연초 경계: 미래 시각이면 작년으로
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = t
+			rest = rest[16:]
+			if sp := strings.IndexByte(rest, ' '); sp > 0 {
+				msg.Hostname = rest[:sp]
+				rest = rest[sp+1:]
+			}
+		}
+	}
+	// TAG: 영숫자 최대 32자 + 선택적 [PID] + ':'
+	if colon := strings.Index(rest, ": "); colon > 0 && colon <= 48 && !strings.ContainsAny(rest[:colon], " |=") {
+		tag := rest[:colon]
+		if lb := strings.IndexByte(tag, '['); lb > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[lb+1 : len(tag)-1]
+			tag = tag[:lb]
+		}
+		msg.AppName = tag
+		rest = rest[colon+2:]
+	}
+	msg.Message = rest
+	return msg
+}
@@ -0,0 +1,167 @@
+package logsink
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseSyslog: RFC 5424/3164 헤더 필드와 메시지 분리
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		want      SyslogMessage // Timestamp는 wantTS/wantStamp로 비교
+		wantTS    string        // 5424: UTC RFC3339
+		wantStamp string        // 3164: time.Stamp (연도는 현재 기준 보정이라 제외)
+		wantErr   bool
+	}{
+		{
+			name:   "rfc5424 with structured data",
+			line:   `<165>1 2024-01-02T03:04:05.123Z pc-1 agent 42 ID47 [ex@1 k="v\]"][b@2 x="y"] hello`,
+			want:   SyslogMessage{Facility: 20, Severity: 5, Hostname: "pc-1", AppName: "agent", ProcID: "42", MsgID: "ID47", Message: "hello"},
+			wantTS: "2024-01-02T03:04:05Z",
+		},
+		{
+			name:   "rfc5424 nil values and BOM",
+			line:   "<14>1 2024-01-02T03:04:05+09:00 - - - - - \ufeffCEF:0|V|P|1|ID|N|5|k=v",
+			want:   SyslogMessage{Facility: 1, Severity: 6, Message: "CEF:0|V|P|1|ID|N|5|k=v"},
+			wantTS: "2024-01-01T18:04:05Z",
+		},
+		{
+			name:   "rfc5424 no message",
+			line:   "<14>1 - host app - - -",
+			want:   SyslogMessage{Facility: 1, Severity: 6, Hostname: "host", AppName: "app"},
+			wantTS: "",
+		},
+		{
+			name:      "rfc3164 with tag and pid",
+			line:      "<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed",
+			want:      SyslogMessage{Facility: 4, Severity: 2, Hostname: "mymachine", AppName: "su", ProcID: "230", Message: "'su root' failed"},
+			wantStamp: "Oct 11 22:14:15",
+		},
+		{
+			name: "rfc3164 without header keeps cef body",
+			line: "<13>CEF:0|V|P|1|ID|N|5|a=b: c",
+			want: SyslogMessage{Facility: 1, Severity: 5, Message: "CEF:0|V|P|1|ID|N|5|a=b: c"},
+		},
+		{name: "no pri", line: "hello", wantErr: true},
+		{name: "pri out of range", line: "<192>1 - - - - - -", wantErr: true},
+		{name: "rfc5424 short header", line: "<14>1 - host", wantErr: true},
+		{name: "rfc5424 unterminated sd", line: `<14>1 - h a - - [x@1 k="v"`, wantErr: true},
+		{name: "rfc5424 bad sd", line: "<14>1 - h a - - x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseSyslog(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSyslog = %+v, want error", msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSyslog: %v", err)
+			}
+			got := *msg
+			ts := got.Timestamp
+			got.Timestamp = time.Time{}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if tt.wantTS != "" && ts.UTC().Format(time.RFC3339) != tt.wantTS {
+				t.Errorf("timestamp = %v, want %s", ts, tt.wantTS)
+			}
+			if tt.wantStamp != "" && ts.Format(time.Stamp) != tt.wantStamp {
+				t.Errorf("timestamp = %v, want %s", ts, tt.wantStamp)
+			}
+		})
+	}
+}
+
+// TestReadSyslogFrame: RFC 6587 octet-counting / LF 구분 프레임, 길이 접두어 상한
+func TestReadSyslogFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		want    []string // 순서대로 읽힐 프레임
+		wantErr bool     // 마지막 읽기가 EOF 이외의 오류
+	}{
+		{"octet counting", "5 hello3 abc", []string{"hello", "abc"}, false},
+		{"lf framing", "<13>a\n<13>b\n", []string{"<13>a\n", "<13>b\n"}, false},
+		{"mixed", "3 abc<13>x\n", []string{"abc", "<13>x\n"}, false},
+		{"count too long", "12345678901 x", nil, true},
+		{"count not digit", "12a x", nil, true},
+		{"count over max frame", "99999999 x", nil, true},
+		{"truncated frame", "10 abc", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tt.stream))
+			var got []string
+			var err error
+			for {
+				var frame []byte
+				frame, err = readSyslogFrame(reader)
+				if err != nil {
+					break
+				}
+				got = append(got, string(frame))
+			}
+			if tt.wantErr != (err != io.EOF) {
+				t.Errorf("last error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("frames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSyslogToEvent: CEF/LEEF 페이로드는 파싱, 그 외는 message 원문 이벤트
+func TestSyslogToEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		msg          SyslogMessage
+		wantMsgID    string
+		wantHost     string
+		wantAppName  string
+		wantTimeZero bool
+	}{
+		{
+			name:      "cef payload keeps its own hostname",
+			msg:       SyslogMessage{Hostname: "relay", AppName: "fwd", Message: "CEF:0|V|P|1|USB|N|5|dvchost=pc-1"},
+			wantMsgID: "USB", wantHost: "pc-1", wantAppName: "P", wantTimeZero: true,
+		},
+		{
+			name:      "plain message",
+			msg:       SyslogMessage{Hostname: "pc-2", AppName: "sshd", MsgID: "AUTH", Message: "login ok", Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			wantMsgID: "AUTH", wantHost: "pc-2", wantAppName: "sshd",
+		},
+		{
+			name:      "plain message without msgid",
+			msg:       SyslogMessage{Message: "hi"},
+			wantMsgID: "SYSLOG", wantHost: "", wantAppName: "", wantTimeZero: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := syslogToEvent(&tt.msg, "10.0.0.1:514", nil)
+			if err != nil {
+				t.Fatalf("syslogToEvent: %v", err)
+			}
+			host, _ := event["hostname"].(string)
+			if event["msgId"] != tt.wantMsgID || host != tt.wantHost || event["appName"] != tt.wantAppName {
+				t.Errorf("msgId=%v hostname=%v appName=%v, want %s/%s/%s", event["msgId"], host, event["appName"], tt.wantMsgID, tt.wantHost, tt.wantAppName)
+			}
+			if _, ok := event["@timestamp"]; ok == tt.wantTimeZero {
+				t.Errorf("@timestamp = %v, want set=%v", event["@timestamp"], !tt.wantTimeZero)
+			}
+			if sl, _ := event["syslog"].(map[string]interface{}); sl["peer"] != "10.0.0.1:514" {
+				t.Errorf("syslog = %v", event["syslog"])
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package logsink
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// defaultTimeFields: 이벤트 시각 후보 (앞에서부터 처음 해석되는 값 사용)
+// CEF rt(수신 시각) → start → end, 설정 필드 다음에는 기존 @timestamp(syslog 헤더 등)를 마지막 후보로 본다
+var defaultTimeFields = []string{"cefExtensions.rt", "cefExtensions.start", "cefExtensions.end"}
+
+// eventTimeLayouts: 문자열 시각 형식 (CEF 권장 형식 + 에이전트/DB 흔한 형식)
+// 타임존이 없는 형식은 설정 타임존 기준으로 해석
+var eventTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05",
+	"20060102150405",
+	"Jan 2 2006 15:04:05.000 MST",
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 2006 15:04:05.000",
+	"Jan 2 2006 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"02/Jan/2006:15:04:05 -0700",
+	time.Stamp,
+}
+
+// timeNormalizer: 이벤트 시각 추출 + 미래 시각(clock skew) 판정
+type timeNormalizer struct {
+	fields []string
+	skew   time.Duration
+}
+
+func newTimeNormalizer(extra []string, skew time.Duration) *timeNormalizer {
+	fields := append(append([]string{}, defaultTimeFields...), extra...)
+	return &timeNormalizer{fields: append(fields, "@timestamp"), skew: skew}
+}
+
+// Normalize: @timestamp를 이벤트 시각으로 설정하고 그 시각을 반환 (일별 인덱스 선택용)
+//
+//	ingestTime   LogSink 수신 시각
+//	timeSource   사용한 필드 (해석 가능한 값이 없으면 "ingest")
+//	futureEvent  수신 시각 + 허용 오차보다 미래인 경우 true, @timestamp는 수신 시각으로 대체하고 원본은 originalTime에 보관
+func (n *timeNormalizer) Normalize(event map[string]interface{}) time.Time {
+	now := common.Now()
+	event["ingestTime"] = now.Format(time.RFC3339)
+
+	for _, field := range n.fields {
+		v, ok := getPath(event, field)
+		if !ok {
+			continue
+		}
+		t, ok := parseEventTime(v, now)
+		if !ok {
+			continue
+		}
+		if n.skew > 0 && t.After(now.Add(n.skew)) {
+			event["futureEvent"] = true
+			event["originalTime"] = t.Format(time.RFC3339Nano)
+			break
+		}
+		event["@timestamp"] = t.Format(time.RFC3339Nano)
+		event["timeSource"] = field
+		return t
+	}
+	event["@timestamp"] = now.Format(time.RFC3339)
+	event["timeSource"] = "ingest"
+	return now
+}
+
+// parseEventTime: epoch(초/밀리초/마이크로초/나노초, 숫자 또는 숫자 문자열) 또는 문자열 시각
+func parseEventTime(v interface{}, now time.Time) (time.Time, bool) {
+	switch val := v.(type) {
+	case float64:
+		return epochTime(val)
+	case int64:
+		return epochTime(float64(val))
+	case int:
+		return epochTime(float64(val))
+	case string:
+		s := strings.TrimSpace(val)
+		if s == "" {
+			return time.Time{}, false
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "eE") && len(s) != len("20060102150405") {
+			return epochTime(f)
+		}
+		for _, layout := range eventTimeLayouts {
+			t, err := time.ParseInLocation(layout, s, now.Location())
+			if err != nil {
+				continue
+			}
+			if t.Year() == 0 {
+				// 연도 없는 형식(syslog Stamp): 올해 기준, 하루 이상 미래면 작년
+				t = t.AddDate(now.Year(), 0, 0)
+				if t.After(now.Add(24 * time.Hour)) {
+					t = t.AddDate(-1, 0, 0)
+				}
+			}
+			return t.In(now.Location()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// epochTime: 자릿수로 단위 추정 (1e11 미만 초, 1e14 미만 밀리초, 1e17 미만 마이크로초, 이상은 나노초)
+func epochTime(f float64) (time.Time, bool) {
+	if f <= 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false
+	}
+	var t time.Time
+	switch {
+	case f < 1e11:
+		sec, frac := math.Modf(f)
+		t = time.Unix(int64(sec), int64(frac*1e9))
+	case f < 1e14:
+		t = time.UnixMilli(int64(f))
+	case f < 1e17:
+		t = time.UnixMicro(int64(f))
+	default:
+		t = time.Unix(0, int64(f))
+	}
+	return t.In(common.Now().Location()), true
+}
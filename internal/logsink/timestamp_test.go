@@ -0,0 +1,115 @@
+package logsink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// TestParseEventTime: epoch 단위 추정과 문자열 형식 (타임존 없는 형식은 설정 타임존)
+func TestParseEventTime(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, common.Now().Location())
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string // UTC RFC3339Nano, 빈값이면 해석 불가
+	}{
+		{"epoch seconds", float64(1704153600), "2024-01-02T00:00:00Z"},
+		{"epoch seconds fraction", 1704153600.5, "2024-01-02T00:00:00.5Z"},
+		{"epoch millis string", "1704153600123", "2024-01-02T00:00:00.123Z"},
+		{"epoch micros int64", int64(1704153600000001), "2024-01-02T00:00:00.000001Z"},
+		{"epoch nanos", "1704153600000000000", "2024-01-02T00:00:00Z"},
+		{"rfc3339", "2024-01-01T23:30:00+09:00", "2024-01-01T14:30:00Z"},
+		{"no zone uses configured zone", "2024-01-01 23:30:00", "2024-01-01T14:30:00Z"},
+		{"compact digits are not epoch", "20240101233000", "2024-01-01T14:30:00Z"},
+		{"cef format", "Jan 1 2024 23:30:00.250", "2024-01-01T14:30:00.25Z"},
+		{"apache format", "01/Jan/2024:23:30:00 +0000", "2024-01-01T23:30:00Z"},
+		{"stamp this year", "Jan  2 08:00:00", "2024-01-01T23:00:00Z"},
+		{"stamp in future is last year", "Dec 31 23:00:00", "2023-12-31T14:00:00Z"},
+		{"zero", float64(0), ""},
+		{"empty", "  ", ""},
+		{"garbage", "yesterday", ""},
+		{"bool", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseEventTime(tt.value, now)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("parseEventTime(%v) = %v, want not parsed", tt.value, got)
+				}
+				return
+			}
+			if !ok || got.UTC().Format(time.RFC3339Nano) != tt.want {
+				t.Errorf("parseEventTime(%v) = %v (%v), want %s", tt.value, got.UTC(), ok, tt.want)
+			}
+		})
+	}
+}
+
+// TestTimeNormalizer: 후보 필드 순서, 미래 시각 대체, 수신 시각 fallback
+func TestTimeNormalizer(t *testing.T) {
+	common.InitTimezone("Asia/Seoul")
+	future := common.Now().Add(time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name       string
+		event      map[string]interface{}
+		wantSource string
+		wantTS     string // 빈값이면 수신 시각
+		wantFuture bool
+	}{
+		{
+			name:       "rt before start",
+			event:      map[string]interface{}{"cefExtensions": map[string]interface{}{"rt": "1704153600000", "start": "1700000000000"}},
+			wantSource: "cefExtensions.rt", wantTS: "2024-01-02T09:00:00+09:00",
+		},
+		{
+			name:       "unparsable rt falls through to start",
+			event:      map[string]interface{}{"cefExtensions": map[string]interface{}{"rt": "n/a", "start": "2024-01-01T00:00:00Z"}},
+			wantSource: "cefExtensions.start", wantTS: "2024-01-01T09:00:00+09:00",
+		},
+		{
+			name:       "extra field before @timestamp",
+			event:      map[string]interface{}{"logTime": "2024-01-03 10:00:00", "@timestamp": "2024-01-01T00:00:00Z"},
+			wantSource: "logTime", wantTS: "2024-01-03T10:00:00+09:00",
+		},
+		{
+			name:       "existing @timestamp last",
+			event:      map[string]interface{}{"@timestamp": "2024-01-01T00:00:00Z"},
+			wantSource: "@timestamp", wantTS: "2024-01-01T09:00:00+09:00",
+		},
+		{
+			name:       "future event uses ingest time",
+			event:      map[string]interface{}{"cefExtensions": map[string]interface{}{"rt": future}},
+			wantSource: "ingest", wantFuture: true,
+		},
+		{
+			name:       "no time fields",
+			event:      map[string]interface{}{"msgId": "USB"},
+			wantSource: "ingest",
+		},
+	}
+	n := newTimeNormalizer([]string{"logTime"}, 5*time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := n.Normalize(tt.event)
+			if tt.event["timeSource"] != tt.wantSource {
+				t.Errorf("timeSource = %v, want %s", tt.event["timeSource"], tt.wantSource)
+			}
+			if tt.wantTS != "" && tt.event["@timestamp"] != tt.wantTS {
+				t.Errorf("@timestamp = %v, want %s", tt.event["@timestamp"], tt.wantTS)
+			}
+			if tt.wantTS == "" && time.Since(got) > time.Minute {
+				t.Errorf("returned %v, want ingest time", got)
+			}
+			if future, _ := tt.event["futureEvent"].(bool); future != tt.wantFuture {
+				t.Errorf("futureEvent = %v, want %v", future, tt.wantFuture)
+			}
+			if _, ok := tt.event["originalTime"]; ok != tt.wantFuture {
+				t.Errorf("originalTime = %v, want set=%v", tt.event["originalTime"], tt.wantFuture)
+			}
+		})
+	}
+}
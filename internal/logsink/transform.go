@@ -0,0 +1,347 @@
+package logsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// pipelineDocID: settings 인덱스 내 변환 파이프라인 문서 ID
+const pipelineDocID = "logsink_pipeline"
+
+// pipelineAllTopics: 모든 원본 토픽에 적용되는 단계 키 (토픽별 단계보다 먼저 실행)
+const pipelineAllTopics = "*"
+
+// TransformStep: 변환 단계 1개
+//
+//	rename  from → to 로 이동
+//	drop    fields 삭제
+//	cast    field 를 type(int/float/bool/string)으로 변환
+//	default field 가 없거나 빈 문자열이면 value 설정
+//	derive  field = from 값의 regex 캡처(group, 기본 1) 또는 template("${a.b}-${c}") 치환
+//
+// 필드 경로는 점 표기 (예: cefExtensions.suid)
+type TransformStep struct {
+	Op       string      `json:"op"`
+	Field    string      `json:"field,omitempty"`
+	From     string      `json:"from,omitempty"`
+	To       string      `json:"to,omitempty"`
+	Fields   []string    `json:"fields,omitempty"`
+	Type     string      `json:"type,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	Regex    string      `json:"regex,omitempty"`
+	Group    int         `json:"group,omitempty"`
+	Template string      `json:"template,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// PipelineConfig: 원본 토픽 → 변환 단계 목록 ("*"는 전체 토픽 공통)
+type PipelineConfig struct {
+	Topics map[string][]TransformStep `json:"topics"`
+}
+
+var templateVar = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// compile: 단계 검증 + 정규식 컴파일
+func (p *PipelineConfig) compile() error {
+	for topic, steps := range p.Topics {
+		for i := range steps {
+			st := &steps[i]
+			where := fmt.Sprintf("%s[%d]", topic, i)
+			switch st.Op {
+			case "rename":
+				if st.From == "" || st.To == "" {
+					return fmt.Errorf("%s: rename에 from/to 필수", where)
+				}
+			case "drop":
+				if len(st.Fields) == 0 {
+					return fmt.Errorf("%s: drop에 fields 필수", where)
+				}
+			case "cast":
+				if st.Field == "" {
+					return fmt.Errorf("%s: cast에 field 필수", where)
+				}
+				if st.Type != "int" && st.Type != "float" && st.Type != "bool" && st.Type != "string" {
+					return fmt.Errorf("%s: 잘못된 cast type '%s' (int/float/bool/string)", where, st.Type)
+				}
+			case "default":
+				if st.Field == "" || st.Value == nil {
+					return fmt.Errorf("%s: default에 field/value 필수", where)
+				}
+			case "derive":
+				if st.Field == "" {
+					return fmt.Errorf("%s: derive에 field 필수", where)
+				}
+				switch {
+				case st.Regex != "":
+					if st.From == "" {
+						return fmt.Errorf("%s: regex derive에 from 필수", where)
+					}
+					re, err := regexp.Compile(st.Regex)
+					if err != nil {
+						return fmt.Errorf("%s: 정규식 오류: %v", where, err)
+					}
+					if st.Group == 0 {
+						st.Group = 1
+					}
+					if st.Group > re.NumSubexp() {
+						return fmt.Errorf("%s: group %d 없음 (캡처 %d개)", where, st.Group, re.NumSubexp())
+					}
+					st.re = re
+				case st.Template == "":
+					return fmt.Errorf("%s: derive에 regex 또는 template 필수", where)
+				}
+			default:
+				return fmt.Errorf("%s: 잘못된 op '%s'", where, st.Op)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply: 공통("*") → 토픽별 순으로 단계 적용. 단계 오류는 건너뛰고 목록으로 반환 (이벤트는 계속 처리)
+func (p *PipelineConfig) Apply(topic string, event map[string]interface{}) []string {
+	if p == nil {
+		return nil
+	}
+	var errs []string
+	for _, key := range []string{pipelineAllTopics, topic} {
+		for i, st := range p.Topics[key] {
+			if err := st.apply(event); err != nil {
+				errs = append(errs, fmt.Sprintf("%s[%d] %s: %v", key, i, st.Op, err))
+			}
+		}
+	}
+	return errs
+}
+
+func (st *TransformStep) apply(event map[string]interface{}) error {
+	switch st.Op {
+	case "rename":
+		if v, ok := getPath(event, st.From); ok {
+			deletePath(event, st.From)
+			setPath(event, st.To, v)
+		}
+	case "drop":
+		for _, f := range st.Fields {
+			deletePath(event, f)
+		}
+	case "cast":
+		v, ok := getPath(event, st.Field)
+		if !ok {
+			return nil
+		}
+		cv, err := castValue(v, st.Type)
+		if err != nil {
+			return err
+		}
+		setPath(event, st.Field, cv)
+	case "default":
+		if v, ok := getPath(event, st.Field); !ok || v == nil || v == "" {
+			setPath(event, st.Field, st.Value)
+		}
+	case "derive":
+		if st.re != nil {
+			v, ok := getPath(event, st.From)
+			if !ok {
+				return nil
+			}
+			m := st.re.FindStringSubmatch(fmt.Sprintf("%v", v))
+			if m == nil {
+				return nil
+			}
+			setPath(event, st.Field, m[st.Group])
+			return nil
+		}
+		setPath(event, st.Field, templateVar.ReplaceAllStringFunc(st.Template, func(s string) string {
+			v, ok := getPath(event, templateVar.FindStringSubmatch(s)[1])
+			if !ok || v == nil {
+				return ""
+			}
+			return fmt.Sprintf("%v", v)
+		}))
+	}
+	return nil
+}
+
+func castValue(v interface{}, typ string) (interface{}, error) {
+	s := strings.TrimSpace(fmt.Sprintf("%v", v))
+	switch typ {
+	case "int":
+		if f, ok := v.(float64); ok {
+			return int64(f), nil
+		}
+		return strconv.ParseInt(s, 10, 64)
+	case "float":
+		return strconv.ParseFloat(s, 64)
+	case "bool":
+		return strconv.ParseBool(s)
+	case "string":
+		if f, ok := v.(float64); ok {
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return s, nil
+	}
+	return v, nil
+}
+
+// getPath/setPath/deletePath: 점 표기 경로로 중첩 map 접근
+func getPath(m map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	cur := m
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	v, ok := cur[parts[len(parts)-1]]
+	return v, ok
+}
+
+func setPath(m map[string]interface{}, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	cur := m
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			cur[p] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = v
+}
+
+func deletePath(m map[string]interface{}, path string) {
+	parts := strings.Split(path, ".")
+	cur := m
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]interface{})
+		if !ok {
+			return
+		}
+		cur = next
+	}
+	delete(cur, parts[len(parts)-1])
+}
+
+// transformer: 현재 파이프라인 보관 + settings 인덱스 주기적 리로드
+// settings 문서가 있으면 우선, 없으면 LOGSINK_PIPELINE_FILE 사용
+type transformer struct {
+	os      common.Store
+	index   string
+	file    string
+	current atomic.Pointer[PipelineConfig]
+
+	mu      sync.Mutex
+	version string // 마지막으로 적용한 settings 문서 updatedAt
+}
+
+func newTransformer(os common.Store, prefix, file string) *transformer {
+	t := &transformer{os: os, index: common.SettingsIndex(prefix), file: file}
+	if file != "" {
+		if p, err := loadPipelineFile(file); err != nil {
+			log.Printf("[LogSink] 파이프라인 파일 로드 실패 (%s): %v", file, err)
+		} else {
+			t.current.Store(p)
+			log.Printf("[LogSink] 파이프라인 파일 로드: %s (%d개 토픽)", file, len(p.Topics))
+		}
+	}
+	t.reload()
+	return t
+}
+
+func loadPipelineFile(path string) (*PipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p PipelineConfig
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (t *transformer) Get() *PipelineConfig {
+	return t.current.Load()
+}
+
+// reload: settings 문서가 바뀐 경우만 재컴파일 (문서는 매핑 충돌 방지를 위해 JSON 문자열로 저장)
+func (t *transformer) reload() {
+	doc, err := t.os.Get(t.index, pipelineDocID)
+	if err != nil || doc == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	version, _ := doc["updatedAt"].(string)
+	if version == t.version {
+		return
+	}
+	raw, _ := doc["pipeline"].(string)
+	var p PipelineConfig
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		log.Printf("[LogSink] 파이프라인 문서 파싱 실패: %v", err)
+		return
+	}
+	if err := p.compile(); err != nil {
+		log.Printf("[LogSink] 파이프라인 문서 검증 실패: %v", err)
+		return
+	}
+	t.current.Store(&p)
+	t.version = version
+	log.Printf("[LogSink] 파이프라인 갱신 (%s, %d개 토픽)", version, len(p.Topics))
+}
+
+// Save: 검증 후 settings 인덱스 저장 + 즉시 적용
+func (t *transformer) Save(p *PipelineConfig) error {
+	if err := p.compile(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	raw, _ := json.Marshal(p)
+	version := common.Now().Format(time.RFC3339Nano)
+	if err := t.os.Put(t.index, pipelineDocID, map[string]interface{}{
+		"pipeline":  string(raw),
+		"updatedAt": version,
+	}); err != nil {
+		return err
+	}
+	t.current.Store(p)
+	t.version = version
+	return nil
+}
+
+// Run: interval마다 settings 문서/파일 재로드 (0 이하면 재로드 없음)
+func (t *transformer) Run(done <-chan struct{}, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			t.reload()
+		}
+	}
+}
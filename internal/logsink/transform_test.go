@@ -0,0 +1,172 @@
+package logsink
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// parseEvent: 테스트용 JSON → 이벤트 (숫자는 실제 입력처럼 float64)
+func parseEvent(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &event); err != nil {
+		t.Fatalf("이벤트 파싱 실패: %v", err)
+	}
+	return event
+}
+
+// TestPipelineApply: 단계별 변환 결과와 공통("*") → 토픽 순서
+func TestPipelineApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    map[string][]TransformStep
+		topic    string
+		event    string
+		want     string
+		wantErrs int
+	}{
+		{
+			name:  "rename nested",
+			steps: map[string][]TransformStep{"T": {{Op: "rename", From: "cefExtensions.suid", To: "userId"}}},
+			topic: "T", event: `{"cefExtensions":{"suid":"u1","x":1}}`,
+			want: `{"cefExtensions":{"x":1},"userId":"u1"}`,
+		},
+		{
+			name:  "rename missing field is no-op",
+			steps: map[string][]TransformStep{"T": {{Op: "rename", From: "a", To: "b"}}},
+			topic: "T", event: `{"c":1}`, want: `{"c":1}`,
+		},
+		{
+			name:  "drop",
+			steps: map[string][]TransformStep{"T": {{Op: "drop", Fields: []string{"a", "n.b", "missing.x"}}}},
+			topic: "T", event: `{"a":1,"n":{"b":2,"c":3}}`, want: `{"n":{"c":3}}`,
+		},
+		{
+			name: "cast",
+			steps: map[string][]TransformStep{"T": {
+				{Op: "cast", Field: "i", Type: "int"}, {Op: "cast", Field: "f", Type: "float"},
+				{Op: "cast", Field: "b", Type: "bool"}, {Op: "cast", Field: "s", Type: "string"},
+			}},
+			topic: "T", event: `{"i":"42","f":" 1.5 ","b":"true","s":3}`, want: `{"i":42,"f":1.5,"b":true,"s":"3"}`,
+		},
+		{
+			name:  "cast error keeps value and is reported",
+			steps: map[string][]TransformStep{"T": {{Op: "cast", Field: "i", Type: "int"}, {Op: "default", Field: "d", Value: "x"}}},
+			topic: "T", event: `{"i":"abc"}`, want: `{"i":"abc","d":"x"}`, wantErrs: 1,
+		},
+		{
+			name:  "default only when missing or empty",
+			steps: map[string][]TransformStep{"T": {{Op: "default", Field: "a", Value: "A"}, {Op: "default", Field: "b", Value: "B"}, {Op: "default", Field: "c", Value: "C"}}},
+			topic: "T", event: `{"a":"","b":"keep"}`, want: `{"a":"A","b":"keep","c":"C"}`,
+		},
+		{
+			name:  "derive regex",
+			steps: map[string][]TransformStep{"T": {{Op: "derive", Field: "domain", From: "email", Regex: `@(.+)$`}}},
+			topic: "T", event: `{"email":"u1@corp.local"}`, want: `{"email":"u1@corp.local","domain":"corp.local"}`,
+		},
+		{
+			name:  "derive regex no match",
+			steps: map[string][]TransformStep{"T": {{Op: "derive", Field: "domain", From: "email", Regex: `@(.+)$`}}},
+			topic: "T", event: `{"email":"nobody"}`, want: `{"email":"nobody"}`,
+		},
+		{
+			name:  "derive template with missing var",
+			steps: map[string][]TransformStep{"T": {{Op: "derive", Field: "key", Template: "${hostname}-${cefExtensions.suid}-${none}"}}},
+			topic: "T", event: `{"hostname":"pc-1","cefExtensions":{"suid":"u1"}}`, want: `{"hostname":"pc-1","cefExtensions":{"suid":"u1"},"key":"pc-1-u1-"}`,
+		},
+		{
+			name: "common steps run before topic steps",
+			steps: map[string][]TransformStep{
+				"*": {{Op: "rename", From: "user", To: "userId"}},
+				"T": {{Op: "derive", Field: "who", Template: "${userId}"}},
+			},
+			topic: "T", event: `{"user":"u1"}`, want: `{"userId":"u1","who":"u1"}`,
+		},
+		{
+			name:  "other topic untouched",
+			steps: map[string][]TransformStep{"T": {{Op: "drop", Fields: []string{"a"}}}},
+			topic: "U", event: `{"a":1}`, want: `{"a":1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PipelineConfig{Topics: tt.steps}
+			if err := p.compile(); err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			event := parseEvent(t, tt.event)
+			errs := p.Apply(tt.topic, event)
+			// 비교는 JSON 왕복 (cast 결과 int64 등 타입 차이 제거)
+			if got, want := copyEvent(event), parseEvent(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("event = %v, want %v", got, want)
+			}
+			if len(errs) != tt.wantErrs {
+				t.Errorf("errors = %v, want %d", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+// TestPipelineCompileErrors: 잘못된 단계는 저장/로드 시점에 거부
+func TestPipelineCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		step TransformStep
+	}{
+		{"unknown op", TransformStep{Op: "upper", Field: "a"}},
+		{"rename without to", TransformStep{Op: "rename", From: "a"}},
+		{"drop without fields", TransformStep{Op: "drop"}},
+		{"cast bad type", TransformStep{Op: "cast", Field: "a", Type: "date"}},
+		{"default without value", TransformStep{Op: "default", Field: "a"}},
+		{"derive without source", TransformStep{Op: "derive", Field: "a"}},
+		{"derive regex without from", TransformStep{Op: "derive", Field: "a", Regex: "(x)"}},
+		{"derive bad regex", TransformStep{Op: "derive", Field: "a", From: "b", Regex: "("}},
+		{"derive missing group", TransformStep{Op: "derive", Field: "a", From: "b", Regex: "(x)", Group: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PipelineConfig{Topics: map[string][]TransformStep{"T": {tt.step}}}
+			if err := p.compile(); err == nil {
+				t.Errorf("compile(%+v) = nil, want error", tt.step)
+			}
+		})
+	}
+}
+
+// TestTransformerSaveReload: settings 문서 저장은 다른 인스턴스의 reload에 반영, 재로드 주기 0이면 Run 즉시 반환
+func TestTransformerSaveReload(t *testing.T) {
+	store := common.NewMemoryStore()
+	writer := newTransformer(store, "test", "")
+	reader := newTransformer(store, "test", "")
+	if reader.Get() != nil {
+		t.Fatalf("pipeline before save = %v, want nil", reader.Get())
+	}
+
+	p := &PipelineConfig{Topics: map[string][]TransformStep{"T": {{Op: "derive", Field: "d", From: "a", Regex: "(x+)"}}}}
+	if err := writer.Save(p); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	reader.reload()
+	event := map[string]interface{}{"a": "axxb"}
+	reader.Get().Apply("T", event)
+	if event["d"] != "xx" {
+		t.Errorf("reloaded pipeline result = %v, want d=xx", event)
+	}
+
+	done := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		reader.Run(done, 0)
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		close(done)
+		t.Fatal("Run(0) did not return")
+	}
+}
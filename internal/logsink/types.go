@@ -0,0 +1,96 @@
+package logsink
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// typer: cefExtensions 값을 타입 사전에 따라 숫자로 변환
+//
+//	표준 키 (cn1~3, fsize, in, out, spt 등)      → common.CEFFieldType
+//	label 확장 이름 (cn1Label="File Count" → FileCount) → 원본 키 타입
+//	field-meta inputType=number 필드             → double
+//
+// 문자열 사본 "<key>Str"은 필요할 때만 남긴다:
+// 변환 불가 값은 숫자 매핑을 오염시키지 않도록 키를 옮기고, 앞자리 0 등 표기가 바뀌는 값은 원본도 보관한다.
+type typer struct {
+	os        common.Store
+	prefix    string
+	metaTypes atomic.Pointer[map[string]string]
+}
+
+func newTyper(os common.Store, prefix string) *typer {
+	t := &typer{os: os, prefix: prefix}
+	t.reload()
+	return t
+}
+
+// reload: field-meta 숫자 필드 갱신 (실패 시 이전 값 유지)
+func (t *typer) reload() {
+	types, err := common.FieldMetaTypes(t.os, t.prefix)
+	if err != nil {
+		log.Printf("[LogSink] field-meta 타입 로드 실패: %v", err)
+		return
+	}
+	if prev := t.metaTypes.Load(); prev == nil || len(*prev) != len(types) {
+		log.Printf("[LogSink] field-meta 숫자 필드 %d개", len(types))
+	}
+	t.metaTypes.Store(&types)
+}
+
+// Run: interval마다 field-meta 타입 재로드 (0 이하면 재로드 없음)
+func (t *typer) Run(done <-chan struct{}, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			t.reload()
+		}
+	}
+}
+
+// Apply: 이벤트의 cefExtensions 값 타입 변환
+func (t *typer) Apply(event map[string]interface{}) {
+	ext, ok := event["cefExtensions"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	labelTypes := common.CEFLabelTypes(ext)
+	var metaTypes map[string]string
+	if p := t.metaTypes.Load(); p != nil {
+		metaTypes = *p
+	}
+
+	for k, v := range ext {
+		s, isString := v.(string)
+		if !isString {
+			continue
+		}
+		typ := common.CEFValueType(k, labelTypes, metaTypes)
+		if typ == "" {
+			continue
+		}
+		num, canonical, ok := common.ParseCEFNumber(s, typ)
+		if !ok {
+			// 빈 문자열은 값 없음으로 처리
+			delete(ext, k)
+			if s != "" {
+				ext[k+"Str"] = s
+			}
+			continue
+		}
+		ext[k] = num
+		if !canonical {
+			ext[k+"Str"] = s
+		}
+	}
+}
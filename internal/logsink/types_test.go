@@ -0,0 +1,113 @@
+package logsink
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/markany/safepc-siem/internal/common"
+	"github.com/markany/safepc-siem/internal/ostest"
+)
+
+// TestTyperApply: 표준 키/label 확장 이름/field-meta 숫자 필드 변환과 "<key>Str" 보존 규칙
+func TestTyperApply(t *testing.T) {
+	store := common.NewMemoryStore()
+	if err := store.Put(common.FieldMetaIndex("test"), "meta-latest", map[string]interface{}{
+		"events": map[string]interface{}{
+			"MESSAGE_DEVICE": map[string]interface{}{"fields": map[string]interface{}{
+				"ratio":   map[string]interface{}{"inputType": "number"},
+				"hour":    map[string]interface{}{"inputType": "number", "virtual": true},
+				"comment": map[string]interface{}{"inputType": "text"},
+			}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	typer := newTyper(store, "test")
+
+	tests := []struct {
+		name string
+		ext  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "standard keys",
+			ext:  map[string]interface{}{"fsize": "1024", "cfp1": "1.5", "suid": "007"},
+			want: map[string]interface{}{"fsize": int64(1024), "cfp1": 1.5, "suid": "007"},
+		},
+		{
+			name: "non canonical keeps string copy",
+			ext:  map[string]interface{}{"dpt": "0080", "cn1": "12.0"},
+			want: map[string]interface{}{"dpt": int64(80), "dptStr": "0080", "cn1": int64(12), "cn1Str": "12.0"},
+		},
+		{
+			name: "unparsable moves to Str, empty removed",
+			ext:  map[string]interface{}{"fsize": "big", "cn2": ""},
+			want: map[string]interface{}{"fsizeStr": "big"},
+		},
+		{
+			name: "label expanded name",
+			ext:  map[string]interface{}{"cn1Label": "File Count", "FileCount": "3"},
+			want: map[string]interface{}{"cn1Label": "File Count", "FileCount": int64(3)},
+		},
+		{
+			name: "field-meta number as double, virtual and text ignored",
+			ext:  map[string]interface{}{"ratio": "3", "hour": "10", "comment": "42"},
+			want: map[string]interface{}{"ratio": 3.0, "hour": "10", "comment": "42"},
+		},
+		{
+			name: "non string values untouched",
+			ext:  map[string]interface{}{"fsize": 10.0},
+			want: map[string]interface{}{"fsize": 10.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := map[string]interface{}{"cefExtensions": tt.ext}
+			typer.Apply(event)
+			if !reflect.DeepEqual(tt.ext, tt.want) {
+				t.Errorf("cefExtensions = %#v, want %#v", tt.ext, tt.want)
+			}
+		})
+	}
+}
+
+// TestTypedValuesMapping: field-meta double 필드의 첫 값이 정수로 직렬화돼도 event-logs 동적 매핑은 double
+func TestTypedValuesMapping(t *testing.T) {
+	srv := ostest.NewServer()
+	defer srv.Close()
+	client := srv.OSClient()
+	if _, err := common.Migrate(client, "test", common.MigrateOptions{Family: "event-logs"}); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	index := common.DailyLogsIndex("test", "2024.01.01")
+	writer := common.NewBulkWriter(client, common.BulkOptions{Name: "test"})
+	for _, v := range []float64{3, 2.5} {
+		writer.Add(index, "", map[string]interface{}{"msgId": "USB", "cefExtensions": map[string]interface{}{"ratio": v, "fsize": int64(v)}})
+	}
+	writer.Flush(context.Background())
+
+	result, err := client.GetMapping(index)
+	if err != nil {
+		t.Fatalf("GetMapping: %v", err)
+	}
+	entry, _ := result[index].(map[string]interface{})
+	mappings, _ := entry["mappings"].(map[string]interface{})
+	props, _ := mappings["properties"].(map[string]interface{})
+	ext, _ := props["cefExtensions"].(map[string]interface{})
+	extProps, _ := ext["properties"].(map[string]interface{})
+	typeOf := func(field string) interface{} {
+		def, _ := extProps[field].(map[string]interface{})
+		return def["type"]
+	}
+	if got := typeOf("ratio"); got != "double" {
+		t.Errorf("cefExtensions.ratio type = %v, want double", got)
+	}
+	if got := typeOf("fsize"); got != "long" {
+		t.Errorf("cefExtensions.fsize type = %v, want long (standard key)", got)
+	}
+	if n := len(srv.Docs(index)); n != 2 {
+		t.Errorf("docs = %d, want 2", n)
+	}
+}
@@ -0,0 +1,246 @@
+package logsink
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// 수집량 상태
+const (
+	VolumeLearning = "learning" // 기대치 학습 중 (경보 없음)
+	VolumeNormal   = "normal"
+	VolumeSilent   = "silent" // 기대 유입이 있는데 연속 구간 0건
+	VolumeSpike    = "spike"  // 기대치 × 배수 초과
+)
+
+// 가끔만 보내는 호스트가 영구히 silent로 남지 않도록 이 기간 동안 안 보이면 추적 중단
+const volumeForget = 7 * 24 * time.Hour
+
+// VolumeSource: 출처(토픽/msgId/호스트) 1개의 구간별 유입량과 학습된 기대치
+type VolumeSource struct {
+	Dimension string    `json:"dimension"` // topic / msgId / host
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Expected  float64   `json:"expected"`  // 구간당 기대 건수 (EWMA)
+	LastCount int64     `json:"lastCount"` // 직전 구간 건수
+	Samples   int       `json:"samples"`
+	ZeroRuns  int       `json:"zeroRuns"` // 연속 0건 구간 수
+	LastSeen  time.Time `json:"lastSeen"`
+	Since     time.Time `json:"since"` // 현재 상태 시작 시각
+
+	current int64
+}
+
+// VolumeOptions: 수집량 감시 설정
+type VolumeOptions struct {
+	Interval    time.Duration // 집계 구간
+	Window      int           // 기대치 EWMA 창 (구간 수)
+	SpikeFactor float64       // 기대치 대비 급증 배수
+	SilentAfter int           // 연속 0건 구간 수
+	MinRate     float64       // 기대치가 이 값 미만이면 경보 없음 (간헐적 출처 잡음 방지)
+}
+
+// volumeMonitor: 출처별 유입량 추적 → 상태 변화 시 health 토픽 발행
+type volumeMonitor struct {
+	opts     VolumeOptions
+	alpha    float64
+	producer sarama.SyncProducer
+	topic    string // health 이벤트 토픽 (빈값이면 로그만)
+
+	mu      sync.Mutex
+	sources map[string]*VolumeSource // "dimension:name"
+}
+
+func newVolumeMonitor(producer sarama.SyncProducer, topic string, opts VolumeOptions) *volumeMonitor {
+	if opts.Window < 1 {
+		opts.Window = 1
+	}
+	return &volumeMonitor{
+		opts:     opts,
+		alpha:    2 / float64(opts.Window+1),
+		producer: producer,
+		topic:    topic,
+		sources:  make(map[string]*VolumeSource),
+	}
+}
+
+// Record: 이벤트 1건 유입 (토픽/msgId/호스트별 카운트)
+func (m *volumeMonitor) Record(topic string, event map[string]interface{}) {
+	if m.opts.Interval <= 0 {
+		return
+	}
+	msgID, _ := event["msgId"].(string)
+	host, _ := event["hostname"].(string)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.count("topic", topic, now)
+	if msgID != "" {
+		m.count("msgId", msgID, now)
+	}
+	if host != "" {
+		m.count("host", host, now)
+	}
+}
+
+func (m *volumeMonitor) count(dimension, name string, now time.Time) {
+	key := dimension + ":" + name
+	src := m.sources[key]
+	if src == nil {
+		src = &VolumeSource{Dimension: dimension, Name: name, State: VolumeLearning, Since: now}
+		m.sources[key] = src
+	}
+	src.current++
+	src.LastSeen = now
+}
+
+// Run: 구간마다 상태 평가 (Interval 0 이하면 감시 비활성)
+func (m *volumeMonitor) Run(done <-chan struct{}) {
+	if m.opts.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, ev := range m.evaluate(time.Now()) {
+				m.emit(ev)
+			}
+		}
+	}
+}
+
+// evaluate: 구간 마감 → 기대치 갱신 + 상태 전이 목록
+func (m *volumeMonitor) evaluate(now time.Time) []map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []map[string]interface{}
+	for key, src := range m.sources {
+		count := src.current
+		src.current = 0
+		src.LastCount = count
+		if now.Sub(src.LastSeen) > volumeForget {
+			delete(m.sources, key)
+			continue
+		}
+		if count == 0 {
+			src.ZeroRuns++
+		} else {
+			src.ZeroRuns = 0
+		}
+
+		prev := src.State
+		next := m.classify(src, count)
+		// silent 동안은 기대치를 고정 (0건이 기대치를 끌어내려 경보가 스스로 풀리지 않도록)
+		if next != VolumeSilent {
+			if src.Samples == 0 {
+				src.Expected = float64(count)
+			} else {
+				src.Expected += m.alpha * (float64(count) - src.Expected)
+			}
+			src.Samples++
+		}
+		if next == prev {
+			continue
+		}
+		src.State = next
+		src.Since = now
+		if prev == VolumeLearning && next == VolumeNormal {
+			continue
+		}
+		events = append(events, volumeHealthEvent(src, prev, m.opts.SpikeFactor))
+	}
+	return events
+}
+
+func (m *volumeMonitor) classify(src *VolumeSource, count int64) string {
+	if src.Samples < m.opts.Window/2 && src.State == VolumeLearning {
+		return VolumeLearning
+	}
+	if src.Expected < m.opts.MinRate {
+		if src.State == VolumeSilent && count == 0 {
+			return VolumeSilent
+		}
+		return VolumeNormal
+	}
+	switch {
+	case src.ZeroRuns >= m.opts.SilentAfter:
+		return VolumeSilent
+	case float64(count) > src.Expected*m.opts.SpikeFactor:
+		return VolumeSpike
+	case src.State == VolumeSilent && count == 0:
+		return VolumeSilent
+	}
+	return VolumeNormal
+}
+
+func volumeHealthEvent(src *VolumeSource, prev string, factor float64) map[string]interface{} {
+	return map[string]interface{}{
+		"@timestamp":    common.Now().Format(time.RFC3339),
+		"type":          "ingest-volume",
+		"dimension":     src.Dimension,
+		"source":        src.Name,
+		"state":         src.State,
+		"previousState": prev,
+		"count":         src.LastCount,
+		"expected":      src.Expected,
+		"spikeFactor":   factor,
+		"zeroIntervals": src.ZeroRuns,
+		"lastSeen":      src.LastSeen.In(common.Now().Location()).Format(time.RFC3339),
+	}
+}
+
+// emit: health 토픽 발행 (실패 시 로그만)
+func (m *volumeMonitor) emit(ev map[string]interface{}) {
+	log.Printf("[LogSink] 수집량 %s: %s %s (%v → %v, 직전 구간 %d건, 기대 %.1f건)",
+		ev["state"], ev["dimension"], ev["source"], ev["previousState"], ev["state"], ev["count"], ev["expected"])
+	if m.topic == "" {
+		return
+	}
+	data, _ := json.Marshal(ev)
+	source, _ := ev["source"].(string)
+	if _, _, err := m.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: m.topic,
+		Key:   sarama.StringEncoder(source),
+		Value: sarama.ByteEncoder(data),
+	}); err != nil {
+		log.Printf("[LogSink] health 이벤트 발행 실패: %v", err)
+	}
+}
+
+// Status: 출처 목록 (dimension/state 필터, 이상 상태 우선 정렬)
+func (m *volumeMonitor) Status(dimension, state string) []VolumeSource {
+	m.mu.Lock()
+	list := make([]VolumeSource, 0, len(m.sources))
+	for _, src := range m.sources {
+		if (dimension != "" && src.Dimension != dimension) || (state != "" && src.State != state) {
+			continue
+		}
+		list = append(list, *src)
+	}
+	m.mu.Unlock()
+
+	rank := map[string]int{VolumeSilent: 0, VolumeSpike: 1, VolumeNormal: 2, VolumeLearning: 3}
+	sort.Slice(list, func(i, j int) bool {
+		if rank[list[i].State] != rank[list[j].State] {
+			return rank[list[i].State] < rank[list[j].State]
+		}
+		if list[i].Dimension != list[j].Dimension {
+			return list[i].Dimension < list[j].Dimension
+		}
+		return strings.Compare(list[i].Name, list[j].Name) < 0
+	})
+	return list
+}
@@ -0,0 +1,139 @@
+package logsink
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestVolumeMonitorEvaluate: 구간별 건수 → 학습/정상/급증/무유입 상태 전이와 health 이벤트
+func TestVolumeMonitorEvaluate(t *testing.T) {
+	// Window 4 → 2구간 학습, alpha 0.4
+	opts := VolumeOptions{Interval: time.Minute, Window: 4, SpikeFactor: 3, SilentAfter: 2, MinRate: 1}
+	tests := []struct {
+		name       string
+		opts       VolumeOptions
+		counts     []int
+		wantStates []string
+		wantEvents []string // 구간별 발행 이벤트 "이전→다음" (없으면 "")
+	}{
+		{
+			name:       "learning then normal without event",
+			opts:       opts,
+			counts:     []int{10, 10, 10, 10},
+			wantStates: []string{VolumeLearning, VolumeLearning, VolumeNormal, VolumeNormal},
+			wantEvents: []string{"", "", "", ""},
+		},
+		{
+			name:       "silent after consecutive zero intervals and recovery",
+			opts:       opts,
+			counts:     []int{10, 10, 10, 0, 0, 0, 10},
+			wantStates: []string{VolumeLearning, VolumeLearning, VolumeNormal, VolumeNormal, VolumeSilent, VolumeSilent, VolumeNormal},
+			wantEvents: []string{"", "", "", "", "normal→silent", "", "silent→normal"},
+		},
+		{
+			name:       "spike above expected times factor",
+			opts:       opts,
+			counts:     []int{10, 10, 10, 31, 10},
+			wantStates: []string{VolumeLearning, VolumeLearning, VolumeNormal, VolumeSpike, VolumeNormal},
+			wantEvents: []string{"", "", "", "normal→spike", "spike→normal"},
+		},
+		{
+			name:       "below min rate never alerts",
+			opts:       VolumeOptions{Interval: time.Minute, Window: 4, SpikeFactor: 3, SilentAfter: 2, MinRate: 5},
+			counts:     []int{2, 2, 2, 0, 0, 0, 40},
+			wantStates: []string{VolumeLearning, VolumeLearning, VolumeNormal, VolumeNormal, VolumeNormal, VolumeNormal, VolumeNormal},
+			wantEvents: []string{"", "", "", "", "", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newVolumeMonitor(nil, "", tt.opts)
+			now := time.Now()
+			for i, n := range tt.counts {
+				for j := 0; j < n; j++ {
+					m.Record("MESSAGE_DEVICE", map[string]interface{}{})
+				}
+				now = now.Add(time.Minute)
+				events := m.evaluate(now)
+
+				status := m.Status("topic", "")
+				if len(status) != 1 || status[0].State != tt.wantStates[i] {
+					t.Fatalf("interval %d (count %d): status = %+v, want %s", i, n, status, tt.wantStates[i])
+				}
+				got := ""
+				if len(events) > 0 {
+					got = events[0]["previousState"].(string) + "→" + events[0]["state"].(string)
+				}
+				if len(events) > 1 || got != tt.wantEvents[i] {
+					t.Errorf("interval %d: events = %v, want %q", i, events, tt.wantEvents[i])
+				}
+			}
+		})
+	}
+}
+
+// TestVolumeMonitorForget: 유입이 volumeForget보다 오래 없으면 추적 중단
+func TestVolumeMonitorForget(t *testing.T) {
+	m := newVolumeMonitor(nil, "", VolumeOptions{Interval: time.Minute, Window: 4, SpikeFactor: 3, SilentAfter: 2})
+	m.Record("MESSAGE_DEVICE", map[string]interface{}{"msgId": "USB", "hostname": "pc-1"})
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  int
+	}{
+		{"recent", time.Hour, 3},
+		{"just under forget", volumeForget - time.Minute, 3},
+		{"forgotten", volumeForget + time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.evaluate(time.Now().Add(tt.after))
+			if got := len(m.Status("", "")); got != tt.want {
+				t.Errorf("sources = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVolumeMonitorStatus: dimension/state 필터와 이상 상태 우선 정렬, Interval 0이면 기록 없음
+func TestVolumeMonitorStatus(t *testing.T) {
+	m := newVolumeMonitor(nil, "", VolumeOptions{Interval: time.Minute, Window: 1})
+	m.sources = map[string]*VolumeSource{
+		"host:b":         {Dimension: "host", Name: "b", State: VolumeNormal},
+		"host:a":         {Dimension: "host", Name: "a", State: VolumeNormal},
+		"topic:T":        {Dimension: "topic", Name: "T", State: VolumeSpike},
+		"msgId:USB":      {Dimension: "msgId", Name: "USB", State: VolumeSilent},
+		"msgId:PRINT":    {Dimension: "msgId", Name: "PRINT", State: VolumeLearning},
+		"topic:LEARNING": {Dimension: "topic", Name: "LEARNING", State: VolumeLearning},
+	}
+	names := func(list []VolumeSource) []string {
+		out := []string{}
+		for _, s := range list {
+			out = append(out, s.Dimension+":"+s.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		name, dimension, state string
+		want                   []string
+	}{
+		{"all sorted", "", "", []string{"msgId:USB", "topic:T", "host:a", "host:b", "msgId:PRINT", "topic:LEARNING"}},
+		{"by dimension", "host", "", []string{"host:a", "host:b"}},
+		{"by state", "", VolumeLearning, []string{"msgId:PRINT", "topic:LEARNING"}},
+		{"no match", "topic", VolumeSilent, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(m.Status(tt.dimension, tt.state)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Status(%q, %q) = %v, want %v", tt.dimension, tt.state, got, tt.want)
+			}
+		})
+	}
+
+	disabled := newVolumeMonitor(nil, "", VolumeOptions{})
+	disabled.Record("MESSAGE_DEVICE", map[string]interface{}{"msgId": "USB"})
+	if n := len(disabled.Status("", "")); n != 0 {
+		t.Errorf("disabled monitor sources = %d, want 0", n)
+	}
+}
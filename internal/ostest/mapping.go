@@ -0,0 +1,344 @@
+package ostest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// copyMap: JSON 왕복 깊은 복사
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	if m == nil {
+		return out
+	}
+	data, _ := json.Marshal(m)
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// mergeMapping: src 매핑을 dst에 병합 (properties는 필드 단위 재귀 병합, 그 외 키는 덮어씀)
+func mergeMapping(dst, src map[string]interface{}) {
+	for k, v := range copyMap(src) {
+		sub, ok := v.(map[string]interface{})
+		cur, curOK := dst[k].(map[string]interface{})
+		if k == "properties" && ok && curOK {
+			for name, def := range sub {
+				d, dOK := def.(map[string]interface{})
+				c, cOK := cur[name].(map[string]interface{})
+				if dOK && cOK {
+					mergeMapping(c, d)
+					continue
+				}
+				cur[name] = def
+			}
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// templateMappings: 인덱스 이름에 맞는 index template 중 priority가 가장 높은 것의 매핑 (호출측에서 lock 보유)
+func (s *Server) templateMappings(index string) map[string]interface{} {
+	var best map[string]interface{}
+	bestPriority := -1.0
+	names := make([]string, 0, len(s.templates))
+	for name := range s.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tpl := s.templates[name]
+		matched := false
+		for _, raw := range asList(tpl["index_patterns"]) {
+			if p, ok := raw.(string); ok {
+				if ok, _ := path.Match(p, index); ok {
+					matched = true
+				}
+			}
+		}
+		priority, _ := tpl["priority"].(float64)
+		if matched && priority > bestPriority {
+			best, bestPriority = tpl, priority
+		}
+	}
+	body, _ := best["template"].(map[string]interface{})
+	mappings, _ := body["mappings"].(map[string]interface{})
+	return copyMap(mappings)
+}
+
+func asList(v interface{}) []interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		return x
+	case nil:
+		return nil
+	}
+	return []interface{}{v}
+}
+
+// indexTemplate: /_index_template[/<name>] 조회/저장/삭제
+func (s *Server) indexTemplate(w http.ResponseWriter, method, name string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case method == "GET":
+		list := []interface{}{}
+		names := make([]string, 0, len(s.templates))
+		for n := range s.templates {
+			if ok, _ := path.Match(name, n); ok || name == "" {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			list = append(list, map[string]interface{}{"name": n, "index_template": copyMap(s.templates[n])})
+		}
+		if len(list) == 0 && name != "" {
+			writeError(w, 404, "resource_not_found_exception", "index template matching ["+name+"] not found")
+			return
+		}
+		writeJSON(w, 200, map[string]interface{}{"index_templates": list})
+	case (method == "PUT" || method == "POST") && name != "":
+		req, ok := decodeBody(w, body)
+		if !ok {
+			return
+		}
+		if len(asList(req["index_patterns"])) == 0 {
+			writeError(w, 400, "action_request_validation_exception", "Validation Failed: 1: index patterns are missing;")
+			return
+		}
+		s.templates[name] = req
+		writeJSON(w, 200, map[string]interface{}{"acknowledged": true})
+	case method == "DELETE" && name != "":
+		if _, ok := s.templates[name]; !ok {
+			writeError(w, 404, "index_template_missing_exception", "index_template ["+name+"] missing")
+			return
+		}
+		delete(s.templates, name)
+		writeJSON(w, 200, map[string]interface{}{"acknowledged": true})
+	default:
+		writeError(w, 405, "illegal_argument_exception", "method ["+method+"] not allowed")
+	}
+}
+
+// mapping: /<index>/_mapping 조회(명시 매핑 + 저장된 문서로 추론한 동적 필드) / 추가(PUT)
+func (s *Server) mapping(w http.ResponseWriter, method, index string, body []byte) {
+	names, err := s.store.Indices(index)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	switch method {
+	case "GET":
+		result := map[string]interface{}{}
+		for _, name := range names {
+			result[name] = map[string]interface{}{"mappings": s.indexMapping(name)}
+		}
+		writeJSON(w, 200, result)
+	case "PUT", "POST":
+		req, ok := decodeBody(w, body)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, name := range names {
+			m, ok := s.mappings[name]
+			if !ok {
+				m = s.templateMappings(name)
+				s.mappings[name] = m
+			}
+			mergeMapping(m, req)
+		}
+		writeJSON(w, 200, map[string]interface{}{"acknowledged": true})
+	default:
+		writeError(w, 405, "illegal_argument_exception", "method ["+method+"] not allowed")
+	}
+}
+
+// indexMapping: 인덱스 매핑 + 매핑에 없는 문서 필드의 동적 매핑
+// (Store()로 직접 넣은 인덱스는 현재 템플릿 기준)
+func (s *Server) indexMapping(index string) map[string]interface{} {
+	s.mu.Lock()
+	m, ok := s.mappings[index]
+	if !ok {
+		m = s.templateMappings(index)
+	}
+	m = copyMap(m)
+	s.mu.Unlock()
+
+	if dyn, ok := m["dynamic"]; ok && dyn != true && dyn != "true" {
+		return m
+	}
+	d := &dynamicMapper{templates: asList(m["dynamic_templates"]), dateDetection: true}
+	if v, ok := m["date_detection"].(bool); ok {
+		d.dateDetection = v
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	s.store.Iterate(context.Background(), index, nil, func(hit common.SearchHit) error {
+		var src map[string]interface{}
+		if json.Unmarshal(hit.Source, &src) == nil {
+			d.addFields(props, src, "")
+		}
+		return nil
+	})
+	if len(props) > 0 {
+		m["properties"] = props
+	}
+	return m
+}
+
+// dynamicMapper: 동적 매핑 규칙 (dynamic_templates의 match_mapping_type/match/unmatch/path_match, date_detection)
+type dynamicMapper struct {
+	templates     []interface{}
+	dateDetection bool
+}
+
+// addFields: 매핑에 없는 필드를 props에 추가 (enabled:false 객체와 기존 필드는 그대로)
+func (d *dynamicMapper) addFields(props, src map[string]interface{}, parent string) {
+	for name, v := range src {
+		full := name
+		if parent != "" {
+			full = parent + "." + name
+		}
+		v = firstValue(v)
+		if v == nil {
+			continue
+		}
+		def, exists := props[name].(map[string]interface{})
+		if obj, ok := v.(map[string]interface{}); ok {
+			if !exists {
+				def = d.fieldMapping(name, full, "object")
+				props[name] = def
+			}
+			if enabled, ok := def["enabled"].(bool); ok && !enabled {
+				continue
+			}
+			if typ, _ := def["type"].(string); typ != "" && typ != "object" {
+				continue
+			}
+			sub, _ := def["properties"].(map[string]interface{})
+			if sub == nil {
+				sub = map[string]interface{}{}
+			}
+			d.addFields(sub, obj, full)
+			if len(sub) > 0 {
+				def["properties"] = sub
+			}
+			continue
+		}
+		if !exists {
+			props[name] = d.fieldMapping(name, full, d.mappingType(v))
+		}
+	}
+}
+
+// firstValue: 배열은 첫 번째 null 아닌 원소로 타입 판단
+func firstValue(v interface{}) interface{} {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+	for _, e := range arr {
+		if e != nil {
+			return firstValue(e)
+		}
+	}
+	return nil
+}
+
+// mappingType: dynamic_templates의 match_mapping_type 값 (string/date/long/double/boolean)
+func (d *dynamicMapper) mappingType(v interface{}) string {
+	switch x := v.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		if x == float64(int64(x)) {
+			return "long"
+		}
+		return "double"
+	case string:
+		if d.dateDetection && looksLikeDate(x) {
+			return "date"
+		}
+	}
+	return "string"
+}
+
+var dynamicDateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02", "2006/01/02 15:04:05", "2006/01/02"}
+
+func looksLikeDate(s string) bool {
+	for _, layout := range dynamicDateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldMapping: 첫 번째로 맞는 dynamic template의 mapping, 없으면 OpenSearch 기본 동적 매핑
+func (d *dynamicMapper) fieldMapping(name, full, kind string) map[string]interface{} {
+	for _, raw := range d.templates {
+		entry, _ := raw.(map[string]interface{})
+		for _, v := range entry {
+			rule, _ := v.(map[string]interface{})
+			if t, ok := rule["match_mapping_type"].(string); ok && t != "*" && t != kind {
+				continue
+			}
+			if p, ok := rule["match"].(string); ok && !wildcardMatch(p, name) {
+				continue
+			}
+			if p, ok := rule["unmatch"].(string); ok && wildcardMatch(p, name) {
+				continue
+			}
+			if p, ok := rule["path_match"].(string); ok && !wildcardMatch(p, full) {
+				continue
+			}
+			if m, ok := rule["mapping"].(map[string]interface{}); ok {
+				return copyMap(m)
+			}
+		}
+	}
+	switch kind {
+	case "string":
+		return map[string]interface{}{
+			"type":   "text",
+			"fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256}},
+		}
+	case "double":
+		return map[string]interface{}{"type": "float"}
+	case "object":
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{"type": kind}
+}
+
+// wildcardMatch: dynamic template 패턴 (* 만 특수문자, 점 포함)
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
@@ -0,0 +1,585 @@
+package ostest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// Server: 통합 테스트용 가짜 OpenSearch (httptest 서버 + common.MemoryStore)
+//
+// 이 프로젝트가 호출하는 REST API만 구현한다:
+// _search(PIT 포함), _count, _doc, _create, _update, _bulk, _refresh, _mapping, _validate/query,
+// 인덱스 생성/삭제(PUT·DELETE·HEAD /<index>)와 _index_template.
+// 쿼리/집계는 MemoryStore가 지원하는 범위만 평가하고, 그 외 API는 400 (no handler found)으로 응답한다.
+// 쓰기는 즉시 검색에 반영되며 PIT는 스냅샷 없이 현재 문서를 본다.
+//
+// 사용 예:
+//
+//	srv := ostest.NewServer()
+//	defer srv.Close()
+//	cfg.OpenSearch.URL = srv.URL
+//	... (StartProcessor, logsink.Start 등 실행)
+//	docs := srv.Docs("siem-safepc-event-logs-2024.01.02")
+type Server struct {
+	*httptest.Server
+	store *common.MemoryStore
+
+	mu        sync.Mutex
+	mappings  map[string]map[string]interface{} // index → 생성 시 확정된 매핑
+	templates map[string]map[string]interface{} // 이름 → _index_template 본문
+	pits      map[string]string                 // pit_id → 인덱스 패턴
+	pitSeq    int
+}
+
+func NewServer() *Server {
+	s := &Server{
+		store:     common.NewMemoryStore(),
+		mappings:  make(map[string]map[string]interface{}),
+		templates: make(map[string]map[string]interface{}),
+		pits:      make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// OSClient: 이 서버를 가리키는 클라이언트
+func (s *Server) OSClient() *common.OSClient {
+	return common.NewOSClient(s.URL)
+}
+
+// Store: 저장소 직접 접근 (HTTP를 거치지 않는 시드/검증용)
+func (s *Server) Store() *common.MemoryStore {
+	return s.store
+}
+
+// Indices: 패턴에 해당하는 인덱스 목록 (이름 순, 없으면 빈 목록)
+func (s *Server) Indices(pattern string) []string {
+	names, _ := s.store.Indices(pattern)
+	return names
+}
+
+// Docs: 인덱스(패턴)의 전체 문서 (_source + "_id", _id 순)
+func (s *Server) Docs(index string) []map[string]interface{} {
+	docs, _ := common.SearchAll(context.Background(), s.store, index, nil)
+	return docs
+}
+
+// OpenPITs: 닫히지 않은 PIT 수 (Iterate가 PIT를 정리하는지 확인용)
+func (s *Server) OpenPITs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pits)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, 400, "parse_exception", err.Error())
+		return
+	}
+	var parts []string
+	if p := strings.Trim(r.URL.Path, "/"); p != "" {
+		parts = strings.Split(p, "/")
+	}
+	m := r.Method
+
+	switch {
+	case len(parts) == 0 && (m == "GET" || m == "HEAD"):
+		writeJSON(w, 200, map[string]interface{}{
+			"name":         "ostest",
+			"cluster_name": "ostest",
+			"version":      map[string]interface{}{"distribution": "opensearch", "number": "2.11.0"},
+		})
+		return
+	case len(parts) == 1 && parts[0] == "_bulk" && m == "POST":
+		s.bulk(w, "", body)
+		return
+	case len(parts) == 1 && parts[0] == "_refresh":
+		s.refresh(w, "_all")
+		return
+	case len(parts) == 1 && parts[0] == "_search":
+		s.search(w, "", body)
+		return
+	case len(parts) == 2 && parts[0] == "_search" && parts[1] == "point_in_time" && m == "DELETE":
+		s.closePIT(w, body)
+		return
+	case parts != nil && parts[0] == "_index_template" && len(parts) <= 2:
+		name := ""
+		if len(parts) == 2 {
+			name = parts[1]
+		}
+		s.indexTemplate(w, m, name, body)
+		return
+	}
+
+	if len(parts) == 0 || strings.HasPrefix(parts[0], "_") {
+		noHandler(w, r)
+		return
+	}
+	index := parts[0]
+	switch {
+	case len(parts) == 1:
+		s.indexAdmin(w, m, index, body)
+	case len(parts) == 2 && parts[1] == "_bulk" && m == "POST":
+		s.bulk(w, index, body)
+	case len(parts) == 2 && parts[1] == "_refresh":
+		s.refresh(w, index)
+	case len(parts) == 2 && parts[1] == "_search" && (m == "GET" || m == "POST"):
+		s.search(w, index, body)
+	case len(parts) == 3 && parts[1] == "_search" && parts[2] == "point_in_time" && m == "POST":
+		s.openPIT(w, index)
+	case len(parts) == 2 && parts[1] == "_count" && (m == "GET" || m == "POST"):
+		s.count(w, index, body)
+	case len(parts) == 2 && parts[1] == "_mapping":
+		s.mapping(w, m, index, body)
+	case len(parts) == 3 && parts[1] == "_validate" && parts[2] == "query":
+		s.validate(w, index, body, r.URL.Query().Get("explain") == "true")
+	case len(parts) == 2 && parts[1] == "_doc" && m == "POST":
+		s.indexDoc(w, index, body)
+	case len(parts) == 3 && parts[1] == "_doc":
+		s.doc(w, m, index, parts[2], body)
+	case len(parts) == 3 && parts[1] == "_create" && (m == "PUT" || m == "POST"):
+		s.createDoc(w, index, parts[2], body)
+	case len(parts) == 3 && parts[1] == "_update" && m == "POST":
+		s.update(w, index, parts[2], body)
+	default:
+		noHandler(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeError: OpenSearch 오류 응답 형식 ({"error":{"root_cause":[...],"type","reason"},"status"})
+func writeError(w http.ResponseWriter, status int, typ, reason string) {
+	cause := map[string]interface{}{"type": typ, "reason": reason}
+	writeJSON(w, status, map[string]interface{}{
+		"error":  map[string]interface{}{"root_cause": []interface{}{cause}, "type": typ, "reason": reason},
+		"status": status,
+	})
+}
+
+// writeStoreError: MemoryStore의 OSError("유형: 사유" 본문)를 HTTP 오류 응답으로
+func writeStoreError(w http.ResponseWriter, err error) {
+	var osErr *common.OSError
+	if !errors.As(err, &osErr) || osErr.Status == 0 {
+		writeError(w, 500, "exception", err.Error())
+		return
+	}
+	typ, reason := "illegal_argument_exception", osErr.Body
+	if osErr.Status == 404 {
+		typ = "resource_not_found_exception"
+	}
+	if i := strings.Index(osErr.Body, ": "); i > 0 && strings.HasSuffix(osErr.Body[:i], "_exception") {
+		typ, reason = osErr.Body[:i], osErr.Body[i+2:]
+	}
+	writeError(w, osErr.Status, typ, reason)
+}
+
+func noHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, 400, "illegal_argument_exception",
+		fmt.Sprintf("no handler found for uri [%s] and method [%s]", r.URL.Path, r.Method))
+}
+
+func indexNotFound(w http.ResponseWriter, index string) {
+	writeError(w, 404, "index_not_found_exception", "no such index ["+index+"]")
+}
+
+// decodeBody: JSON 본문 → map (빈 본문은 빈 map)
+func decodeBody(w http.ResponseWriter, body []byte) (map[string]interface{}, bool) {
+	req := map[string]interface{}{}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return req, true
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req == nil {
+		writeError(w, 400, "parse_exception", fmt.Sprintf("요청 본문이 JSON 객체가 아님: %v", err))
+		return nil, false
+	}
+	return req, true
+}
+
+func shards() map[string]interface{} {
+	return map[string]interface{}{"total": 1, "successful": 1, "failed": 0}
+}
+
+// ensureIndex: 쓰기 대상 인덱스 자동 생성 (index template 매핑 적용)
+func (s *Server) ensureIndex(index string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store.CreateIndex(index) {
+		s.mappings[index] = s.templateMappings(index)
+	}
+}
+
+// writable: 쓰기 가능한 구체 인덱스 이름인지 (와일드카드/쉼표/_ 시작 불가)
+func writable(w http.ResponseWriter, index string) bool {
+	if index == "" || strings.HasPrefix(index, "_") || strings.ContainsAny(index, "*?,") || index != strings.ToLower(index) {
+		writeError(w, 400, "invalid_index_name_exception", "Invalid index name ["+index+"]")
+		return false
+	}
+	return true
+}
+
+func (s *Server) indexAdmin(w http.ResponseWriter, method, index string, body []byte) {
+	switch method {
+	case "HEAD", "GET":
+		names, err := s.store.Indices(index)
+		if err != nil || len(names) == 0 {
+			w.WriteHeader(404)
+			return
+		}
+		if method == "HEAD" {
+			w.WriteHeader(200)
+			return
+		}
+		s.mapping(w, "GET", index, nil)
+	case "PUT":
+		if !writable(w, index) {
+			return
+		}
+		req, ok := decodeBody(w, body)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.store.CreateIndex(index) {
+			writeError(w, 400, "resource_already_exists_exception", "index ["+index+"] already exists")
+			return
+		}
+		// 요청 본문의 mappings가 템플릿 매핑보다 우선
+		mappings := s.templateMappings(index)
+		if m, ok := req["mappings"].(map[string]interface{}); ok {
+			mergeMapping(mappings, m)
+		}
+		s.mappings[index] = mappings
+		writeJSON(w, 200, map[string]interface{}{"acknowledged": true, "shards_acknowledged": true, "index": index})
+	case "DELETE":
+		names, err := s.store.Indices(index)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, name := range names {
+			s.store.DeleteIndex(name)
+			delete(s.mappings, name)
+		}
+		writeJSON(w, 200, map[string]interface{}{"acknowledged": true})
+	default:
+		writeError(w, 405, "illegal_argument_exception", "method ["+method+"] not allowed")
+	}
+}
+
+func (s *Server) refresh(w http.ResponseWriter, index string) {
+	if _, err := s.store.Indices(index); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"_shards": shards()})
+}
+
+// search: /<index>/_search 또는 /_search (본문에 pit가 있으면 PIT의 인덱스 패턴으로)
+func (s *Server) search(w http.ResponseWriter, index string, body []byte) {
+	req, ok := decodeBody(w, body)
+	if !ok {
+		return
+	}
+	pitID := ""
+	if pit, ok := req["pit"].(map[string]interface{}); ok {
+		if index != "" {
+			writeError(w, 400, "illegal_argument_exception", "[indices] cannot be used with point in time")
+			return
+		}
+		pitID, _ = pit["id"].(string)
+		s.mu.Lock()
+		pattern, found := s.pits[pitID]
+		s.mu.Unlock()
+		if !found {
+			writeError(w, 404, "search_context_missing_exception", "No search context found for id ["+pitID+"]")
+			return
+		}
+		index = pattern
+		delete(req, "pit")
+	}
+	if index == "" {
+		index = "_all"
+	}
+	result, err := s.store.SearchRaw(index, req)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	result["_shards"] = shards()
+	if pitID != "" {
+		result["pit_id"] = pitID
+	}
+	writeJSON(w, 200, result)
+}
+
+func (s *Server) openPIT(w http.ResponseWriter, index string) {
+	if _, err := s.store.Indices(index); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.mu.Lock()
+	s.pitSeq++
+	id := fmt.Sprintf("pit-%08d", s.pitSeq)
+	s.pits[id] = index
+	s.mu.Unlock()
+	writeJSON(w, 200, map[string]interface{}{"pit_id": id, "_shards": shards(), "creation_time": time.Now().UnixMilli()})
+}
+
+func (s *Server) closePIT(w http.ResponseWriter, body []byte) {
+	req, ok := decodeBody(w, body)
+	if !ok {
+		return
+	}
+	var ids []interface{}
+	switch v := req["pit_id"].(type) {
+	case string:
+		ids = []interface{}{v}
+	case []interface{}:
+		ids = v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pits := []interface{}{}
+	for _, raw := range ids {
+		id, _ := raw.(string)
+		_, found := s.pits[id]
+		delete(s.pits, id)
+		pits = append(pits, map[string]interface{}{"pit_id": id, "successful": found})
+	}
+	writeJSON(w, 200, map[string]interface{}{"pits": pits})
+}
+
+func (s *Server) count(w http.ResponseWriter, index string, body []byte) {
+	req, ok := decodeBody(w, body)
+	if !ok {
+		return
+	}
+	n, err := s.store.Count(index, req["query"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"count": n, "_shards": shards()})
+}
+
+func (s *Server) validate(w http.ResponseWriter, index string, body []byte, explain bool) {
+	req, ok := decodeBody(w, body)
+	if !ok {
+		return
+	}
+	if _, err := s.store.Indices(index); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	result := map[string]interface{}{"_shards": shards(), "valid": true}
+	if err := s.store.Validate(index, req); err != nil {
+		result["valid"] = false
+		// 실제 OpenSearch처럼 사유는 explain=true일 때만
+		if explain {
+			var osErr *common.OSError
+			if errors.As(err, &osErr) {
+				result["error"] = osErr.Body
+			} else {
+				result["error"] = err.Error()
+			}
+		}
+	}
+	writeJSON(w, 200, result)
+}
+
+func docResult(index, id, result string) map[string]interface{} {
+	return map[string]interface{}{
+		"_index": index, "_id": id, "_version": 1, "result": result,
+		"_shards": shards(), "_seq_no": 0, "_primary_term": 1,
+	}
+}
+
+// indexDoc: POST /<index>/_doc (자동 _id)
+func (s *Server) indexDoc(w http.ResponseWriter, index string, body []byte) {
+	if !writable(w, index) {
+		return
+	}
+	if _, ok := decodeBody(w, body); !ok {
+		return
+	}
+	s.ensureIndex(index)
+	id, err := s.store.Index(index, json.RawMessage(body))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, 201, docResult(index, id, "created"))
+}
+
+// createDoc: PUT /<index>/_create/<id> (같은 _id 문서가 있으면 409)
+func (s *Server) createDoc(w http.ResponseWriter, index, id string, body []byte) {
+	if !writable(w, index) {
+		return
+	}
+	if _, ok := decodeBody(w, body); !ok {
+		return
+	}
+	s.ensureIndex(index)
+	if existing, _ := s.store.Get(index, id); existing != nil {
+		writeError(w, 409, "version_conflict_engine_exception", "["+id+"]: version conflict, document already exists")
+		return
+	}
+	if err := s.store.Put(index, id, json.RawMessage(body)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, 201, docResult(index, id, "created"))
+}
+
+// doc: /<index>/_doc/<id> 조회/저장/삭제
+func (s *Server) doc(w http.ResponseWriter, method, index, id string, body []byte) {
+	switch method {
+	case "GET":
+		doc, err := s.store.Get(index, id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if doc == nil {
+			writeJSON(w, 404, map[string]interface{}{"_index": index, "_id": id, "found": false})
+			return
+		}
+		delete(doc, "_id")
+		writeJSON(w, 200, map[string]interface{}{
+			"_index": index, "_id": id, "_version": 1, "_seq_no": 0, "_primary_term": 1,
+			"found": true, "_source": doc,
+		})
+	case "PUT", "POST":
+		if !writable(w, index) {
+			return
+		}
+		if _, ok := decodeBody(w, body); !ok {
+			return
+		}
+		s.ensureIndex(index)
+		existing, _ := s.store.Get(index, id)
+		if err := s.store.Put(index, id, json.RawMessage(body)); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if existing != nil {
+			writeJSON(w, 200, docResult(index, id, "updated"))
+		} else {
+			writeJSON(w, 201, docResult(index, id, "created"))
+		}
+	case "DELETE":
+		if _, err := s.store.Indices(index); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		existing, _ := s.store.Get(index, id)
+		if existing == nil {
+			writeJSON(w, 404, docResult(index, id, "not_found"))
+			return
+		}
+		s.store.Delete(index, id)
+		writeJSON(w, 200, docResult(index, id, "deleted"))
+	default:
+		writeError(w, 405, "illegal_argument_exception", "method ["+method+"] not allowed")
+	}
+}
+
+// update: POST /<index>/_update/<id> ({"doc": ..., "doc_as_upsert": bool})
+func (s *Server) update(w http.ResponseWriter, index, id string, body []byte) {
+	req, ok := decodeBody(w, body)
+	if !ok {
+		return
+	}
+	patch, ok := req["doc"].(map[string]interface{})
+	if !ok {
+		writeError(w, 400, "action_request_validation_exception", "Validation Failed: 1: script or doc is missing;")
+		return
+	}
+	existing, _ := s.store.Get(index, id)
+	if existing == nil {
+		if upsert, _ := req["doc_as_upsert"].(bool); !upsert {
+			writeError(w, 404, "document_missing_exception", "["+id+"]: document missing")
+			return
+		}
+		if !writable(w, index) {
+			return
+		}
+		s.ensureIndex(index)
+		if err := s.store.Put(index, id, patch); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, 201, docResult(index, id, "created"))
+		return
+	}
+	if err := s.store.Update(index, id, patch); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, 200, docResult(index, id, "updated"))
+}
+
+// bulk: /_bulk, /<index>/_bulk — _index가 없는 항목은 경로의 인덱스로, 쓰기 대상 인덱스는 자동 생성
+func (s *Server) bulk(w http.ResponseWriter, defaultIndex string, body []byte) {
+	lines := bytes.Split(body, []byte("\n"))
+	var out bytes.Buffer
+	for i := 0; i < len(lines); i++ {
+		line := bytes.TrimSpace(lines[i])
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]map[string]interface{}
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			writeError(w, 400, "illegal_argument_exception", "Malformed action/metadata line ["+string(line)+"]")
+			return
+		}
+		for op, meta := range action {
+			if meta == nil {
+				meta = map[string]interface{}{}
+				action[op] = meta
+			}
+			if _, ok := meta["_index"]; !ok && defaultIndex != "" {
+				meta["_index"] = defaultIndex
+			}
+			if index, _ := meta["_index"].(string); index != "" && op != "delete" && op != "update" &&
+				!strings.HasPrefix(index, "_") && !strings.ContainsAny(index, "*?,") {
+				s.ensureIndex(index)
+			}
+			data, _ := json.Marshal(action)
+			out.Write(data)
+			out.WriteByte('\n')
+			if op != "delete" && i+1 < len(lines) {
+				i++
+				out.Write(bytes.TrimSpace(lines[i]))
+				out.WriteByte('\n')
+			}
+		}
+	}
+	result, err := s.store.Bulk(out.Bytes())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, 200, result)
+}
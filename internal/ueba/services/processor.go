@@ -2,7 +2,9 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -19,7 +21,6 @@ import (
 )
 
 const (
-	maxRulesSize      = 100
 	compositePageSize = 1000
 )
 
@@ -38,11 +39,7 @@ func compositeAgg(index, userField string, query interface{}, subAggs map[string
 		if subAggs != nil {
 			aggs["users"].(map[string]interface{})["aggs"] = subAggs
 		}
-		body, _ := json.Marshal(map[string]interface{}{"size": 0, "query": query, "aggs": aggs})
-		resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, index), "application/json", bytes.NewReader(body))
-		if err != nil {
-			break
-		}
+		body := map[string]interface{}{"size": 0, "query": query, "aggs": aggs}
 		var result struct {
 			Aggregations struct {
 				Users struct {
@@ -51,8 +48,9 @@ func compositeAgg(index, userField string, query interface{}, subAggs map[string
 				} `json:"users"`
 			} `json:"aggregations"`
 		}
-		json.NewDecoder(resp.Body).Decode(&result)
-		resp.Body.Close()
+		if err := searchInto(index, body, &result); err != nil {
+			break
+		}
 
 		for _, bucket := range result.Aggregations.Users.Buckets {
 			key, _ := bucket["key"].(map[string]interface{})
@@ -79,7 +77,8 @@ var (
 	loc            *time.Location
 	healthWarnMB, healthCritMB float64
 	
-	osClient  *common.OSClient
+	store      common.Store // OpenSearch (StartProcessor에서 설정)
+	bulkWriter *common.BulkWriter
 
 	configCache *Config
 	configMu    sync.RWMutex
@@ -97,6 +96,14 @@ var (
 
 	currentDate   string
 	currentDateMu sync.RWMutex
+
+	// field-meta에서 number로 정의된 필드 (LogSink가 숫자로 색인 → .keyword 없음)
+	fieldMetaTypes   map[string]string
+	fieldMetaTypesMu sync.RWMutex
+
+	// label 확장 이름(cn1Label="File Count" → FileCount) → 원본 키 숫자 타입 (LogSink typer와 같은 규칙)
+	labelFieldTypes   = make(map[string]string)
+	labelFieldTypesMu sync.RWMutex
 )
 
 type UserProfile struct {
@@ -234,6 +241,8 @@ func initialize() {
 	log.Println("[INIT] UEBA 시스템 초기화 시작")
 	loadConfig()
 	loadRules()
+	loadFieldMetaTypes()
+	loadLabelTypes()
 	loadAllBaselines()
 	loadUserProfiles()
 	ensureBaselinesFresh()
@@ -250,37 +259,22 @@ func initialize() {
 func ensureBaselinesFresh() {
 	today := time.Now().In(loc).Format("2006-01-02")
 
-	resp, err := httpClient.Get(fmt.Sprintf("%s/%s/_doc/baseline_meta", opensearchURL, common.SettingsIndex(indexPrefix)))
-	if err == nil && resp.StatusCode == 200 {
-		var result struct {
-			Source struct {
-				UpdatedAt string `json:"updated_at"`
-			} `json:"_source"`
-		}
-		json.NewDecoder(resp.Body).Decode(&result)
-		resp.Body.Close()
-		if result.Source.UpdatedAt == today {
+	var meta struct {
+		UpdatedAt string `json:"updated_at"`
+	}
+	if found, err := getInto(common.SettingsIndex(indexPrefix), "baseline_meta", &meta); err == nil && found {
+		if meta.UpdatedAt == today {
 			log.Println("[INIT] Baseline 최신 (오늘 자정 갱신 완료)")
 			return
 		}
-		log.Printf("[INIT] Baseline 갱신 필요 (마지막: %s, 오늘: %s)", result.Source.UpdatedAt, today)
+		log.Printf("[INIT] Baseline 갱신 필요 (마지막: %s, 오늘: %s)", meta.UpdatedAt, today)
 	} else {
-		if resp != nil {
-			resp.Body.Close()
-		}
 		log.Println("[INIT] Baseline 메타 없음, 갱신 실행")
 	}
 
 	updateBaselines()
 	// 갱신 완료 시점 기록
-	meta, _ := json.Marshal(map[string]string{"updated_at": today})
-	req, _ := http.NewRequest("PUT",
-		fmt.Sprintf("%s/%s/_doc/baseline_meta", opensearchURL, common.SettingsIndex(indexPrefix)),
-		bytes.NewReader(meta))
-	req.Header.Set("Content-Type", "application/json")
-	if r, err := httpClient.Do(req); err == nil {
-		r.Body.Close()
-	}
+	store.Put(common.SettingsIndex(indexPrefix), "baseline_meta", map[string]string{"updated_at": today})
 	log.Println("[INIT] Baseline 갱신 완료")
 }
 
@@ -450,13 +444,9 @@ func recoverEventCounts(today string) {
 		if afterKey != nil {
 			composite["after"] = afterKey
 		}
-		body, _ := json.Marshal(map[string]interface{}{
+		body := map[string]interface{}{
 			"size": 0, "query": queryPart,
 			"aggs": map[string]interface{}{"pairs": map[string]interface{}{"composite": composite}},
-		})
-		resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.LogsIndexPattern(indexPrefix)), "application/json", bytes.NewReader(body))
-		if err != nil {
-			break
 		}
 		var result struct {
 			Aggregations struct {
@@ -469,8 +459,9 @@ func recoverEventCounts(today string) {
 				} `json:"pairs"`
 			} `json:"aggregations"`
 		}
-		json.NewDecoder(resp.Body).Decode(&result)
-		resp.Body.Close()
+		if err := searchInto(common.LogsIndexPattern(indexPrefix), body, &result); err != nil {
+			break
+		}
 
 		userStatesMu.Lock()
 		for _, b := range result.Aggregations.Pairs.Buckets {
@@ -650,9 +641,84 @@ func resolveESField(field string) string {
 	if top[field] {
 		return field + ".keyword"
 	}
+	if isNumericField(field) {
+		return "cefExtensions." + field
+	}
 	return "cefExtensions." + field + ".keyword"
 }
 
+// isNumericField: LogSink가 숫자로 변환하는 cefExtensions 필드 (CEF 표준 숫자 키 + label 확장 이름 + field-meta number)
+func isNumericField(field string) bool {
+	labelFieldTypesMu.RLock()
+	defer labelFieldTypesMu.RUnlock()
+	fieldMetaTypesMu.RLock()
+	defer fieldMetaTypesMu.RUnlock()
+	return common.CEFValueType(field, labelFieldTypes, fieldMetaTypes) != ""
+}
+
+// recordLabelTypes: 이벤트에서 본 label 확장 이름의 타입 추가 (이름이 같으면 마지막 값)
+func recordLabelTypes(types map[string]string) {
+	if len(types) == 0 {
+		return
+	}
+	labelFieldTypesMu.Lock()
+	defer labelFieldTypesMu.Unlock()
+	for name, typ := range types {
+		if labelFieldTypes[name] != typ {
+			log.Printf("[UEBA] label 숫자 필드: %s (%s)", name, typ)
+			labelFieldTypes[name] = typ
+		}
+	}
+}
+
+// loadLabelTypes: 최근 7일 event-logs의 숫자 키 *Label 값 → label 확장 이름 타입
+// 기동 직후(이벤트 수신 전) 복구/baseline 쿼리도 label 숫자 필드를 .keyword 없이 조회하도록
+func loadLabelTypes() {
+	keys := common.CEFNumericKeys()
+	aggs := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		aggs[key] = map[string]interface{}{"terms": map[string]interface{}{
+			"field": "cefExtensions." + key + "Label.keyword", "size": 100,
+		}}
+	}
+	var result struct {
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key string `json:"key"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	err := searchInto(common.LogsIndexPattern(indexPrefix), map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"range": map[string]interface{}{"@timestamp": map[string]interface{}{"gte": "now-7d/d"}}},
+		"aggs":  aggs,
+	}, &result)
+	if err != nil {
+		log.Printf("[WARN] label 타입 로드 실패: %v", err)
+		return
+	}
+	types := make(map[string]string)
+	for key, agg := range result.Aggregations {
+		for _, b := range agg.Buckets {
+			if b.Key != "" {
+				types[strings.ReplaceAll(b.Key, " ", "")] = common.CEFFieldType(key)
+			}
+		}
+	}
+	recordLabelTypes(types)
+}
+
+func loadFieldMetaTypes() {
+	types, err := common.FieldMetaTypes(store, indexPrefix)
+	if err != nil {
+		log.Printf("[WARN] field-meta 타입 로드 실패: %v", err)
+		return
+	}
+	fieldMetaTypesMu.Lock()
+	fieldMetaTypes = types
+	fieldMetaTypesMu.Unlock()
+}
+
 // resolveAggField: sum/cardinality 대상 필드 → OpenSearch 경로
 func resolveAggField(field string) string {
 	// 숫자 필드는 .keyword 불필요
@@ -661,29 +727,23 @@ func resolveAggField(field string) string {
 
 func loadAllBaselines() {
 	log.Println("[INIT] Baseline 로드 중...")
-	query := map[string]interface{}{"size": 10000, "query": map[string]interface{}{"match_all": map[string]interface{}{}}}
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.BaselinesIndex(indexPrefix)), "application/json", bytes.NewReader(body))
+	loaded := make(map[string]*Baseline)
+	err := store.Iterate(context.Background(), common.BaselinesIndex(indexPrefix), nil, func(hit common.SearchHit) error {
+		var bl Baseline
+		if err := json.Unmarshal(hit.Source, &bl); err != nil {
+			return nil
+		}
+		loaded[hit.ID] = &bl
+		return nil
+	})
 	if err != nil {
 		log.Printf("[WARN] Baseline 로드 실패: %v", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Hits struct {
-			Hits []struct {
-				ID     string   `json:"_id"`
-				Source Baseline `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-	json.NewDecoder(resp.Body).Decode(&result)
 
 	baselinesMu.Lock()
-	for _, hit := range result.Hits.Hits {
-		bl := hit.Source
-		baselines[hit.ID] = &bl
+	for id, bl := range loaded {
+		baselines[id] = bl
 	}
 	baselinesMu.Unlock()
 	log.Printf("[INIT] %d개 Baseline 로드 완료", len(baselines))
@@ -703,12 +763,6 @@ func getPrevScore(userID string) (float64, int) {
 		}}},
 		"_source": []string{"riskScore", "@timestamp"},
 	}
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.ScoresIndexPattern(indexPrefix)), "application/json", bytes.NewReader(body))
-	if err != nil {
-		return 0, 1
-	}
-	defer resp.Body.Close()
 	var result struct {
 		Hits struct {
 			Hits []struct {
@@ -719,7 +773,9 @@ func getPrevScore(userID string) (float64, int) {
 			} `json:"hits"`
 		} `json:"hits"`
 	}
-	json.NewDecoder(resp.Body).Decode(&result)
+	if err := searchInto(common.ScoresIndexPattern(indexPrefix), query, &result); err != nil {
+		return 0, 1
+	}
 	if len(result.Hits.Hits) > 0 && result.Hits.Hits[0].Source.RiskScore > 0 {
 		score := result.Hits.Hits[0].Source.RiskScore
 		if t, err := time.Parse(time.RFC3339, result.Hits.Hits[0].Source.Timestamp); err == nil {
@@ -757,10 +813,22 @@ func processEvent(data []byte) {
 		return
 	}
 
+	// LogSink가 중복으로 표시한 재전송 이벤트와 siem replay 재주입 이벤트는 집계 제외
+	// (재주입은 CEP 규칙 검증용 — 점수/baseline에 과거 이벤트가 다시 반영되면 안 됨)
+	if dup, _ := event["duplicate"].(bool); dup {
+		return
+	}
+	if replayed, _ := event["replayed"].(bool); replayed {
+		return
+	}
+
 	checkDateRollover()
 
 	// CEF Label 기반으로 attrs 파싱 → event["_attrs"]에 저장
 	event["_attrs"] = parseCEFAttrs(event)
+	if ext, ok := event["cefExtensions"].(map[string]interface{}); ok {
+		recordLabelTypes(common.CEFLabelTypes(ext))
+	}
 
 	rules := loadRules()
 
@@ -1235,14 +1303,7 @@ func checkDateRollover() {
 		updateBaselines()
 		loadUserProfiles() // 만료된 상황가중치 정리
 		today := time.Now().In(loc).Format("2006-01-02")
-		meta, _ := json.Marshal(map[string]string{"updated_at": today})
-		req, _ := http.NewRequest("PUT",
-			fmt.Sprintf("%s/%s/_doc/baseline_meta", opensearchURL, common.SettingsIndex(indexPrefix)),
-			bytes.NewReader(meta))
-		req.Header.Set("Content-Type", "application/json")
-		if r, err := httpClient.Do(req); err == nil {
-			r.Body.Close()
-		}
+		store.Put(common.SettingsIndex(indexPrefix), "baseline_meta", map[string]string{"updated_at": today})
 	}()
 }
 
@@ -1265,14 +1326,9 @@ func loadConfig() *Config {
 		Tiers:   TierConfig{GreenMax: 40, YellowMax: 99},
 	}
 
-	resp, err := httpClient.Get(fmt.Sprintf("%s/%s/_doc/settings", opensearchURL, common.SettingsIndex(indexPrefix)))
-	if err == nil && resp.StatusCode == 200 {
-		var result struct {
-			Source Config `json:"_source"`
-		}
-		json.NewDecoder(resp.Body).Decode(&result)
-		resp.Body.Close()
-		configCache = &result.Source
+	var cfg Config
+	if found, err := getInto(common.SettingsIndex(indexPrefix), "settings", &cfg); err == nil && found {
+		configCache = &cfg
 	}
 	return configCache
 }
@@ -1288,44 +1344,23 @@ func loadRules() []Rule {
 	rulesMu.Lock()
 	defer rulesMu.Unlock()
 
-	query := map[string]interface{}{"size": maxRulesSize, "query": map[string]interface{}{
-		"bool": map[string]interface{}{
-			"must": []map[string]interface{}{
-				{"term": map[string]interface{}{"enabled": true}},
-				{"term": map[string]interface{}{"ueba.enabled": true}},
-			},
-		},
-	}}
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.RulesIndex(indexPrefix)), "application/json", bytes.NewReader(body))
-	if err != nil {
-		return rulesCache
-	}
-	defer resp.Body.Close()
-
-	// raw JSON으로 디코딩 (struct 필드 누락 방지)
-	var rawResult struct {
-		Hits struct {
-			Hits []struct {
-				ID  string          `json:"_id"`
-				Raw json.RawMessage `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(bodyBytes, &rawResult)
-
-	rulesCache = make([]Rule, 0, len(rawResult.Hits.Hits))
-	for _, hit := range rawResult.Hits.Hits {
+	rules := make([]Rule, 0)
+	err := store.Iterate(context.Background(), common.RulesIndex(indexPrefix), uebaRulesQuery(), func(hit common.SearchHit) error {
 		var rule Rule
-		json.Unmarshal(hit.Raw, &rule)
+		json.Unmarshal(hit.Source, &rule)
 		if rule.Name == "" {
 			rule.Name = hit.ID
 		}
 		rule.ID = hit.ID
 
-		rulesCache = append(rulesCache, rule)
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[WARN] UEBA 규칙 로드 실패: %v", err)
+		return rulesCache
 	}
+	rulesCache = rules
 	log.Printf("[CONFIG] %d개 UEBA 규칙 로드", len(rulesCache))
 	return rulesCache
 }
@@ -1381,14 +1416,6 @@ func updateBaselines() {
 		},
 	}
 
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.LogsIndexPattern(indexPrefix)), "application/json", bytes.NewReader(body))
-	if err != nil {
-		log.Printf("[BASELINE] 조회 실패: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
 	var result struct {
 		Aggregations struct {
 			ByUser struct {
@@ -1422,9 +1449,12 @@ func updateBaselines() {
 			} `json:"global_by_msgId"`
 		} `json:"aggregations"`
 	}
-	json.NewDecoder(resp.Body).Decode(&result)
+	if err := searchInto(common.LogsIndexPattern(indexPrefix), query, &result); err != nil {
+		log.Printf("[BASELINE] 조회 실패: %v", err)
+		return
+	}
 
-	var bulkBody bytes.Buffer
+	var items []*common.BulkItem
 	count := 0
 	today := time.Now().In(loc).Format("2006-01-02")
 
@@ -1444,11 +1474,8 @@ func updateBaselines() {
 			key := ub.Key + "_" + mb.Key
 			baselines[key] = bl
 
-			bulkBody.WriteString(fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s"}}`, common.BaselinesIndex(indexPrefix), key))
-			bulkBody.WriteString("\n")
 			blJSON, _ := json.Marshal(bl)
-			bulkBody.Write(blJSON)
-			bulkBody.WriteString("\n")
+			items = append(items, &common.BulkItem{Index: common.BaselinesIndex(indexPrefix), ID: key, Doc: blJSON})
 			count++
 		}
 	}
@@ -1483,19 +1510,18 @@ func updateBaselines() {
 		key := "_global_" + mb.Key
 		baselines[key] = bl
 
-		bulkBody.WriteString(fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s"}}`, common.BaselinesIndex(indexPrefix), key))
-		bulkBody.WriteString("\n")
 		blJSON, _ := json.Marshal(bl)
-		bulkBody.Write(blJSON)
-		bulkBody.WriteString("\n")
+		items = append(items, &common.BulkItem{Index: common.BaselinesIndex(indexPrefix), ID: key, Doc: blJSON})
 		count++
 		globalCount++
 	}
 	baselinesMu.Unlock()
 
-	if bulkBody.Len() > 0 {
-		httpClient.Post(opensearchURL+"/_bulk", "application/x-ndjson", &bulkBody)
+	// 락 밖에서 색인 (flush 완료까지 대기 — 이후 baseline_meta 기록 순서 보장)
+	for _, item := range items {
+		bulkWriter.AddItem(item)
 	}
+	bulkWriter.Flush(context.Background())
 	log.Printf("[BASELINE] %d개 업데이트 완료 (global: %d개)", count, globalCount)
 }
 
@@ -1523,7 +1549,7 @@ func saveScoresBatch() {
 
 func saveScoresBatchForDate(day string, overrideTimestamp string) {
 	userStatesMu.Lock()
-	var bulkBody bytes.Buffer
+	var items []*common.BulkItem
 	cfg := loadConfig()
 	count := 0
 
@@ -1557,18 +1583,23 @@ func saveScoresBatchForDate(day string, overrideTimestamp string) {
 			EventValues:  state.EventValues,
 			Timestamp:    timestamp,
 		}
-		bulkBody.WriteString(fmt.Sprintf(`{"index":{"_index":"%s","_id":"%s_%s"}}`, common.DailyScoresIndex(indexPrefix, day), userID, time.Now().In(loc).Format("15")))
-		bulkBody.WriteString("\n")
+		// EventCounts 등 상태 맵을 공유하므로 락 안에서 직렬화
 		scoreJSON, _ := json.Marshal(score)
-		bulkBody.Write(scoreJSON)
-		bulkBody.WriteString("\n")
+		items = append(items, &common.BulkItem{
+			Index: common.DailyScoresIndex(indexPrefix, day),
+			ID:    fmt.Sprintf("%s_%s", userID, time.Now().In(loc).Format("15")),
+			Doc:   scoreJSON,
+		})
 		state.Dirty = false
 		count++
 	}
 	userStatesMu.Unlock()
 
-	if bulkBody.Len() > 0 {
-		httpClient.Post(opensearchURL+"/_bulk", "application/x-ndjson", &bulkBody)
+	if len(items) > 0 {
+		for _, item := range items {
+			bulkWriter.AddItem(item)
+		}
+		bulkWriter.Flush(context.Background())
 		log.Printf("[SAVE] %d명 점수 저장", count)
 	}
 }
@@ -1587,38 +1618,25 @@ func pushToDashboard(userID string, state *UserState) {
 
 // ===== HTTP API =====
 
-// esQuery: OpenSearch에 POST _search 요청
-func esQuery(index string, body map[string]interface{}) (map[string]interface{}, error) {
-	b, _ := json.Marshal(body)
-	resp, err := httpClient.Post(opensearchURL+"/"+index+"/_search", "application/json", bytes.NewReader(b))
+// searchInto: 검색 응답 전체를 v(응답 형태의 구조체)로 디코딩
+func searchInto(index string, body interface{}, v interface{}) error {
+	result, err := store.SearchRaw(index, body)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	return result, nil
+	data, _ := json.Marshal(result)
+	return json.Unmarshal(data, v)
 }
 
-// esRequest: OpenSearch에 임의 HTTP 요청
-func esRequest(method, path string, body interface{}) (map[string]interface{}, error) {
-	var reqBody *bytes.Reader
-	if body != nil {
-		b, _ := json.Marshal(body)
-		reqBody = bytes.NewReader(b)
-	} else {
-		reqBody = bytes.NewReader(nil)
+// getInto: 문서 _source를 v로 디코딩, 문서가 없으면 false
+func getInto(index, docID string, v interface{}) (bool, error) {
+	doc, err := store.Get(index, docID)
+	if err != nil || doc == nil {
+		return false, err
 	}
-	req, _ := http.NewRequest(method, opensearchURL+path, reqBody)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	return result, nil
+	delete(doc, "_id")
+	data, _ := json.Marshal(doc)
+	return true, json.Unmarshal(data, v)
 }
 
 func writeJSON(w http.ResponseWriter, v interface{}) {
@@ -1743,39 +1761,31 @@ func GetRules() []Rule {
 
 // GetRulesRaw: 프론트엔드용 — OpenSearch 원본 JSON 반환 (struct 직렬화 누락 방지)
 func GetRulesRaw() []map[string]interface{} {
-	query := map[string]interface{}{"size": maxRulesSize, "query": map[string]interface{}{
-		"bool": map[string]interface{}{
-			"must": []map[string]interface{}{
-				{"term": map[string]interface{}{"enabled": true}},
-				{"term": map[string]interface{}{"ueba.enabled": true}},
-			},
-		},
-	}}
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.RulesIndex(indexPrefix)), "application/json", bytes.NewReader(body))
+	docs, err := common.SearchAll(context.Background(), store, common.RulesIndex(indexPrefix), uebaRulesQuery())
 	if err != nil {
 		return nil
 	}
-	defer resp.Body.Close()
-	var result struct {
-		Hits struct {
-			Hits []struct {
-				ID     string                 `json:"_id"`
-				Source map[string]interface{} `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-	json.NewDecoder(resp.Body).Decode(&result)
-	rules := make([]map[string]interface{}, 0, len(result.Hits.Hits))
-	for _, hit := range result.Hits.Hits {
-		r := hit.Source
-		r["id"] = hit.ID
+	rules := make([]map[string]interface{}, 0, len(docs))
+	for _, r := range docs {
+		r["id"] = r["_id"]
 		delete(r, "_id")
 		rules = append(rules, r)
 	}
 	return rules
 }
 
+// uebaRulesQuery: 활성화된 UEBA 규칙 조회 조건
+func uebaRulesQuery() map[string]interface{} {
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": []map[string]interface{}{
+				{"term": map[string]interface{}{"enabled": true}},
+				{"term": map[string]interface{}{"ueba.enabled": true}},
+			},
+		},
+	}
+}
+
 func CreateRule(data map[string]interface{}) (string, error) {
 	if errs := validateRule(data); len(errs) > 0 {
 		return "", fmt.Errorf("%s", strings.Join(errs, "; "))
@@ -1786,12 +1796,11 @@ func CreateRule(data map[string]interface{}) (string, error) {
 	delete(data, "_id")
 	delete(data, "id")
 	data["createdAt"] = time.Now().In(loc).Format(time.RFC3339)
-	result, err := esRequest("POST", fmt.Sprintf("/%s/_doc", common.RulesIndex(indexPrefix)), data)
+	id, err := store.Index(common.RulesIndex(indexPrefix), data)
 	if err != nil {
 		return "", err
 	}
 	reloadAndReprocess()
-	id, _ := result["_id"].(string)
 	return id, nil
 }
 
@@ -1804,7 +1813,7 @@ func UpdateRule(id string, data map[string]interface{}) error {
 	}
 	delete(data, "_id")
 	delete(data, "id")
-	_, err := esRequest("PUT", fmt.Sprintf("/%s/_doc/%s", common.RulesIndex(indexPrefix), id), data)
+	err := store.Put(common.RulesIndex(indexPrefix), id, data)
 	if err != nil {
 		return err
 	}
@@ -1813,7 +1822,7 @@ func UpdateRule(id string, data map[string]interface{}) error {
 }
 
 func DeleteRule(id string) error {
-	_, err := esRequest("DELETE", fmt.Sprintf("/%s/_doc/%s", common.RulesIndex(indexPrefix), id), nil)
+	err := store.Delete(common.RulesIndex(indexPrefix), id)
 	if err != nil {
 		return err
 	}
@@ -1865,24 +1874,14 @@ func validateDSL(data map[string]interface{}) error {
 		return nil // 파싱 불가 시 구조 검증에서 이미 걸림
 	}
 	query := buildRuleESQuery(*rule, time.Now().In(loc).Format("2006-01-02"))
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(
-		fmt.Sprintf("%s/%s/_validate/query", opensearchURL, common.LogsIndexPattern(indexPrefix)),
-		"application/json", bytes.NewReader(body),
-	)
+	err := store.Validate(common.LogsIndexPattern(indexPrefix), query)
+	var osErr *common.OSError
+	if errors.As(err, &osErr) && osErr.Status != 0 {
+		return fmt.Errorf("%s", osErr.Body) // 무효 쿼리 사유
+	}
 	if err != nil {
 		return fmt.Errorf("OpenSearch 연결 실패: %v", err)
 	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	if valid, _ := result["valid"].(bool); !valid {
-		errMsg, _ := result["error"].(string)
-		if errMsg == "" {
-			errMsg = "쿼리가 유효하지 않음"
-		}
-		return fmt.Errorf("%s", errMsg)
-	}
 	return nil
 }
 
@@ -1973,6 +1972,8 @@ func ReloadCache() {
 	rulesMu.Unlock()
 	loadConfig()
 	loadRules()
+	loadFieldMetaTypes()
+	loadLabelTypes()
 	loadUserProfiles()
 }
 
@@ -1988,7 +1989,7 @@ func GetUserHistory(userID string) map[string]interface{} {
 	now := time.Now().In(loc)
 	today := now.Format("2006-01-02")
 
-	daily, _ := esQuery(common.ScoresIndexPattern(indexPrefix), map[string]interface{}{
+	daily, _ := store.SearchRaw(common.ScoresIndexPattern(indexPrefix), map[string]interface{}{
 		"size": 0,
 		"query": map[string]interface{}{"bool": map[string]interface{}{"must": []interface{}{
 			map[string]interface{}{"term": map[string]interface{}{"userId": userID}},
@@ -2018,7 +2019,7 @@ func GetUserHistory(userID string) map[string]interface{} {
 		}
 	}
 
-	hourly, _ := esQuery(common.ScoresIndexPattern(indexPrefix), map[string]interface{}{
+	hourly, _ := store.SearchRaw(common.ScoresIndexPattern(indexPrefix), map[string]interface{}{
 		"size": 0,
 		"query": map[string]interface{}{"bool": map[string]interface{}{"must": []interface{}{
 			map[string]interface{}{"term": map[string]interface{}{"userId": userID}},
@@ -2062,7 +2063,7 @@ func GetUserHistory(userID string) map[string]interface{} {
 }
 
 func GetUserHourly(userID string) []map[string]interface{} {
-	result, err := esQuery(common.LogsIndexPattern(indexPrefix), map[string]interface{}{
+	result, err := store.SearchRaw(common.LogsIndexPattern(indexPrefix), map[string]interface{}{
 		"size": 0,
 		"query": map[string]interface{}{"bool": map[string]interface{}{"must": []interface{}{
 			map[string]interface{}{"term": map[string]interface{}{"userId": userID}},
@@ -2169,41 +2170,33 @@ func GetUserScores(draw, start, length int, search, sortField, orderDir string)
 }
 
 func GetSettings() map[string]interface{} {
-	result, err := esRequest("GET", fmt.Sprintf("/%s/_doc/settings", common.SettingsIndex(indexPrefix)), nil)
+	cfg, err := store.Get(common.SettingsIndex(indexPrefix), "settings")
 	if err != nil {
 		return map[string]interface{}{}
 	}
-	cfg, _ := result["_source"].(map[string]interface{})
 	if cfg == nil {
 		cfg = map[string]interface{}{}
 	}
+	delete(cfg, "_id")
 
-	rulesResult, _ := esQuery(common.RulesIndex(indexPrefix), map[string]interface{}{
-		"size":  maxRulesSize,
-		"query": map[string]interface{}{"term": map[string]interface{}{"ueba.enabled": true}},
-	})
+	ruleDocs, _ := common.SearchAll(context.Background(), store, common.RulesIndex(indexPrefix),
+		map[string]interface{}{"term": map[string]interface{}{"ueba.enabled": true}})
 	weights := map[string]interface{}{}
-	if hits, ok := rulesResult["hits"].(map[string]interface{}); ok {
-		if hitArr, ok := hits["hits"].([]interface{}); ok {
-			for _, h := range hitArr {
-				hit, _ := h.(map[string]interface{})
-				id, _ := hit["_id"].(string)
-				src, _ := hit["_source"].(map[string]interface{})
-				weight := 0.0
-				if w, ok := src["weight"].(float64); ok {
-					weight = w
-				}
-				msgId := ""
-				if m, ok := src["match"].(map[string]interface{}); ok {
-					msgId, _ = m["msgId"].(string)
-				}
-				ruleName, _ := src["name"].(string)
-				if ruleName == "" {
-					ruleName = id
-				}
-				weights[id] = map[string]interface{}{"name": ruleName, "weight": weight, "msgId": msgId}
-			}
+	for _, src := range ruleDocs {
+		id, _ := src["_id"].(string)
+		weight := 0.0
+		if w, ok := src["weight"].(float64); ok {
+			weight = w
 		}
+		msgId := ""
+		if m, ok := src["match"].(map[string]interface{}); ok {
+			msgId, _ = m["msgId"].(string)
+		}
+		ruleName, _ := src["name"].(string)
+		if ruleName == "" {
+			ruleName = id
+		}
+		weights[id] = map[string]interface{}{"name": ruleName, "weight": weight, "msgId": msgId}
 	}
 	cfg["weights"] = weights
 	return cfg
@@ -2219,28 +2212,25 @@ func SaveSettings(data map[string]interface{}) (map[string]interface{}, error) {
 			if w, ok := wm["weight"].(float64); ok {
 				weight = w
 			}
-			esRequest("POST", fmt.Sprintf("/%s/_update/%s", common.RulesIndex(indexPrefix), name), map[string]interface{}{
-				"doc": map[string]interface{}{"weight": weight},
-			})
+			store.Update(common.RulesIndex(indexPrefix), name, map[string]interface{}{"weight": weight})
 		}
 		delete(data, "weights")
 	}
 
-	result, err := esRequest("PUT", fmt.Sprintf("/%s/_doc/settings", common.SettingsIndex(indexPrefix)), data)
-	if err != nil {
+	if err := store.Put(common.SettingsIndex(indexPrefix), "settings", data); err != nil {
 		return nil, err
 	}
 	ReloadCache()
-	return result, nil
+	return data, nil
 }
 
 // ===== Kafka Consumer =====
 
-func startKafkaConsumer() {
+func startKafkaConsumer() error {
 	topics := strings.Split(kafkaEventTopics, ",")
 	if len(topics) == 1 && topics[0] == "" {
 		log.Println("[KAFKA] 구독할 UEBA 토픽이 설정되지 않았습니다. (KAFKA_EVENT_TOPICS)")
-		return
+		return nil
 	}
 
 	config := sarama.NewConfig()
@@ -2248,7 +2238,7 @@ func startKafkaConsumer() {
 
 	consumer, err := sarama.NewConsumer([]string{kafkaBootstrap}, config)
 	if err != nil {
-		log.Fatalf("[KAFKA] 연결 실패: %v", err)
+		return fmt.Errorf("Kafka 연결 실패: %v", err)
 	}
 	defer consumer.Close()
 
@@ -2277,58 +2267,52 @@ func startKafkaConsumer() {
 // ===== 유저 프로필 (상황가중치) =====
 
 func loadUserProfiles() {
-	query := map[string]interface{}{
-		"size": 1000,
-		"query": map[string]interface{}{
-			"term": map[string]interface{}{"type": "profile"},
-		},
-	}
-	body, _ := json.Marshal(query)
-	resp, err := httpClient.Post(fmt.Sprintf("%s/%s/_search", opensearchURL, common.BaselinesIndex(indexPrefix)), "application/json", bytes.NewReader(body))
+	type profileDoc struct {
+		UserID      string `json:"userId"`
+		Context     string `json:"context"`
+		StartDate   string `json:"startDate"`
+		EndDate     string `json:"endDate"`
+		Note        string `json:"note"`
+		Whitelisted bool   `json:"whitelisted"`
+	}
+	var docs []profileDoc
+	err := store.Iterate(context.Background(), common.BaselinesIndex(indexPrefix),
+		map[string]interface{}{"term": map[string]interface{}{"type": "profile"}},
+		func(hit common.SearchHit) error {
+			var doc profileDoc
+			if err := json.Unmarshal(hit.Source, &doc); err == nil {
+				docs = append(docs, doc)
+			}
+			return nil
+		})
 	if err != nil {
+		log.Printf("[WARN] 유저 프로필 로드 실패: %v", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Hits struct {
-			Hits []struct {
-				Source struct {
-					UserID      string `json:"userId"`
-					Context     string `json:"context"`
-					StartDate   string `json:"startDate"`
-					EndDate     string `json:"endDate"`
-					Note        string `json:"note"`
-					Whitelisted bool   `json:"whitelisted"`
-				} `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-	json.NewDecoder(resp.Body).Decode(&result)
 
 	today := time.Now().In(loc).Format("2006-01-02")
 	newProfiles := make(map[string]*UserProfile)
 	var expiredUsers []string
 	
-	for _, h := range result.Hits.Hits {
-		if h.Source.UserID == "" {
+	for _, h := range docs {
+		if h.UserID == "" {
 			continue
 		}
 		profile := &UserProfile{
-			Context:     h.Source.Context,
-			StartDate:   h.Source.StartDate,
-			EndDate:     h.Source.EndDate,
-			Note:        h.Source.Note,
-			Whitelisted: h.Source.Whitelisted,
+			Context:     h.Context,
+			StartDate:   h.StartDate,
+			EndDate:     h.EndDate,
+			Note:        h.Note,
+			Whitelisted: h.Whitelisted,
 		}
 		// 기간 만료 체크: endDate가 있고 오늘보다 과거면 만료
 		if profile.EndDate != "" && profile.EndDate < today && profile.Context != "normal" {
-			expiredUsers = append(expiredUsers, h.Source.UserID)
+			expiredUsers = append(expiredUsers, h.UserID)
 			profile.Context = "normal"
 			profile.StartDate = ""
 			profile.EndDate = ""
 		}
-		newProfiles[h.Source.UserID] = profile
+		newProfiles[h.UserID] = profile
 	}
 	
 	userProfilesMu.Lock()
@@ -2369,14 +2353,9 @@ func SetUserProfile(userID string, profile *UserProfile) error {
 	} else {
 		doc["endDate"] = nil
 	}
-	body, _ := json.Marshal(doc)
-	req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/%s/_doc/%s_profile", opensearchURL, common.BaselinesIndex(indexPrefix), userID), bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := httpClient.Do(req)
-	if err != nil {
+	if err := store.Put(common.BaselinesIndex(indexPrefix), userID+"_profile", doc); err != nil {
 		return err
 	}
-	resp.Body.Close()
 
 	userProfilesMu.Lock()
 	userProfiles[userID] = profile
@@ -2422,7 +2401,18 @@ func ReloadConfig() {
 
 // ===== Main =====
 
-func StartProcessor(cfg *config.Config) {
+// StartProcessor: 설정의 OpenSearch로 UEBA 프로세서 실행 (Kafka 소비, 정상 동작 중에는 반환하지 않음)
+func StartProcessor(cfg *config.Config) error {
+	osClient, err := common.NewOSClientFromConfig(cfg.OpenSearch)
+	if err != nil {
+		return fmt.Errorf("OpenSearch 클라이언트 설정 오류: %v", err)
+	}
+	return StartProcessorWithStore(cfg, osClient)
+}
+
+// StartProcessorWithStore: s를 저장소로 상태 초기화(설정/규칙/baseline/오늘 집계 복구) 후 Kafka 소비
+// KAFKA_EVENT_TOPICS가 비어 있으면 초기화만 하고 nil 반환 (테스트에서 MemoryStore로 사용)
+func StartProcessorWithStore(cfg *config.Config, s common.Store) error {
 	opensearchURL = cfg.OpenSearch.URL
 	kafkaBootstrap = cfg.Kafka.Bootstrap
 	kafkaEventTopics = cfg.Kafka.EventTopics
@@ -2432,7 +2422,9 @@ func StartProcessor(cfg *config.Config) {
 	healthWarnMB = cfg.UEBA.HealthWarnMB
 	healthCritMB = cfg.UEBA.HealthCritMB
 
-	osClient = common.NewOSClient(opensearchURL)
+	store = s
+	// 점수/baseline 저장 (저장 시점마다 Flush로 즉시 전송)
+	bulkWriter = common.NewBulkWriter(store, common.BulkOptions{Name: "UEBA", MaxRetries: 3})
 
 	log.Printf("[UEBA] 프로세서 시작")
 	log.Printf("[UEBA] OpenSearch: %s", opensearchURL)
@@ -2445,5 +2437,5 @@ func StartProcessor(cfg *config.Config) {
 	}
 
 	initialize()
-	startKafkaConsumer()
+	return startKafkaConsumer()
 }
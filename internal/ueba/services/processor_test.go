@@ -0,0 +1,150 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markany/safepc-siem/config"
+	"github.com/markany/safepc-siem/internal/common"
+)
+
+// TestStartProcessorWithStoreRecovery: MemoryStore에 규칙/오늘 이벤트를 넣고 초기화 시 집계 복구 결과 확인
+// (term/range/terms/bool should + composite 집계 경로)
+func TestStartProcessorWithStoreRecovery(t *testing.T) {
+	const prefix = "test"
+	s := common.NewMemoryStore()
+	kst := time.FixedZone("KST", 9*60*60)
+	today := time.Now().In(kst)
+
+	rules := map[string]map[string]interface{}{
+		"big-usb": {
+			"name": "big-usb", "weight": 10, "enabled": true, "ueba": map[string]interface{}{"enabled": true},
+			"match": map[string]interface{}{"msgId": "USB", "conditions": []interface{}{
+				map[string]interface{}{"field": "fsize", "op": "gte", "value": 100},
+			}},
+			"aggregate": map[string]interface{}{"type": "sum", "field": "fsize"},
+		},
+		"usb-hosts": {
+			"name": "usb-hosts", "weight": 5, "enabled": true, "ueba": map[string]interface{}{"enabled": true},
+			"match": map[string]interface{}{"msgId": "USB", "logic": "or", "conditions": []interface{}{
+				map[string]interface{}{"field": "shost", "op": "in", "value": []string{"pc-1"}},
+				map[string]interface{}{"field": "fsize", "op": "lt", "value": 10},
+			}},
+		},
+		"disabled": {
+			"name": "disabled", "weight": 5, "enabled": false, "ueba": map[string]interface{}{"enabled": true},
+			"match": map[string]interface{}{"msgId": "USB"},
+		},
+	}
+	for id, rule := range rules {
+		if err := s.Put(common.RulesIndex(prefix), id, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	event := func(day time.Time, user, msgID, host string, fsize int) map[string]interface{} {
+		return map[string]interface{}{
+			"@timestamp": day.Format("2006-01-02") + "T12:00:00+09:00",
+			"msgId":      msgID,
+			"cefExtensions": map[string]interface{}{
+				"suid": user, "shost": host, "fsize": fsize,
+			},
+		}
+	}
+	todayIndex := common.DailyLogsIndex(prefix, today.Format("2006.01.02"))
+	yesterday := today.AddDate(0, 0, -1)
+	docs := []map[string]interface{}{
+		event(today, "u1", "USB", "pc-1", 300),
+		event(today, "u1", "USB", "pc-2", 50),
+		event(today, "u1", "USB", "pc-2", 5),
+		event(today, "u2", "USB", "pc-3", 120),
+		event(today, "u2", "PRINT", "pc-3", 1),
+	}
+	for _, doc := range docs {
+		if _, err := s.Index(todayIndex, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 어제 이벤트는 오늘 집계에 포함되지 않음
+	if _, err := s.Index(common.DailyLogsIndex(prefix, yesterday.Format("2006.01.02")), event(yesterday, "u1", "USB", "pc-1", 1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Timezone: "Asia/Seoul", IndexPrefix: prefix}
+	if err := StartProcessorWithStore(cfg, s); err != nil {
+		t.Fatalf("StartProcessorWithStore: %v", err)
+	}
+
+	u1, u2 := GetUser("u1"), GetUser("u2")
+	if u1 == nil || u2 == nil {
+		t.Fatalf("복구된 유저 없음: u1=%v u2=%v", u1, u2)
+	}
+	values := func(u map[string]interface{}) map[string]float64 { return u["eventValues"].(map[string]float64) }
+	counts := func(u map[string]interface{}) map[string]int { return u["eventCounts"].(map[string]int) }
+
+	if got := values(u1)["big-usb"]; got != 300 {
+		t.Errorf("u1 big-usb sum = %v, want 300", got)
+	}
+	if got := values(u2)["big-usb"]; got != 120 {
+		t.Errorf("u2 big-usb sum = %v, want 120", got)
+	}
+	if got := values(u1)["usb-hosts"]; got != 2 {
+		t.Errorf("u1 usb-hosts count = %v, want 2 (shost in pc-1 or fsize < 10)", got)
+	}
+	if _, ok := values(u2)["usb-hosts"]; ok {
+		t.Errorf("u2 usb-hosts should not match: %v", values(u2))
+	}
+	if _, ok := values(u1)["disabled"]; ok {
+		t.Errorf("disabled rule aggregated: %v", values(u1))
+	}
+	if got := counts(u1)["USB"]; got != 3 {
+		t.Errorf("u1 USB count = %v, want 3", got)
+	}
+	if got := counts(u2); got["USB"] != 1 || got["PRINT"] != 1 {
+		t.Errorf("u2 counts = %v, want USB:1 PRINT:1", got)
+	}
+
+	// 복구 직후 점수는 오늘자 scores 인덱스에 저장됨
+	scores, err := s.Indices(common.ScoresIndexPattern(prefix))
+	if err != nil || len(scores) != 1 || !strings.HasSuffix(scores[0], today.Format("2006.01.02")) {
+		t.Errorf("scores indices = %v (%v), want today's daily index", scores, err)
+	}
+}
+
+// TestProcessEventSkipsReplayed: siem replay 재주입(replayed)과 LogSink 중복 표시(duplicate) 이벤트는 집계 제외
+func TestProcessEventSkipsReplayed(t *testing.T) {
+	cfg := &config.Config{Timezone: "Asia/Seoul", IndexPrefix: "test"}
+	if err := StartProcessorWithStore(cfg, common.NewMemoryStore()); err != nil {
+		t.Fatalf("StartProcessorWithStore: %v", err)
+	}
+	tests := []struct {
+		name      string
+		event     string
+		user      string
+		wantCount int // 0이면 유저 상태 없음
+	}{
+		{"live", `{"msgId":"USB","cefExtensions":{"suid":"live-1"}}`, "live-1", 1},
+		{"replayed false counted", `{"msgId":"USB","replayed":false,"cefExtensions":{"suid":"live-2"}}`, "live-2", 1},
+		{"replayed", `{"msgId":"USB","replayed":true,"cefExtensions":{"suid":"replay-1"}}`, "replay-1", 0},
+		{"duplicate", `{"msgId":"USB","duplicate":true,"cefExtensions":{"suid":"dup-1"}}`, "dup-1", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processEvent([]byte(tt.event))
+			u := GetUser(tt.user)
+			if tt.wantCount == 0 {
+				if u != nil {
+					t.Errorf("user %s aggregated: %v", tt.user, u)
+				}
+				return
+			}
+			if u == nil {
+				t.Fatalf("user %s not aggregated", tt.user)
+			}
+			if got := u["eventCounts"].(map[string]int)["USB"]; got != tt.wantCount {
+				t.Errorf("USB count = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}